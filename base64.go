@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// base64Encodings are tried in order on the values -decode-base64 decodes.
+var base64Encodings = []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding}
+
+// decodeBase64Fields decodes the string values of the keys matching any of
+// the patterns, and every string inside them when they are objects or
+// arrays, such as the data of a Kubernetes Secret. The decoded values are
+// bytes, shown as text when printable and summarized otherwise; strings
+// that are not base64 are left alone with a warning. keyPath is as for
+// redact.
+func decodeBase64Fields(v interface{}, keyPath string, patterns []keyPattern) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, k := range jt.OrderedKeys(v) {
+			key := strings.ToLower(k)
+			childPath := key
+			if keyPath != "" {
+				childPath = keyPath + "/" + key
+			}
+			if matchAny(patterns, key, childPath) {
+				v[k] = decodeBase64Strings(v[k], k)
+				continue
+			}
+			v[k] = decodeBase64Fields(v[k], childPath, patterns)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = decodeBase64Fields(item, keyPath, patterns)
+		}
+	}
+	return v
+}
+
+func decodeBase64Strings(v interface{}, key string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, k := range jt.OrderedKeys(v) {
+			v[k] = decodeBase64Strings(v[k], k)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = decodeBase64Strings(item, key)
+		}
+	case string:
+		compact := strings.Join(strings.Fields(v), "")
+		for _, enc := range base64Encodings {
+			if data, err := enc.DecodeString(compact); err == nil {
+				return data
+			}
+		}
+		warn("base64", 0, "value of '%s' is not base64, shown as is", key)
+	}
+	return v
+}
@@ -0,0 +1,13 @@
+package main
+
+import "strings"
+
+// expandCaption fills in the placeholders of a -caption that are the same
+// for every document: {filename} (stdin when reading from a pipe) and
+// {selector}.
+func expandCaption(caption, filename, selector string) string {
+	if filename == "" {
+		filename = "stdin"
+	}
+	return strings.NewReplacer("{filename}", filename, "{selector}", selector).Replace(caption)
+}
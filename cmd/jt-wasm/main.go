@@ -0,0 +1,145 @@
+//go:build js && wasm
+
+// Command jt-wasm exposes the jt engine to JavaScript when compiled to
+// WebAssembly:
+//
+//	GOOS=js GOARCH=wasm go build -o jt.wasm ./cmd/jt-wasm
+//
+// Once loaded with Go's wasm_exec.js, it defines a global jt object:
+//
+//	jt.render(input, options) // {output} or {error}
+//	jt.styleSheet()           // CSS for HTML output in the current theme
+//	jt.themes                 // names accepted by options.theme
+//
+// input is JSON, YAML or XML text. options may set format (html, the
+// default, markdown or table), filename, selector, theme, columns, exclude,
+// depth, maxWidth, maxRows, transpose, noHeader, noIndex, headerCase,
+// caption, multiline and base64, named after the command-line flags.
+package main
+
+import (
+	"strings"
+	"syscall/js"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+func main() {
+	var themes []interface{}
+	for _, name := range strings.Split(jt.ThemeNames(), "/") {
+		themes = append(themes, name)
+	}
+	js.Global().Set("jt", map[string]interface{}{
+		"render":     js.FuncOf(render),
+		"styleSheet": js.FuncOf(func(js.Value, []js.Value) interface{} { return jt.StyleSheet() }),
+		"themes":     themes,
+	})
+	// Keep the functions callable
+	select {}
+}
+
+func render(_ js.Value, args []js.Value) interface{} {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return result("", "render needs the input as a string")
+	}
+	input := []byte(args[0].String())
+	var options js.Value
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		options = args[1]
+	}
+	get := func(name string) js.Value {
+		if options.IsUndefined() {
+			return js.Undefined()
+		}
+		return options.Get(name)
+	}
+
+	if theme := get("theme"); theme.Type() == js.TypeString {
+		if err := jt.ApplyTheme(theme.String()); err != nil {
+			return result("", err.Error())
+		}
+	}
+	popts := jt.ParseOptions{}
+	if v := get("filename"); v.Type() == js.TypeString {
+		popts.Filename = v.String()
+	}
+	data, isMultiDoc, err := jt.Parse(input, popts)
+	if err != nil {
+		return result("", err.Error())
+	}
+	if v := get("selector"); v.Type() == js.TypeString && v.String() != "" {
+		if data, err = jt.Select(data, v.String()); err != nil {
+			return result("", err.Error())
+		}
+	}
+
+	opts := jt.DefaultOptions()
+	opts.Format = "html"
+	setString(get("format"), &opts.Format)
+	setString(get("headerCase"), &opts.HeaderCase)
+	setString(get("caption"), &opts.Caption)
+	setString(get("multiline"), &opts.Multiline)
+	setString(get("base64"), &opts.Base64)
+	setInt(get("depth"), &opts.Levels)
+	setInt(get("maxWidth"), &opts.MaxWidth)
+	setInt(get("maxRows"), &opts.MaxRows)
+	setBool(get("transpose"), &opts.Transpose)
+	setBool(get("noHeader"), &opts.NoHeader)
+	setBool(get("noIndex"), &opts.NoIndex)
+	opts.Columns = stringList(get("columns"))
+	for _, key := range stringList(get("exclude")) {
+		if opts.Exclude == nil {
+			opts.Exclude = make(map[string]bool)
+		}
+		opts.Exclude[key] = true
+	}
+
+	output, err := jt.RenderDocuments(data, opts, isMultiDoc)
+	if err != nil {
+		return result("", err.Error())
+	}
+	return result(output, "")
+}
+
+func result(output, err string) interface{} {
+	if err != "" {
+		return map[string]interface{}{"error": err}
+	}
+	return map[string]interface{}{"output": output}
+}
+
+func setString(v js.Value, s *string) {
+	if v.Type() == js.TypeString {
+		*s = v.String()
+	}
+}
+
+func setInt(v js.Value, n *int) {
+	if v.Type() == js.TypeNumber {
+		*n = v.Int()
+	}
+}
+
+func setBool(v js.Value, b *bool) {
+	if v.Type() == js.TypeBoolean {
+		*b = v.Bool()
+	}
+}
+
+// stringList accepts an array of strings or a comma-separated string.
+func stringList(v js.Value) []string {
+	var items []string
+	switch v.Type() {
+	case js.TypeString:
+		for _, item := range strings.Split(v.String(), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	case js.TypeObject:
+		for i := 0; i < v.Length(); i++ {
+			items = append(items, v.Index(i).String())
+		}
+	}
+	return items
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// numberText matches numbers written the way JSON writes them. Leading
+// zeros are not allowed, so codes such as 007 or 01234 stay text.
+var numberText = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// coerceValues converts strings that hold numbers, booleans, timestamps or
+// dates into those types, for APIs that send everything as text, so that
+// alignment, coloring, sorting and statistics treat them as what they are.
+// The values of an array, or of a column of an array of objects, are only
+// converted if all of them convert to the same type: a version column with
+// 2 and 1.2.3 stays text.
+func coerceValues(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if jt.IsMetaKey(k) {
+				continue
+			}
+			if s, ok := val.(string); ok {
+				v[k], _ = coerceString(s)
+			} else {
+				v[k] = coerceValues(val)
+			}
+		}
+		return v
+	case []interface{}:
+		coerceColumn(len(v), func(i int) interface{} { return v[i] }, func(i int, c interface{}) { v[i] = c })
+		var keys []string
+		seen := make(map[string]bool)
+		for _, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, k := range jt.OrderedKeys(row) {
+				if !seen[k] {
+					seen[k] = true
+					keys = append(keys, k)
+				}
+			}
+		}
+		for _, k := range keys {
+			cell := func(i int) interface{} {
+				if row, ok := v[i].(map[string]interface{}); ok {
+					return row[k]
+				}
+				return nil
+			}
+			coerceColumn(len(v), cell, func(i int, c interface{}) { v[i].(map[string]interface{})[k] = c })
+		}
+		for i, item := range v {
+			switch item := item.(type) {
+			case map[string]interface{}:
+				// Only nested containers are left; the columns are done
+				for k, val := range item {
+					if _, ok := val.(string); !ok && !jt.IsMetaKey(k) {
+						item[k] = coerceValues(val)
+					}
+				}
+			case []interface{}:
+				v[i] = coerceValues(item)
+			}
+		}
+		return v
+	case string:
+		c, _ := coerceString(v)
+		return c
+	}
+	return data
+}
+
+// coerceColumn converts the strings among n cells if they all convert to
+// the same type.
+func coerceColumn(n int, get func(int) interface{}, set func(int, interface{})) {
+	kind := ""
+	for i := 0; i < n; i++ {
+		s, ok := get(i).(string)
+		if !ok {
+			continue
+		}
+		_, k := coerceString(s)
+		if k == "" || kind != "" && k != kind {
+			return
+		}
+		kind = k
+	}
+	for i := 0; i < n; i++ {
+		if s, ok := get(i).(string); ok {
+			c, _ := coerceString(s)
+			set(i, c)
+		}
+	}
+}
+
+// coerceString converts s and names the type it converted to, "" if it
+// stays a string.
+func coerceString(s string) (interface{}, string) {
+	switch {
+	case numberText.MatchString(s):
+		return json.Number(s), "number"
+	case strings.EqualFold(s, "true"):
+		return true, "bool"
+	case strings.EqualFold(s, "false"):
+		return false, "bool"
+	}
+	if t, ok := parseTimestamp(s); ok && strings.TrimSpace(s) == s {
+		return t, "time"
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, "time"
+	}
+	return s, ""
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/obegron/jt/pkg/jt"
+	"gopkg.in/yaml.v3"
+)
+
+type config struct {
+	Colors  map[string]jt.RuleList `yaml:"colors"`
+	MaxRows *int                   `yaml:"max_rows"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "jt", "config.yaml")
+}
+
+func loadConfig(path string) config {
+	var cfg config
+	if path == "" {
+		return cfg
+	}
+	input, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg
+		}
+		fail(exitUsage, "reading config: %v", err)
+	}
+	if err := yaml.Unmarshal(input, &cfg); err != nil {
+		fail(exitUsage, "parsing config %s: %v", path, err)
+	}
+	return cfg
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// copyKey copies what the viewer is on to the clipboard, for y (the value),
+// Y (its row) and c (its path), and reports what was copied in the status
+// bar. The tree copies the node under the cursor; the table the value in the
+// cell of the current search match.
+func (m *model) copyKey(key string) {
+	path, value, ok := m.copyTarget()
+	if !ok {
+		return
+	}
+	switch key {
+	case "y":
+		writeClipboard(copyText(value))
+		m.notice = "Copied the value of " + m.inputPath(path)
+	case "Y":
+		rowPath, row := rowOf(m.data, path)
+		writeClipboard(copyText(row))
+		m.notice = "Copied the row " + m.inputPath(rowPath)
+	case "c":
+		path = m.inputPath(path)
+		writeClipboard(path)
+		m.notice = "Copied the path " + path
+	}
+}
+
+// copyTarget returns the path and value to copy. In the table that is the
+// value shown in the cell of the current search match; when there is no
+// match, or it is not in a value, it says so in the status bar and reports
+// false.
+func (m *model) copyTarget() (string, interface{}, bool) {
+	if m.tree != nil {
+		n := m.tree.current()
+		return n.path, n.value, true
+	}
+	if len(m.matches) == 0 {
+		m.notice = "Search (/) for a value to copy, or press t to pick one in the tree"
+		return "", nil, false
+	}
+	match := m.matches[m.currentMatch]
+	line := m.plainContent[match.line]
+	start, end := jt.DisplayWidth(line[:match.col]), jt.DisplayWidth(line[:match.end])
+	if cells := m.tableCells(); match.line < len(cells) {
+		for _, cell := range cells[match.line] {
+			if cell.Start <= start && end <= cell.End {
+				if value, ok := findPath(m.data, "", cell.Path); ok {
+					return cell.Path, value, true
+				}
+			}
+		}
+	}
+	m.notice = "The match is not in a value; press t to pick one in the tree"
+	return "", nil, false
+}
+
+// tableCells returns the values shown on each line of the table, rendering
+// it again with them marked the first time it is asked for.
+func (m *model) tableCells() [][]jt.Cell {
+	if m.cells == nil {
+		opts := m.opts.Options
+		opts.Color = colorOutput(m.opts)
+		if _, cells, err := jt.RenderCells(m.data, opts, m.isMultiDoc); err == nil {
+			m.cells = cells
+		}
+	}
+	return m.cells
+}
+
+// rowOf returns the row holding the value at path: the nearest element of
+// an array around it, or the object it is a key of when it is in no array.
+func rowOf(data interface{}, path string) (string, interface{}) {
+	for i := len(path); i > 0; i-- {
+		if path[i-1] != ']' {
+			continue
+		}
+		if row, ok := findPath(data, "", path[:i]); ok {
+			return path[:i], row
+		}
+	}
+	if i := strings.LastIndex(path, "."); i > 0 {
+		if row, ok := findPath(data, "", path[:i]); ok {
+			return path[:i], row
+		}
+	}
+	return ".", data
+}
+
+// copyText is the clipboard text of a value: strings as they are, other
+// scalars as jt shows them, objects and arrays as indented JSON.
+func copyText(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		out, err := encodeJSON(v, "  ")
+		if err == nil {
+			return strings.TrimSuffix(string(out), "\n")
+		}
+	}
+	return jt.ScalarString(v)
+}
+
+// writeClipboard puts text on the system clipboard. Where there is no
+// clipboard tool to run, as over SSH, the terminal is asked to do it with
+// an OSC 52 escape sequence instead.
+func writeClipboard(text string) {
+	if clipboard.WriteAll(text) == nil {
+		return
+	}
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+	fmt.Fprint(os.Stderr, seq)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// builtinFormats are the input formats jt decodes itself. Any other format
+// is decoded by a plugin: an executable named jt-decode-<format> on the
+// PATH that reads the input on stdin and writes it as JSON on stdout.
+var builtinFormats = []string{"json", "ndjson", "yaml", "toml", "xml", "csv"}
+
+// decoderPlugin returns the path of the plugin that decodes the input, or
+// "" when jt decodes it itself. -in names the format; otherwise a plugin
+// is looked up for file extensions jt does not know.
+func decoderPlugin(opts jt.ParseOptions) string {
+	format := opts.Format
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(opts.Filename), "."))
+		if format == "" || jt.ExtensionFormat(opts.Filename) != "" {
+			return ""
+		}
+		path, err := exec.LookPath("jt-decode-" + format)
+		if err != nil {
+			return ""
+		}
+		return path
+	}
+	if slices.Contains(builtinFormats, format) {
+		return ""
+	}
+	path, err := exec.LookPath("jt-decode-" + format)
+	if err != nil {
+		fail(exitUsage, "unknown input format '%s': expected %s, or a jt-decode-%s plugin on the PATH", format, strings.Join(builtinFormats, "/"), format)
+	}
+	return path
+}
+
+// runDecoder feeds input to a decoder plugin and returns the JSON it
+// writes. A plugin that fails is reported with what it wrote to stderr.
+func runDecoder(path string, input []byte) []byte {
+	var stderr bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		fail(exitParse, "%s: %s", filepath.Base(path), message)
+	}
+	return output
+}
+
+// inputFormat is the format the input is read in, for output that follows
+// it. Plugins write JSON.
+func inputFormat(input []byte, opts jt.ParseOptions) string {
+	switch {
+	case decoderPlugin(opts) != "":
+		return "json"
+	case opts.Format != "":
+		return opts.Format
+	}
+	return jt.DetectFormat(input, opts.Filename)
+}
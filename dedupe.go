@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// dedupeRows collapses rows that are identical, or that agree on the given
+// columns, into the first of them, keeping the order rows first appear in.
+// With count, each row gets a count column of how many rows it stands for;
+// rows that are not objects become a value and a count.
+func dedupeRows(data interface{}, columns []string, count bool) interface{} {
+	rows, ok := data.([]interface{})
+	if !ok {
+		return data
+	}
+	var kept []interface{}
+	var counts []int
+	index := make(map[string]int)
+	for _, row := range rows {
+		key := dedupeKey(row, columns)
+		if i, ok := index[key]; ok {
+			counts[i]++
+			continue
+		}
+		index[key] = len(kept)
+		kept = append(kept, row)
+		counts = append(counts, 1)
+	}
+	if kept == nil {
+		kept = []interface{}{}
+	}
+	if !count {
+		return kept
+	}
+
+	name := "count"
+	for _, row := range kept {
+		if m, ok := row.(map[string]interface{}); ok {
+			if _, exists := m[name]; exists {
+				name = "dedupe_count"
+				break
+			}
+		}
+	}
+	for i, row := range kept {
+		n := json.Number(strconv.Itoa(counts[i]))
+		m, ok := row.(map[string]interface{})
+		if !ok {
+			kept[i] = map[string]interface{}{"value": row, name: n, jt.OrderKey: []string{"value", name}}
+			continue
+		}
+		counted := make(map[string]interface{}, len(m)+1)
+		for k, v := range m {
+			counted[k] = v
+		}
+		counted[name] = n
+		counted[jt.OrderKey] = append(append([]string{}, jt.OrderedKeys(m)...), name)
+		kept[i] = counted
+	}
+	return kept
+}
+
+// dedupeKey is what rows are compared by: the JSON of the whole row, or
+// of the values of the given columns.
+func dedupeKey(row interface{}, columns []string) string {
+	if len(columns) == 0 {
+		return jsonText(row)
+	}
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i], _ = sortValue(row, column)
+	}
+	return jsonText(values)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// leaf is a scalar (or empty container) at a path of a document.
+type leaf struct {
+	path  string
+	value interface{}
+}
+
+func leaves(data interface{}) []leaf {
+	var result []leaf
+	walkLeaves(data, "", func(path string, v interface{}) {
+		result = append(result, leaf{path: path, value: v})
+	})
+	return result
+}
+
+// diffDocuments compares a and b path by path and returns one row per
+// added, removed or changed value, in the order the paths occur in a, then
+// paths only found in b.
+func diffDocuments(a, b interface{}) []interface{} {
+	before := leaves(a)
+	after := leaves(b)
+	afterByPath := make(map[string]interface{}, len(after))
+	for _, l := range after {
+		afterByPath[l.path] = l.value
+	}
+	beforeByPath := make(map[string]bool, len(before))
+
+	var rows []interface{}
+	row := func(path, change string, old, new interface{}) {
+		rows = append(rows, map[string]interface{}{
+			"path":      path,
+			"change":    change,
+			"old":       old,
+			"new":       new,
+			jt.OrderKey: []string{"path", "change", "old", "new"},
+		})
+	}
+
+	for _, l := range before {
+		beforeByPath[l.path] = true
+		value, ok := afterByPath[l.path]
+		switch {
+		case !ok:
+			row(l.path, "removed", l.value, "")
+		case !sameValue(l.value, value) && jt.ScalarString(l.value) == jt.ScalarString(value):
+			// such as the string "true" for the boolean true
+			row(l.path, "type changed", l.value, value)
+		case !sameValue(l.value, value):
+			row(l.path, "changed", l.value, value)
+		}
+	}
+	for _, l := range after {
+		if !beforeByPath[l.path] {
+			row(l.path, "added", "", l.value)
+		}
+	}
+	return rows
+}
+
+// sameValue compares two leaves regardless of the format they were read
+// from: numbers by value, so 1.0 in YAML equals 1 in JSON, and everything
+// else by type and then by its displayed form, so the string "true" is not
+// the boolean true. Timestamps equal strings that read the same, as JSON
+// has no timestamps to write them as.
+func sameValue(a, b interface{}) bool {
+	an, aNum := a.(json.Number)
+	bn, bNum := b.(json.Number)
+	if aNum && bNum {
+		if an == bn {
+			return true
+		}
+		af, aErr := an.Float64()
+		bf, bErr := bn.Float64()
+		return aErr == nil && bErr == nil && af == bf
+	}
+	if fmt.Sprintf("%T", a) != fmt.Sprintf("%T", b) && !(isTimeOrString(a) && isTimeOrString(b)) {
+		return false
+	}
+	switch a.(type) {
+	case map[string]interface{}, []interface{}:
+		// Empty containers of the same kind
+		return true
+	}
+	return jt.ScalarString(a) == jt.ScalarString(b)
+}
+
+func isTimeOrString(v interface{}) bool {
+	switch v.(type) {
+	case time.Time, string:
+		return true
+	}
+	return false
+}
+
+// runDiff implements `jt diff <a> <b>`: both files are parsed in their own
+// format and compared structurally, and the differences rendered, or
+// printed as a patch of the given format. It exits with exitDiffers when
+// they differ, which failures never exit with.
+func runDiff(args []string, patchFormat string, popts jt.ParseOptions, opts renderOptions) {
+	if len(args) != 2 {
+		fail(exitUsage, "usage: jt diff <file> <file>")
+	}
+
+	var docs [2]interface{}
+	for i, path := range args {
+		popts.Filename = path
+		docs[i], _ = parseInput(readFile(path), popts)
+		docs[i] = redactInput(docs[i])
+	}
+
+	if patchFormat != "" {
+		patch, changed := diffPatch(docs[0], docs[1], patchFormat)
+		printEmitted(patch, "json", false, opts)
+		if changed {
+			os.Exit(exitDiffers)
+		}
+		return
+	}
+
+	rows := diffDocuments(docs[0], docs[1])
+	if len(rows) == 0 {
+		return
+	}
+	render(rows, opts, false)
+	os.Exit(exitDiffers)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSameValue(t *testing.T) {
+	tests := []struct {
+		a, b interface{}
+		want bool
+	}{
+		{json.Number("1"), json.Number("1.0"), true},
+		{"a", "a", true},
+		{nil, nil, true},
+		{"true", true, false},
+		{"1", json.Number("1"), false},
+		{"null", nil, false},
+		{map[string]interface{}{}, []interface{}{}, false},
+	}
+	for _, tt := range tests {
+		if got := sameValue(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameValue(%#v, %#v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDiffTypeChange(t *testing.T) {
+	a, _ := decodeJSON([]byte(`{"enabled":"true","port":"8080"}`))
+	b, _ := decodeJSON([]byte(`{"enabled":true,"port":8080}`))
+	rows := diffDocuments(a, b)
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want both type changes: %v", len(rows), rows)
+	}
+	for _, row := range rows {
+		if change := row.(map[string]interface{})["change"]; change != "type changed" {
+			t.Errorf("got change %v, want type changed", change)
+		}
+	}
+}
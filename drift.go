@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// driftRows compares the shapes of two documents, as -shape infers them,
+// and returns one row per generalized path whose shape changed: added and
+// removed paths, changed types and keys that became optional or required.
+// Paths under an added or removed one are left out. A change is breaking
+// when a consumer of the old shape might not cope with the new one.
+func driftRows(a, b interface{}) []interface{} {
+	oldOrder, before := shapeEntries(a)
+	newOrder, after := shapeEntries(b)
+
+	var rows []interface{}
+	var reported []string // added and removed paths
+	row := func(path, change, old, new string, breaking bool) {
+		mark := ""
+		if breaking {
+			mark = "yes"
+		}
+		rows = append(rows, map[string]interface{}{
+			"path":      path,
+			"change":    change,
+			"old":       old,
+			"new":       new,
+			"breaking":  mark,
+			jt.OrderKey: []string{"path", "change", "old", "new", "breaking"},
+		})
+	}
+	covered := func(path string) bool {
+		for _, p := range reported {
+			if strings.HasPrefix(path, p+".") || strings.HasPrefix(path, p+"[]") {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, path := range oldOrder {
+		if covered(path) {
+			continue
+		}
+		oldTypes := strings.Join(before[path].types, " | ")
+		e, ok := after[path]
+		if !ok {
+			row(path, "removed", oldTypes, "", true)
+			reported = append(reported, path)
+			continue
+		}
+		if newTypes := strings.Join(e.types, " | "); !sameTypes(before[path].types, e.types) {
+			added := slices.DeleteFunc(slices.Clone(e.types), func(t string) bool {
+				return slices.Contains(before[path].types, t)
+			})
+			change := "type changed"
+			if len(added) == 1 && added[0] == "null" {
+				change = "nullable"
+			}
+			row(path, change, oldTypes, newTypes, len(added) > 0)
+		}
+		switch wasOptional, isOptional := shapeOptional(path, before), shapeOptional(path, after); {
+		case !wasOptional && isOptional:
+			row(path, "optional", "required", "optional", true)
+		case wasOptional && !isOptional:
+			row(path, "required", "optional", "required", false)
+		}
+	}
+	for _, path := range newOrder {
+		if _, ok := before[path]; ok || covered(path) {
+			continue
+		}
+		row(path, "added", "", strings.Join(after[path].types, " | "), false)
+		reported = append(reported, path)
+	}
+	return rows
+}
+
+func sameTypes(a, b []string) bool {
+	return len(a) == len(b) && !slices.ContainsFunc(a, func(t string) bool { return !slices.Contains(b, t) })
+}
+
+// runDrift implements `jt drift <old> <new>`: the shapes of both files,
+// which may be in different formats, are compared and the changes
+// rendered. It exits with 1 when any of them is breaking.
+func runDrift(args []string, popts jt.ParseOptions, opts renderOptions) {
+	if len(args) != 2 {
+		fail(exitUsage, "usage: jt drift <old> <new>")
+	}
+
+	var docs [2]interface{}
+	for i, path := range args {
+		popts.Filename = path
+		docs[i], _ = parseInput(readFile(path), popts)
+	}
+
+	rows := driftRows(docs[0], docs[1])
+	if len(rows) == 0 {
+		return
+	}
+	render(rows, opts, false)
+	for _, row := range rows {
+		if row.(map[string]interface{})["breaking"] == "yes" {
+			os.Exit(exitError)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// parseEmbeddedJSON replaces strings that hold a serialized JSON object or
+// array, as log payloads and Kubernetes annotations often do, with the
+// value they hold, at any depth, so they can be selected into and shown as
+// nested tables.
+func parseEmbeddedJSON(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for _, k := range jt.OrderedKeys(v) {
+			v[k] = parseEmbeddedJSON(v[k])
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = parseEmbeddedJSON(item)
+		}
+	case string:
+		if value, ok := embeddedJSON(v); ok {
+			return parseEmbeddedJSON(value)
+		}
+	}
+	return data
+}
+
+// embeddedJSON decodes s when it is a JSON object or array and nothing
+// else. Other strings, including JSON numbers and strings, are left alone.
+func embeddedJSON(s string) (interface{}, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || !(s[0] == '{' && s[len(s)-1] == '}' || s[0] == '[' && s[len(s)-1] == ']') {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(s)))
+	decoder.UseNumber()
+	value, err := decodeJSONValue(decoder)
+	if err != nil {
+		return nil, false
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, false
+	}
+	return value, true
+}
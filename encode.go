@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// encodeJSON serializes data as JSON, listing keys in display order and
+// writing numbers exactly as they were read.
+func encodeJSON(data interface{}, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, data, indent, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeJSON decodes a single JSON value like jt.Parse, but records the
+// order of object keys, for responses whose fields are listed in the order
+// they were asked for.
+func decodeJSON(input []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	return decodeJSONValue(decoder)
+}
+
+func decodeJSONValue(decoder *json.Decoder) (interface{}, error) {
+	tok, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := make(map[string]interface{})
+		var order []string
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := obj[key.(string)]; !exists {
+				order = append(order, key.(string))
+			}
+			obj[key.(string)] = value
+		}
+		obj[jt.OrderKey] = order
+		_, err = decoder.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for decoder.More() {
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token()
+		return list, err
+	}
+	return tok, nil
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}, indent string, level int) error {
+	newline := func(level int) {
+		if indent != "" {
+			buf.WriteByte('\n')
+			buf.WriteString(strings.Repeat(indent, level))
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := jt.OrderedKeys(v)
+		if len(keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			newline(level + 1)
+			key, _ := json.Marshal(k)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if indent != "" {
+				buf.WriteByte(' ')
+			}
+			if err := writeJSON(buf, v[k], indent, level+1); err != nil {
+				return err
+			}
+		}
+		newline(level)
+		buf.WriteByte('}')
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			newline(level + 1)
+			if err := writeJSON(buf, item, indent, level+1); err != nil {
+				return err
+			}
+		}
+		newline(level)
+		buf.WriteByte(']')
+	case json.Number:
+		buf.WriteString(v.String())
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			// JSON has no representation for these
+			out, _ := json.Marshal(jt.ScalarString(v))
+			buf.Write(out)
+			return nil
+		}
+		buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	case time.Time:
+		out, _ := json.Marshal(jt.ScalarString(v))
+		buf.Write(out)
+	default:
+		out, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(out)
+	}
+	return nil
+}
+
+// yamlNode builds a YAML node tree for data, listing keys in display order
+// and writing numbers exactly as they were read.
+func yamlNode(v interface{}) *yaml.Node {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range jt.OrderedKeys(v) {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k},
+				yamlNode(v[k]))
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	case json.Number:
+		tag := "!!float"
+		if _, err := v.Int64(); err == nil || jt.IsDecimalInteger(v.String()) {
+			tag = "!!int"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case float64:
+		value := strconv.FormatFloat(v, 'g', -1, 64)
+		switch {
+		case math.IsNaN(v):
+			value = ".nan"
+		case math.IsInf(v, 1):
+			value = ".inf"
+		case math.IsInf(v, -1):
+			value = "-.inf"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: value}
+	case time.Time:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: jt.ScalarString(v)}
+	case []byte:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!binary", Value: base64.StdEncoding.EncodeToString(v)}
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: jt.ScalarString(v)}
+}
+
+func encodeYAML(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(yamlNode(data)); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeXML serializes data as an XML element named root, mapping the
+// conventions of parseXML back: @name keys become attributes, #text becomes
+// text, #content holds interleaved mixed content, and arrays become
+// repeated elements.
+func encodeXML(root string, data interface{}) []byte {
+	var buf bytes.Buffer
+	writeXMLElement(&buf, root, data)
+	return buf.Bytes()
+}
+
+func writeXMLElement(buf *bytes.Buffer, name string, v interface{}) {
+	if items, ok := v.([]interface{}); ok {
+		for _, item := range items {
+			writeXMLElement(buf, name, item)
+		}
+		return
+	}
+
+	buf.WriteString("<" + name)
+	m, isMap := v.(map[string]interface{})
+	var keys []string
+	if isMap {
+		keys = jt.OrderedKeys(m)
+		for _, k := range keys {
+			if strings.HasPrefix(k, "@") {
+				buf.WriteString(" " + k[1:] + `="`)
+				xml.EscapeText(buf, []byte(jt.ScalarString(m[k])))
+				buf.WriteString(`"`)
+			}
+		}
+	}
+	buf.WriteString(">")
+
+	switch {
+	case isMap:
+		for _, k := range keys {
+			switch {
+			case strings.HasPrefix(k, "@"):
+			case k == "#text":
+				xml.EscapeText(buf, []byte(jt.ScalarString(m[k])))
+			case k == "#content":
+				content, _ := m[k].([]interface{})
+				for _, item := range content {
+					if child, ok := item.(map[string]interface{}); ok {
+						for _, childName := range jt.OrderedKeys(child) {
+							writeXMLElement(buf, childName, child[childName])
+						}
+					} else {
+						xml.EscapeText(buf, []byte(jt.ScalarString(item)))
+					}
+				}
+			default:
+				writeXMLElement(buf, k, m[k])
+			}
+		}
+	case v == nil:
+	default:
+		xml.EscapeText(buf, []byte(jt.ScalarString(v)))
+	}
+	buf.WriteString("</" + name + ">")
+}
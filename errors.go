@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// Exit codes, so wrappers and CI can tell failure causes apart.
+const (
+	exitError    = 1 // I/O and other runtime failures
+	exitUsage    = 2 // invalid flags, arguments or config
+	exitParse    = 3 // input could not be parsed
+	exitSelector = 4 // selector does not match the data
+	exitRender   = 5 // output could not be rendered
+	exitInvalid  = 6 // input does not match its schema
+	exitEmpty    = 7 // result is null, false or empty (-exit-status)
+	exitDiffers  = 8 // documents differ (jt diff), or output its snapshot
+)
+
+var errorKinds = map[int]string{
+	exitError:    "error",
+	exitUsage:    "usage",
+	exitParse:    "parse",
+	exitSelector: "selector",
+	exitRender:   "render",
+	exitInvalid:  "invalid",
+	exitEmpty:    "empty",
+	exitDiffers:  "differs",
+}
+
+// errorFormat is "text" or "json", set by the -errors flag.
+var errorFormat = "text"
+
+// jsonError is the shape of errors and warnings printed with -errors json,
+// one object per line on stderr.
+type jsonError struct {
+	Level    string `json:"level"`
+	Kind     string `json:"kind"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	Message  string `json:"message"`
+	Format   string `json:"format,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+func printJSONError(e jsonError) {
+	out, _ := json.Marshal(e)
+	fmt.Fprintln(os.Stderr, string(out))
+}
+
+// fail reports an error and exits with code.
+func fail(code int, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if errorFormat == "json" {
+		printJSONError(jsonError{Level: "error", Kind: errorKinds[code], ExitCode: code, Message: msg})
+	} else {
+		fmt.Fprintln(os.Stderr, "Error: "+msg)
+	}
+	os.Exit(code)
+}
+
+// warn reports a problem that does not stop processing.
+func warn(kind string, line int, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if errorFormat == "json" {
+		printJSONError(jsonError{Level: "warning", Kind: kind, Message: msg, Line: line})
+	} else {
+		fmt.Fprintln(os.Stderr, "Warning: "+msg)
+	}
+}
+
+// failParse reports a parse error, with a source excerpt in text mode, and
+// exits.
+func failParse(err error) {
+	if errorFormat == "json" {
+		e := jsonError{Level: "error", Kind: errorKinds[exitParse], ExitCode: exitParse, Message: err.Error()}
+		if pe, ok := err.(*jt.ParseError); ok {
+			e.Message = pe.Msg
+			e.Format = pe.Format
+			e.Line = pe.Line
+			e.Column = pe.Column
+		}
+		printJSONError(e)
+	} else {
+		printParseError(err)
+	}
+	os.Exit(exitParse)
+}
+
+// printParseError prints err with an excerpt of the source around the
+// failing line.
+func printParseError(err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+
+	e, ok := err.(*jt.ParseError)
+	if !ok || e.Line == 0 {
+		return
+	}
+	lines := strings.Split(string(e.Input), "\n")
+	if e.Line > len(lines) {
+		return
+	}
+
+	first := e.Line - 2
+	if first < 1 {
+		first = 1
+	}
+	numWidth := len(strconv.Itoa(e.Line))
+	for n := first; n <= e.Line; n++ {
+		marker := " "
+		if n == e.Line {
+			marker = ">"
+		}
+		text := strings.TrimRight(lines[n-1], "\r")
+		fmt.Fprintf(os.Stderr, "%s %*d | %s\n", marker, numWidth, n, jt.TruncateWidth(text, jt.DefaultMaxWidth))
+	}
+	if e.Column > 0 {
+		prefix := []rune(strings.TrimRight(lines[e.Line-1], "\r"))
+		if e.Column-1 <= len(prefix) {
+			prefix = prefix[:e.Column-1]
+		}
+		fmt.Fprintf(os.Stderr, "  %s | %s^\n", strings.Repeat(" ", numWidth), strings.Repeat(" ", jt.DisplayWidth(string(prefix))))
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// describe summarizes a value for -explain: its type and size.
+func describe(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return "object with " + strings.TrimSuffix(strings.TrimPrefix(jt.Summary(v), "{"), "}")
+	case []interface{}:
+		summary := "array of " + strings.TrimSuffix(strings.TrimPrefix(jt.Summary(v), "["), "]")
+		if len(v) > 0 && jt.IsObjectArray(v) {
+			summary += ", all objects (one column per key)"
+		}
+		return summary
+	}
+	return typeName(v) + " " + jt.TruncateWidth(jt.ScalarString(v), 40)
+}
+
+// runExplain reports how jt arrived at its output instead of rendering it:
+// the detected input format, each step of the selector, and the size of
+// the table that would be rendered.
+func runExplain(input []byte, popts jt.ParseOptions, selector string, parsed, data interface{}, isMultiDoc bool, opts renderOptions) {
+	var rows []interface{}
+	add := func(stage, detail string) {
+		rows = append(rows, map[string]interface{}{
+			"stage":     stage,
+			"detail":    detail,
+			jt.OrderKey: []string{"stage", "detail"},
+		})
+	}
+
+	source := popts.Filename
+	if source == "" {
+		source = "stdin"
+	}
+	add("input", fmt.Sprintf("%s, %s", source, jt.FormatBytes(int64(len(input)))))
+	switch plugin := decoderPlugin(popts); {
+	case plugin != "":
+		add("format", fmt.Sprintf("decoded to JSON by %s", plugin))
+	case popts.Format != "":
+		add("format", fmt.Sprintf("%s (-in)", strings.ToUpper(popts.Format)))
+	default:
+		format, reason := jt.ExplainFormat(input, popts.Filename)
+		add("format", fmt.Sprintf("%s (%s)", strings.ToUpper(format), reason))
+	}
+
+	doc := parsed
+	if docs, ok := parsed.([]interface{}); ok && isMultiDoc {
+		add("documents", fmt.Sprintf("%d, the selector is applied to each", len(docs)))
+		doc = docs[0]
+	}
+	add("parsed", describe(doc))
+
+	var steps []string
+	switch {
+	case jt.IsFilter(selector):
+		add("selector", selector+" (a filter, evaluated as a whole)")
+	case strings.Contains(selector, "*"):
+		add("selector", selector+" (with wildcards, evaluated as a whole)")
+	case strings.HasPrefix(selector, ".."):
+		add("selector", selector+" (recursive descent, evaluated as a whole)")
+	case len(jt.SelectorSteps(selector)) == 0:
+		add("selector", ". (the whole document)")
+	default:
+		steps = jt.SelectorSteps(selector)
+		add("selector", fmt.Sprintf("%s → %s", selector, strings.Join(steps, " ")))
+	}
+	current := doc
+	fullPath := ""
+	for i, key := range steps {
+		if fullPath == "" {
+			fullPath = key
+		} else {
+			fullPath += "." + key
+		}
+		next, err := jt.SelectStep(current, key, fullPath)
+		if err != nil {
+			add(fmt.Sprintf("step %d: %s", i+1, key), "error: "+err.Error())
+			break
+		}
+		add(fmt.Sprintf("step %d: %s", i+1, key), describe(next))
+		current = next
+	}
+
+	add("result", describe(data))
+	switch opts.Format {
+	case "table", "html":
+		output := renderDocuments(data, opts, isMultiDoc)
+		lines := strings.Count(strings.TrimRight(output, "\n"), "\n") + 1
+		width := jt.ContentWidth(output)
+		detail := fmt.Sprintf("%s, %d lines × %d columns", opts.Format, lines, width)
+		if opts.Format == "table" && colorOutput(opts) {
+			if termWidth := getTerminalWidth(); width > termWidth {
+				detail += fmt.Sprintf(", wider than the terminal (%d): interactive viewer", termWidth)
+			} else {
+				detail += fmt.Sprintf(", fits the terminal (%d): printed directly", termWidth)
+			}
+		}
+		add("render", detail)
+	default:
+		add("render", opts.Format)
+	}
+
+	fmt.Print(renderDocuments(rows, renderOptions{Options: jt.Options{
+		Format:    "table",
+		MaxWidth:  jt.DefaultMaxWidth * 2,
+		Multiline: "keep",
+		Base64:    "summary",
+		MaxDepth:  jt.DefaultMaxDepth,
+	}}, false))
+}
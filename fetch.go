@@ -0,0 +1,174 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// fetchOptions controls how input given as a URL is fetched.
+type fetchOptions struct {
+	headers     []string // "Name: value"
+	tokenEnv    string   // environment variable holding a bearer token
+	origin      string   // scheme and host of the URL given, the only one the token is sent to
+	paginate    bool     // follow Link rel="next" headers
+	next        string   // selector of the next page's URL or cursor
+	cursorParam string   // query parameter a cursor is sent in
+	maxPages    int
+	popts       jt.ParseOptions
+}
+
+// fetch is set from the flags before the input is read.
+var fetch fetchOptions
+
+// defaultTokenEnv holds the bearer token sent when -token-env is not given.
+const defaultTokenEnv = "JT_TOKEN"
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+var fetchClient = &http.Client{Timeout: 60 * time.Second}
+
+// fetchPage GETs rawURL with the configured headers and returns the body
+// and the response headers.
+func fetchPage(rawURL string) ([]byte, http.Header) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		fail(exitUsage, "invalid URL %s: %v", rawURL, err)
+	}
+	return fetchRequest(req)
+}
+
+// fetchRequest sends req with the configured headers and returns the body
+// and the response headers, failing on any status but 2xx.
+func fetchRequest(req *http.Request) ([]byte, http.Header) {
+	rawURL := req.URL.String()
+	req.Header.Set("User-Agent", "jt/"+version)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json, application/yaml;q=0.9, application/xml;q=0.8, */*;q=0.5")
+	}
+	// Pages may link to other hosts, which must not see the token
+	if token := os.Getenv(fetch.tokenEnv); token != "" && urlOrigin(req.URL) == fetch.origin {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for _, h := range fetch.headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			fail(exitUsage, "invalid -header '%s' (expected Name: value)", h)
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		fail(exitError, "fetching %s: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fail(exitError, "fetching %s: %v", rawURL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		excerpt := strings.TrimSpace(jt.TruncateWidth(strings.Join(strings.Fields(string(body)), " "), 200))
+		fail(exitError, "fetching %s: %s %s", rawURL, resp.Status, excerpt)
+	}
+	return body, resp.Header
+}
+
+// fetchInput reads the input from a URL. When paginating, every page is
+// fetched and the selector applied to each; the selected arrays are joined
+// into one, returned as JSON, and the selector is used up.
+func fetchInput(rawURL, selector string) ([]byte, string) {
+	if u, err := url.Parse(rawURL); err == nil {
+		fetch.origin = urlOrigin(u)
+	}
+	if !fetch.paginate && fetch.next == "" {
+		body, _ := fetchPage(rawURL)
+		return body, selector
+	}
+
+	var rows []interface{}
+	seen := make(map[string]bool)
+	for page := 1; rawURL != ""; page++ {
+		if page > fetch.maxPages {
+			warn("max_pages", 0, "stopped after %d pages, raise -max-pages for more", fetch.maxPages)
+			break
+		}
+		seen[rawURL] = true
+		body, header := fetchPage(rawURL)
+		popts := fetch.popts
+		popts.Filename = rawURL
+		data, _ := parseInput(body, popts)
+
+		items := applySelector(data, selector)
+		if list, ok := items.([]interface{}); ok {
+			rows = append(rows, list...)
+		} else {
+			rows = append(rows, items)
+		}
+
+		next := nextPage(rawURL, header, data)
+		if seen[next] {
+			break
+		}
+		rawURL = next
+	}
+	if rows == nil {
+		rows = []interface{}{}
+	}
+	out, err := encodeJSON(rows, "")
+	if err != nil {
+		fail(exitError, "joining pages: %v", err)
+	}
+	return out, "."
+}
+
+// urlOrigin returns the scheme and host of u, the port included.
+func urlOrigin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+var linkNext = regexp.MustCompile(`<([^>]*)>\s*;[^,]*\brel="?next"?`)
+
+// nextPage returns the URL of the page after current, or "" on the last
+// page: from the Link header with -paginate, or from the -next field of the
+// page, which holds either a URL or a cursor.
+func nextPage(current string, header http.Header, data interface{}) string {
+	base, _ := url.Parse(current)
+	if fetch.next != "" {
+		v, err := jt.Select(data, fetch.next)
+		if err != nil || v == nil || jt.ScalarString(v) == "" {
+			return ""
+		}
+		next := jt.ScalarString(v)
+		if isURL(next) || strings.HasPrefix(next, "/") || strings.HasPrefix(next, "?") {
+			ref, err := url.Parse(next)
+			if err != nil {
+				return ""
+			}
+			return base.ResolveReference(ref).String()
+		}
+		query := base.Query()
+		query.Set(fetch.cursorParam, next)
+		u := *base
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	for _, link := range header.Values("Link") {
+		if m := linkNext.FindStringSubmatch(link); m != nil {
+			ref, err := url.Parse(m[1])
+			if err != nil {
+				return ""
+			}
+			return base.ResolveReference(ref).String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package main
+
+import "github.com/obegron/jt/pkg/jt"
+
+// flattenRows flattens nested objects into dotted keys (spec.replicas,
+// metadata.labels.app), up to levels deep, for each object of an array or
+// for a single object. Arrays inside are kept as values.
+func flattenRows(data interface{}, levels int) interface{} {
+	switch v := data.(type) {
+	case []interface{}:
+		rows := make([]interface{}, len(v))
+		for i, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				rows[i] = flattenMap(m, levels)
+			} else {
+				rows[i] = item
+			}
+		}
+		return rows
+	case map[string]interface{}:
+		return flattenMap(v, levels)
+	}
+	return data
+}
+
+func flattenMap(m map[string]interface{}, levels int) map[string]interface{} {
+	result := make(map[string]interface{})
+	var order []string
+	var walk func(m map[string]interface{}, prefix string, levels int)
+	walk = func(m map[string]interface{}, prefix string, levels int) {
+		for _, k := range jt.OrderedKeys(m) {
+			v := m[k]
+			if nested, ok := v.(map[string]interface{}); ok && levels > 0 && len(jt.OrderedKeys(nested)) > 0 {
+				walk(nested, prefix+k+".", levels-1)
+				continue
+			}
+			result[prefix+k] = v
+			order = append(order, prefix+k)
+		}
+	}
+	walk(m, "", levels)
+	// Keep each flattened key where its parent was
+	result[jt.OrderKey] = order
+	return result
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// graphqlResponse is the body a GraphQL server answers with.
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string        `json:"message"`
+		Path    []interface{} `json:"path"`
+	} `json:"errors"`
+}
+
+// runGraphQL implements `jt graphql <endpoint> [selector]`: it posts the
+// query in queryPath with the -var variables and renders the data of the
+// response, with connections flattened to lists of their nodes.
+func runGraphQL(args []string, queryPath string, vars []string, opts renderOptions) {
+	if len(args) < 1 || len(args) > 2 {
+		fail(exitUsage, "usage: jt graphql -query <file> [-var name=value]... <endpoint> [selector]")
+	}
+	if queryPath == "" {
+		fail(exitUsage, "jt graphql needs -query <file> with the query to run")
+	}
+	endpoint, selector := args[0], "."
+	if len(args) == 2 {
+		selector = args[1]
+	}
+	var query []byte
+	if queryPath == "-" {
+		query = readStdin()
+	} else {
+		query = readFile(queryPath)
+	}
+	variables, err := graphqlVariables(vars)
+	if err != nil {
+		fail(exitUsage, "invalid -var: %v", err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"query": string(query), "variables": variables})
+	if err != nil {
+		fail(exitError, "encoding request: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		fail(exitUsage, "invalid URL %s: %v", endpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/graphql-response+json, application/json")
+	fetch.origin = urlOrigin(req.URL)
+	out, _ := fetchRequest(req)
+
+	var response graphqlResponse
+	if err := json.Unmarshal(out, &response); err != nil {
+		fail(exitParse, "%s did not answer with a GraphQL response: %v", endpoint, err)
+	}
+	var messages []string
+	for _, e := range response.Errors {
+		message := e.Message
+		if len(e.Path) > 0 {
+			message = graphqlPath(e.Path) + ": " + message
+		}
+		messages = append(messages, message)
+	}
+	if len(response.Data) == 0 || string(response.Data) == "null" {
+		if len(messages) == 0 {
+			messages = []string{"the response has no data"}
+		}
+		fail(exitError, "%s", strings.Join(messages, "; "))
+	}
+	// Errors beside data are partial failures: the fields they name are null
+	for _, message := range messages {
+		warn("graphql", 0, "%s", message)
+	}
+
+	data, err := decodeJSON(response.Data)
+	if err != nil {
+		fail(exitParse, "reading the data of the response: %v", err)
+	}
+	data = redactInput(flattenConnections(data, ""))
+	render(applySelector(data, selector), opts, false)
+}
+
+// graphqlVariables parses name=value pairs. Values that are valid JSON, such
+// as 10, true or ["a"], are sent as such; anything else as a string.
+func graphqlVariables(vars []string) (map[string]interface{}, error) {
+	variables := make(map[string]interface{})
+	for _, v := range vars {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("'%s' (expected name=value)", v)
+		}
+		var decoded interface{}
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&decoded); err != nil || decoder.More() {
+			decoded = value
+		}
+		variables[name] = decoded
+	}
+	return variables, nil
+}
+
+func graphqlPath(path []interface{}) string {
+	var b strings.Builder
+	for _, step := range path {
+		if s, ok := step.(string); ok {
+			b.WriteString("." + s)
+		} else {
+			fmt.Fprintf(&b, "[%v]", step)
+		}
+	}
+	return b.String()
+}
+
+// connectionKeys are the fields of a Relay-style connection that
+// flattenConnections knows what to do with.
+var connectionKeys = map[string]bool{"edges": true, "nodes": true, "pageInfo": true, "totalCount": true}
+
+// flattenConnections replaces connections, objects holding the edges or
+// nodes of a paginated list, with the list of nodes. Edges carrying fields
+// besides node and cursor are kept as objects without the cursor. A
+// connection with more fields than that is left alone, and one with more
+// pages is reported, as only the fetched page is shown.
+func flattenConnections(v interface{}, path string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if nodes, ok := connectionNodes(v); ok {
+			if info, ok := v["pageInfo"].(map[string]interface{}); ok && info["hasNextPage"] == true {
+				warn("graphql_page", 0, "%s has more pages than were fetched", orRoot(path))
+			}
+			return flattenConnections(nodes, path)
+		}
+		for _, k := range jt.OrderedKeys(v) {
+			v[k] = flattenConnections(v[k], path+"."+k)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = flattenConnections(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+		return v
+	}
+	return v
+}
+
+func connectionNodes(obj map[string]interface{}) ([]interface{}, bool) {
+	keys := jt.OrderedKeys(obj)
+	for _, k := range keys {
+		if !connectionKeys[k] {
+			return nil, false
+		}
+	}
+	if nodes, ok := obj["nodes"].([]interface{}); ok {
+		return nodes, true
+	}
+	edges, ok := obj["edges"].([]interface{})
+	if !ok {
+		return nil, false
+	}
+	nodes := make([]interface{}, 0, len(edges))
+	for _, e := range edges {
+		edge, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if _, ok := edge["node"]; !ok {
+			return nil, false
+		}
+		extra := false
+		for _, k := range jt.OrderedKeys(edge) {
+			if k != "node" && k != "cursor" {
+				extra = true
+			}
+		}
+		if !extra {
+			nodes = append(nodes, edge["node"])
+			continue
+		}
+		kept := make(map[string]interface{})
+		var order []string
+		for _, k := range jt.OrderedKeys(edge) {
+			if k != "cursor" {
+				kept[k] = edge[k]
+				order = append(order, k)
+			}
+		}
+		kept[jt.OrderKey] = order
+		nodes = append(nodes, kept)
+	}
+	return nodes, true
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// parseHumanize parses a -humanize list of kind:column pairs, such as
+// bytes:size,duration:elapsed_ms, and auto to guess the kind of columns
+// from their names.
+func parseHumanize(s string) (map[string]string, bool, error) {
+	columns := make(map[string]string)
+	auto := false
+	for _, item := range splitList(s) {
+		if item == "auto" {
+			auto = true
+			continue
+		}
+		kind, column, ok := strings.Cut(item, ":")
+		if !ok || column == "" {
+			return nil, false, fmt.Errorf("expected kind:column or auto, got '%s'", item)
+		}
+		if !slices.Contains(jt.HumanizeKinds, kind) {
+			return nil, false, fmt.Errorf("unknown kind '%s' (expected %s)", kind, strings.Join(jt.HumanizeKinds, "/"))
+		}
+		columns[column] = kind
+	}
+	return columns, auto, nil
+}
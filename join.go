@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// joinSide is one of the inputs of jt join: its rows, the column they are
+// matched on and the columns found in them, in the order they first appear.
+type joinSide struct {
+	name    string
+	rows    []map[string]interface{}
+	on      string
+	columns []string
+}
+
+// runJoin implements `jt join <left> <right> -on key`: the rows of both
+// files, which may be in different formats, are matched where their key
+// columns are equal and each pair combined into one row. An inner join
+// keeps only left rows with a match; with left, the others are kept without
+// right columns. -on left=right joins columns named differently.
+func runJoin(args []string, on string, left bool, emit string, popts jt.ParseOptions, opts renderOptions) {
+	if len(args) != 2 {
+		fail(exitUsage, "usage: jt join -on <key>[=<key>] <left> <right>")
+	}
+	if on == "" {
+		fail(exitUsage, "jt join needs -on <key> to match rows by")
+	}
+	leftOn, rightOn, ok := strings.Cut(on, "=")
+	if !ok {
+		rightOn = leftOn
+	}
+
+	var sides [2]joinSide
+	for i, path := range args {
+		popts.Filename = path
+		data, _ := parseInput(readFile(path), popts)
+		sides[i] = joinRows(path, redactInput(data))
+	}
+	sides[0].on, sides[1].on = leftOn, rightOn
+
+	rows := joinSides(sides[0], sides[1], left)
+	switch emit {
+	case "":
+		render(rows, opts, false)
+	case "json", "yaml":
+		printEmitted(rows, emit, false, opts)
+	default:
+		fail(exitUsage, "invalid -emit format '%s' (expected json/yaml)", emit)
+	}
+}
+
+// joinRows takes the rows of a file: the documents of a multi-document file
+// or the elements of an array, all of which must be objects.
+func joinRows(path string, data interface{}) joinSide {
+	list, ok := data.([]interface{})
+	if !ok {
+		list = []interface{}{data}
+	}
+	side := joinSide{name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	seen := make(map[string]bool)
+	for i, item := range list {
+		row, ok := item.(map[string]interface{})
+		if !ok {
+			fail(exitError, "%s: row %d is not an object; jt join needs an array of objects", path, i+1)
+		}
+		for _, k := range jt.OrderedKeys(row) {
+			if !seen[k] {
+				seen[k] = true
+				side.columns = append(side.columns, k)
+			}
+		}
+		side.rows = append(side.rows, row)
+	}
+	return side
+}
+
+// joinSides combines every left row with every right row of the same key,
+// in the order of the left rows. The right key column is left out when it
+// has the name of the left one, and right columns whose names are taken
+// are prefixed with the name of their file.
+func joinSides(l, r joinSide, left bool) []interface{} {
+	byKey := make(map[string][]map[string]interface{})
+	for _, row := range r.rows {
+		if key, ok := joinKey(row, r.on); ok {
+			byKey[key] = append(byKey[key], row)
+		}
+	}
+
+	taken := make(map[string]bool, len(l.columns))
+	for _, c := range l.columns {
+		taken[c] = true
+	}
+	var columns, names []string
+	for _, c := range r.columns {
+		if c == r.on && r.on == l.on {
+			continue
+		}
+		name := c
+		if taken[name] {
+			name = r.name + "_" + c
+		}
+		taken[name] = true
+		columns = append(columns, c)
+		names = append(names, name)
+	}
+	order := append(append([]string{}, l.columns...), names...)
+
+	rows := []interface{}{}
+	combine := func(lrow, rrow map[string]interface{}) {
+		row := make(map[string]interface{}, len(order)+1)
+		for _, c := range l.columns {
+			if v, ok := lrow[c]; ok {
+				row[c] = v
+			}
+		}
+		for i, c := range columns {
+			if v, ok := rrow[c]; ok {
+				row[names[i]] = v
+			}
+		}
+		row[jt.OrderKey] = order
+		rows = append(rows, row)
+	}
+	for _, lrow := range l.rows {
+		key, ok := joinKey(lrow, l.on)
+		matches := byKey[key]
+		if !ok || len(matches) == 0 {
+			if left {
+				combine(lrow, nil)
+			}
+			continue
+		}
+		for _, rrow := range matches {
+			combine(lrow, rrow)
+		}
+	}
+	return rows
+}
+
+// joinKey is what rows are matched by: the displayed form of the key
+// column, with numbers compared by value so that 1 in JSON matches "1" in
+// CSV and 1.0 in YAML. Rows without the key, or with null, match nothing.
+func joinKey(row map[string]interface{}, column string) (string, bool) {
+	v, ok := sortValue(row, column)
+	if !ok {
+		return "", false
+	}
+	switch v := v.(type) {
+	case map[string]interface{}, []interface{}:
+		return jsonText(v), true
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return strconv.FormatFloat(f, 'g', -1, 64), true
+		}
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil && numberText.MatchString(v) {
+			return strconv.FormatFloat(f, 'g', -1, 64), true
+		}
+	}
+	return jt.ScalarString(v), true
+}
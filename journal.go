@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// journalPriorities names the syslog priorities journald records as
+// PRIORITY, from 0 to 7.
+var journalPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// journalColors color the priority column unless the config has rules
+// for it.
+var journalColors = jt.NewRuleList(
+	"emerg", "red",
+	"alert", "red",
+	"crit", "red",
+	"err", "red",
+	"warning", "yellow",
+	"notice", "cyan",
+	"debug", "gray",
+)
+
+// journalRows reads the output of `journalctl -o json` (one entry per
+// line) or -o json-pretty and turns each entry into a row of the fields
+// worth a column: timestamp, unit, priority and message, and the host when
+// there are several. Every field of the entry is kept under fields.
+func journalRows(input []byte) []interface{} {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	var entries []map[string]interface{}
+	hosts := make(map[string]bool)
+	for {
+		v, err := decodeJSONValue(decoder)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fail(exitParse, "reading journal entry %d: %v", len(entries)+1, err)
+		}
+		entry, ok := v.(map[string]interface{})
+		if !ok {
+			fail(exitParse, "journal entry %d is not an object: is this the output of journalctl -o json?", len(entries)+1)
+		}
+		for _, k := range jt.OrderedKeys(entry) {
+			entry[k] = journalString(entry[k])
+		}
+		entries = append(entries, entry)
+		if host, ok := entry["_HOSTNAME"].(string); ok {
+			hosts[host] = true
+		}
+	}
+
+	columns := []string{"timestamp"}
+	if len(hosts) > 1 {
+		columns = append(columns, "host")
+	}
+	columns = append(columns, "unit", "priority", "message", "fields")
+	rows := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		row := map[string]interface{}{
+			"timestamp": journalTime(entry["__REALTIME_TIMESTAMP"]),
+			"host":      entry["_HOSTNAME"],
+			"unit":      firstField(entry, "_SYSTEMD_UNIT", "_SYSTEMD_USER_UNIT", "SYSLOG_IDENTIFIER", "_COMM"),
+			"priority":  journalPriority(entry["PRIORITY"]),
+			"message":   entry["MESSAGE"],
+			"fields":    entry,
+			jt.OrderKey: columns,
+		}
+		if len(hosts) <= 1 {
+			delete(row, "host")
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// journalString decodes fields journald wrote as an array of bytes, as it
+// does for values that are not valid UTF-8 or hold control characters.
+func journalString(v interface{}) interface{} {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return v
+	}
+	b := make([]byte, len(list))
+	for i, item := range list {
+		n, ok := item.(json.Number)
+		if !ok {
+			return v
+		}
+		c, err := strconv.ParseUint(n.String(), 10, 8)
+		if err != nil {
+			return v
+		}
+		b[i] = byte(c)
+	}
+	return string(b)
+}
+
+// journalTime converts __REALTIME_TIMESTAMP, microseconds since the epoch,
+// to local time as journalctl shows it. It is written out rather than kept a
+// time.Time so an entry at midnight still shows its time of day; sorting and
+// -tz read it back as a timestamp.
+func journalTime(v interface{}) interface{} {
+	s, _ := v.(string)
+	us, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return v
+	}
+	return time.UnixMicro(us).Local().Format(time.RFC3339Nano)
+}
+
+func journalPriority(v interface{}) interface{} {
+	s, _ := v.(string)
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < len(journalPriorities) {
+		return journalPriorities[n]
+	}
+	return v
+}
+
+func firstField(entry map[string]interface{}, keys ...string) interface{} {
+	for _, k := range keys {
+		if v, ok := entry[k]; ok {
+			return v
+		}
+	}
+	return nil
+}
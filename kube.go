@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// kubeLastApplied is the annotation kubectl apply keeps a copy of the whole
+// object in.
+const kubeLastApplied = "kubectl.kubernetes.io/last-applied-configuration"
+
+// isKubectlPlugin reports whether jt runs as `kubectl jt`, i.e. from a
+// binary or symlink named kubectl-jt on the PATH.
+func isKubectlPlugin() bool {
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	return name == "kubectl-jt"
+}
+
+// stripKubeNoise removes metadata.managedFields and the last-applied
+// annotation from every Kubernetes object in data. They are bookkeeping of
+// the API server and kubectl, and usually larger than the object itself.
+func stripKubeNoise(data interface{}) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if meta, ok := v["metadata"].(map[string]interface{}); ok {
+			delete(meta, "managedFields")
+			if annotations, ok := meta["annotations"].(map[string]interface{}); ok {
+				delete(annotations, kubeLastApplied)
+				if len(jt.OrderedKeys(annotations)) == 0 {
+					delete(meta, "annotations")
+				}
+			}
+		}
+		for _, k := range jt.OrderedKeys(v) {
+			stripKubeNoise(v[k])
+		}
+	case []interface{}:
+		for _, item := range v {
+			stripKubeNoise(item)
+		}
+	}
+}
+
+// kubeItems returns the items of a List kind, such as the output of
+// `kubectl get pods -o json`. Items of typed lists (PodList) carry no kind
+// of their own, so it is filled in from the list's.
+func kubeItems(data interface{}) ([]interface{}, bool) {
+	list, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	kind, _ := list["kind"].(string)
+	items, ok := list["items"].([]interface{})
+	if !ok || !strings.HasSuffix(kind, "List") {
+		return nil, false
+	}
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok && m["kind"] == nil && kind != "List" {
+			m["kind"] = strings.TrimSuffix(kind, "List")
+		}
+	}
+	return items, true
+}
+
+// kubeColumns computes the columns shown for each kind of resource, like
+// `kubectl get -o wide` does.
+var kubeColumns = map[string]func(obj map[string]interface{}) ([]string, []interface{}){
+	"Pod":        podColumns,
+	"Deployment": deploymentColumns,
+	"Service":    serviceColumns,
+	"Node":       nodeColumns,
+}
+
+// kubeRows turns an array of Kubernetes objects into one row per object
+// with the columns of its kind: name, status, age and so on. A namespace
+// column is added when the objects span several namespaces, and a kind
+// column when they are of several kinds. Anything else is returned
+// unchanged.
+func kubeRows(data interface{}) interface{} {
+	items, ok := data.([]interface{})
+	if !ok || len(items) == 0 {
+		return data
+	}
+	kinds := make(map[string]bool)
+	namespaces := make(map[string]bool)
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return data
+		}
+		kind, _ := obj["kind"].(string)
+		if _, ok := obj["metadata"].(map[string]interface{}); !ok || kind == "" {
+			return data
+		}
+		kinds[kind] = true
+		namespaces[kubeString(obj, "metadata.namespace")] = true
+	}
+
+	rows := make([]interface{}, len(items))
+	for i, item := range items {
+		obj := item.(map[string]interface{})
+		order := []string{"name"}
+		row := map[string]interface{}{"name": kubeString(obj, "metadata.name")}
+		if len(namespaces) > 1 {
+			order = append(order, "namespace")
+			row["namespace"] = kubeString(obj, "metadata.namespace")
+		}
+		kind := obj["kind"].(string)
+		if columns, ok := kubeColumns[kind]; ok && len(kinds) == 1 {
+			names, values := columns(obj)
+			for j, name := range names {
+				order = append(order, name)
+				row[name] = values[j]
+			}
+		} else if len(kinds) > 1 {
+			order = append(order, "kind")
+			row["kind"] = kind
+		}
+		order = append(order, "age")
+		row["age"] = kubeAge(obj)
+		row[jt.OrderKey] = order
+		rows[i] = row
+	}
+	return rows
+}
+
+func podColumns(pod map[string]interface{}) ([]string, []interface{}) {
+	containers, _ := lookupList(pod, "spec.containers")
+	statuses, _ := lookupList(pod, "status.containerStatuses")
+	ready, restarts := 0, 0
+	status := kubeString(pod, "status.phase")
+	for _, s := range statuses {
+		cs, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if cs["ready"] == true {
+			ready++
+		}
+		if n, err := strconv.Atoi(jt.ScalarString(cs["restartCount"])); err == nil {
+			restarts += n
+		}
+		for _, state := range []string{"state.waiting.reason", "state.terminated.reason"} {
+			if reason := kubeString(cs, state); reason != "" {
+				status = reason
+			}
+		}
+	}
+	if _, ok := jt.LookupColumn(pod, "metadata.deletionTimestamp"); ok {
+		status = "Terminating"
+	}
+	return []string{"ready", "status", "restarts", "node", "ip"}, []interface{}{
+		fmt.Sprintf("%d/%d", ready, len(containers)),
+		status,
+		json.Number(strconv.Itoa(restarts)),
+		kubeOrNone(kubeString(pod, "spec.nodeName")),
+		kubeOrNone(kubeString(pod, "status.podIP")),
+	}
+}
+
+func deploymentColumns(d map[string]interface{}) ([]string, []interface{}) {
+	replicas := func(path string) string {
+		if n := kubeString(d, path); n != "" {
+			return n
+		}
+		return "0"
+	}
+	var images []string
+	containers, _ := lookupList(d, "spec.template.spec.containers")
+	for _, c := range containers {
+		if m, ok := c.(map[string]interface{}); ok {
+			images = append(images, kubeString(m, "image"))
+		}
+	}
+	return []string{"ready", "up-to-date", "available", "images"}, []interface{}{
+		replicas("status.readyReplicas") + "/" + replicas("spec.replicas"),
+		json.Number(replicas("status.updatedReplicas")),
+		json.Number(replicas("status.availableReplicas")),
+		kubeOrNone(strings.Join(images, ",")),
+	}
+}
+
+func serviceColumns(svc map[string]interface{}) ([]string, []interface{}) {
+	var externalIPs []string
+	ingress, _ := lookupList(svc, "status.loadBalancer.ingress")
+	for _, in := range ingress {
+		if m, ok := in.(map[string]interface{}); ok {
+			ip := kubeString(m, "ip")
+			if ip == "" {
+				ip = kubeString(m, "hostname")
+			}
+			externalIPs = append(externalIPs, ip)
+		}
+	}
+	ips, _ := lookupList(svc, "spec.externalIPs")
+	for _, ip := range ips {
+		externalIPs = append(externalIPs, jt.ScalarString(ip))
+	}
+	var ports []string
+	specPorts, _ := lookupList(svc, "spec.ports")
+	for _, p := range specPorts {
+		m, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		port := kubeString(m, "port")
+		if nodePort := kubeString(m, "nodePort"); nodePort != "" {
+			port += ":" + nodePort
+		}
+		ports = append(ports, port+"/"+kubeString(m, "protocol"))
+	}
+	return []string{"type", "cluster-ip", "external-ip", "ports"}, []interface{}{
+		kubeString(svc, "spec.type"),
+		kubeOrNone(kubeString(svc, "spec.clusterIP")),
+		kubeOrNone(strings.Join(externalIPs, ",")),
+		kubeOrNone(strings.Join(ports, ",")),
+	}
+}
+
+func nodeColumns(node map[string]interface{}) ([]string, []interface{}) {
+	status := "NotReady"
+	conditions, _ := lookupList(node, "status.conditions")
+	for _, c := range conditions {
+		if m, ok := c.(map[string]interface{}); ok && m["type"] == "Ready" && m["status"] == "True" {
+			status = "Ready"
+		}
+	}
+	if v, ok := jt.LookupColumn(node, "spec.unschedulable"); ok && v == true {
+		status += ",SchedulingDisabled"
+	}
+	var roles []string
+	if labels, ok := jt.LookupColumn(node, "metadata.labels"); ok {
+		if m, ok := labels.(map[string]interface{}); ok {
+			for _, label := range jt.OrderedKeys(m) {
+				if role, ok := strings.CutPrefix(label, "node-role.kubernetes.io/"); ok {
+					roles = append(roles, role)
+				}
+			}
+		}
+	}
+	sort.Strings(roles)
+	internalIP := ""
+	addresses, _ := lookupList(node, "status.addresses")
+	for _, a := range addresses {
+		if m, ok := a.(map[string]interface{}); ok && m["type"] == "InternalIP" {
+			internalIP = kubeString(m, "address")
+		}
+	}
+	return []string{"status", "roles", "version", "internal-ip"}, []interface{}{
+		status,
+		kubeOrNone(strings.Join(roles, ",")),
+		kubeString(node, "status.nodeInfo.kubeletVersion"),
+		kubeOrNone(internalIP),
+	}
+}
+
+// kubeAge is the time since the object was created, shortened the way
+// kubectl does: 45s, 12m, 5h, 3d, 2y.
+func kubeAge(obj map[string]interface{}) interface{} {
+	created, ok := jt.LookupColumn(obj, "metadata.creationTimestamp")
+	if !ok {
+		return "<unknown>"
+	}
+	t, ok := created.(time.Time)
+	if !ok {
+		if t, ok = parseTimestamp(jt.ScalarString(created)); !ok {
+			return "<unknown>"
+		}
+	}
+	d := time.Since(t)
+	switch {
+	case d < 0:
+		return "<invalid>"
+	case d < 2*time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < 2*time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 2*365*24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%dy", int(d.Hours()/24/365))
+}
+
+// kubeString returns the scalar at a dotted path of obj, or "" if there is
+// none.
+func kubeString(obj map[string]interface{}, path string) string {
+	v, ok := jt.LookupColumn(obj, path)
+	if !ok || v == nil {
+		return ""
+	}
+	return jt.ScalarString(v)
+}
+
+func lookupList(obj map[string]interface{}, path string) ([]interface{}, bool) {
+	v, _ := jt.LookupColumn(obj, path)
+	list, ok := v.([]interface{})
+	return list, ok
+}
+
+// kubeOrNone shows empty values as kubectl does.
+func kubeOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
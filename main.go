@@ -1,36 +1,26 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
-	"encoding/xml"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"sort"
-	"strconv"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/olekukonko/tablewriter"
-	"github.com/olekukonko/tablewriter/renderer"
-	"github.com/olekukonko/tablewriter/tw"
 	"golang.org/x/term"
-	"gopkg.in/yaml.v3"
+
+	"github.com/obegron/jt/pkg/jt"
 )
 
 var (
-	headerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ca9ee6"))
-	keyStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#c6d0f5"))
-	stringStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#a6d189"))
-	boolStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#ea999c"))
-	intStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
-
 	statusBarStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#c6d0f5")).
 			Background(lipgloss.Color("#414559")).
@@ -51,15 +41,17 @@ var (
 				Foreground(lipgloss.Color("#232634"))
 )
 
-const maxValueWidth = 80
-
 type searchMatch struct {
 	line int
-	col  int
+	col  int // byte offsets into the plain (unstyled) line
+	end  int
 	text string
 }
 
 type model struct {
+	data         interface{} // parsed data, kept so the table can be re-rendered
+	opts         renderOptions
+	isMultiDoc   bool
 	viewport     viewport.Model
 	content      []string // lines of content
 	plainContent []string // content without ANSI codes for searching
@@ -72,6 +64,20 @@ type model struct {
 	searchTerm   string
 	matches      []searchMatch
 	currentMatch int
+	tree         *treeView // the data as a tree, while it is shown instead of the table
+	notice       string    // shown in the status bar until the next key
+	sortMode     bool
+	sortInput    textinput.Model
+	sortSpec     string // the columns the rows are sorted by, as for -sort-by
+	filterMode   bool
+	filterInput  textinput.Model
+	filterTerm   string        // rows without it are hidden
+	rows         []interface{} // the rows in input order, once sorted or filtered in the viewer
+	rowFields    [][]rowField  // the values of each row and their keys, for the filter
+	filterScope  string        // what the filter term is matched against, see filterScopeFor
+	scopeTerm    string        // the term filterScope was found for
+	order        []int         // the input index of each row shown
+	cells        [][]jt.Cell   // the values on each line of the table, once one is copied
 }
 
 func (m model) Init() tea.Cmd {
@@ -95,6 +101,24 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case tea.KeyMsg:
+		if m.filterMode {
+			return m, m.filterKey(msg)
+		}
+		if m.sortMode {
+			switch msg.String() {
+			case "esc":
+				m.sortMode = false
+				m.sortInput.Blur()
+			case "enter":
+				m.sortMode = false
+				m.sortInput.Blur()
+				m.sortBy(m.sortInput.Value())
+			default:
+				m.sortInput, cmd = m.sortInput.Update(msg)
+				return m, cmd
+			}
+			return m, nil
+		}
 		if m.searchMode {
 			switch msg.String() {
 			case "esc":
@@ -117,6 +141,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, cmd
 			}
 		} else {
+			m.notice = ""
+			if m.tree != nil && m.treeKey(msg.String()) {
+				return m, nil
+			}
 			switch msg.String() {
 			case "q", "ctrl+c":
 				return m, tea.Quit
@@ -139,6 +167,28 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.viewport.SetContent(m.renderContent())
 				}
 				return m, nil
+			case "b":
+				if m.opts.Base64 == "decode" {
+					m.opts.Base64 = "summary"
+				} else {
+					m.opts.Base64 = "decode"
+				}
+				if m.tree != nil {
+					m.showTree()
+				} else {
+					m.setOutput(renderDocuments(m.data, m.opts, m.isMultiDoc))
+				}
+				return m, nil
+			case "t":
+				m.toggleTree()
+				return m, nil
+			case "y", "Y", "c":
+				m.copyKey(msg.String())
+				return m, nil
+			case "s":
+				return m, m.openSort()
+			case "f":
+				return m, m.openFilter()
 			case "l", "right":
 				m.viewport.ScrollRight(5)
 			case "h", "left":
@@ -156,127 +206,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *model) findMatches() {
-	m.matches = []searchMatch{}
-	if m.searchTerm == "" {
-		return
-	}
-
-	searchLower := strings.ToLower(m.searchTerm)
-	for lineNum, line := range m.plainContent {
-		lineLower := strings.ToLower(line)
-		col := 0
-		for {
-			idx := strings.Index(lineLower[col:], searchLower)
-			if idx == -1 {
-				break
-			}
-			actualCol := col + idx
-			m.matches = append(m.matches, searchMatch{
-				line: lineNum,
-				col:  actualCol,
-				text: m.searchTerm,
-			})
-			col = actualCol + 1
-		}
-	}
-}
-
-// xml
-func parseXML(input []byte) (interface{}, error) {
-	decoder := xml.NewDecoder(bytes.NewReader(input))
-	var result interface{}
-	foundStartElement := false // New flag
-
-	for {
-		token, err := decoder.Token()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		if se, ok := token.(xml.StartElement); ok {
-			result = parseXMLElement(decoder, se)
-			foundStartElement = true // Set flag
-			break
-		}
+// setOutput replaces the displayed table, keeping the current search.
+func (m *model) setOutput(output string) {
+	m.content = strings.Split(output, "\n")
+	m.cells = nil
+	m.plainContent = make([]string, len(m.content))
+	for i, line := range m.content {
+		m.plainContent[i] = stripANSI(line)
 	}
+	m.contentWidth = jt.ContentWidth(output)
 
-	if !foundStartElement && result == nil { // If no start element found and result is still nil
-		return nil, fmt.Errorf("no XML start element found") // Return an explicit error
+	m.findMatches()
+	if m.currentMatch >= len(m.matches) {
+		m.currentMatch = 0
 	}
-
-	return result, nil
-}
-
-func parseXMLElement(decoder *xml.Decoder, start xml.StartElement) interface{} {
-	children := make(map[string][]interface{})
-	var text strings.Builder
-	hasAttributes := len(start.Attr) > 0
-
-	// Handle attributes
-	var attrs map[string]interface{}
-	if hasAttributes {
-		attrs = make(map[string]interface{})
-		for _, attr := range start.Attr {
-			attrs["@"+attr.Name.Local] = attr.Value
-		}
-	}
-
-	for {
-		token, err := decoder.Token()
-		if err != nil {
-			break
-		}
-
-		switch t := token.(type) {
-		case xml.StartElement:
-			child := parseXMLElement(decoder, t)
-			children[t.Name.Local] = append(children[t.Name.Local], child)
-		case xml.CharData:
-			text.Write(t)
-		case xml.EndElement:
-			textContent := strings.TrimSpace(text.String())
-
-			// If we have no children and no attributes, just return text
-			if len(children) == 0 && !hasAttributes {
-				if textContent != "" {
-					return textContent
-				}
-				return ""
-			}
-
-			// Build result map
-			result := make(map[string]interface{})
-
-			// Add attributes first (prefixed with @)
-			if hasAttributes {
-				for k, v := range attrs {
-					result[k] = v
-				}
-			}
-
-			// Add children
-			for key, values := range children {
-				if len(values) == 1 {
-					result[key] = values[0]
-				} else {
-					result[key] = values
-				}
-			}
-
-			// Add text content if present
-			if textContent != "" {
-				result["#text"] = textContent
-			}
-
-			return result
-		}
-	}
-
-	return nil
+	m.viewport.SetContent(m.renderContent())
 }
 
 func (m *model) jumpToMatch() {
@@ -285,72 +229,19 @@ func (m *model) jumpToMatch() {
 	}
 	match := m.matches[m.currentMatch]
 	m.viewport.SetYOffset(match.line)
-}
-
-func (m *model) renderContent() string {
-	if m.searchTerm == "" {
-		return strings.Join(m.content, "\n")
+	if m.tree != nil {
+		// the cursor follows the match; only the highlighted line changes
+		m.tree.cursor = match.line
+		m.content = m.tree.lines(true)
 	}
 
-	highlightedLines := make([]string, len(m.content))
-	copy(highlightedLines, m.content)
-
-	// Group matches by line for efficient highlighting
-	matchesByLine := make(map[int][]searchMatch)
-	for _, match := range m.matches {
-		matchesByLine[match.line] = append(matchesByLine[match.line], match)
-	}
-
-	// Highlight each line with matches
-	for lineNum, matches := range matchesByLine {
-		if lineNum >= len(m.plainContent) {
-			continue
-		}
-		line := m.plainContent[lineNum]
-
-		// Sort matches by column to process left to right
-		sort.Slice(matches, func(i, j int) bool {
-			return matches[i].col < matches[j].col
-		})
-
-		// Build highlighted line
-		var result strings.Builder
-		lastPos := 0
-
-		for i, match := range matches {
-			// Add text before match
-			if match.col > lastPos {
-				result.WriteString(line[lastPos:match.col])
-			}
-
-			// Add highlighted match
-			matchText := line[match.col : match.col+len(m.searchTerm)]
-			isCurrentMatch := false
-			for j, currentMatch := range m.matches {
-				if j == m.currentMatch && currentMatch.line == lineNum && currentMatch.col == match.col {
-					isCurrentMatch = true
-					break
-				}
-			}
-
-			if isCurrentMatch {
-				result.WriteString(currentMatchStyle.Render(matchText))
-			} else {
-				result.WriteString(highlightStyle.Render(matchText))
-			}
-
-			lastPos = match.col + len(m.searchTerm)
-
-			// Add remaining text after last match
-			if i == len(matches)-1 && lastPos < len(line) {
-				result.WriteString(line[lastPos:])
-			}
-		}
-
-		highlightedLines[lineNum] = result.String()
+	// Scroll horizontally so the match is visible, measuring in cells
+	matchCol := jt.DisplayWidth(m.plainContent[match.line][:match.col])
+	if matchCol < m.viewport.Width {
+		m.viewport.SetXOffset(0)
+	} else {
+		m.viewport.SetXOffset(matchCol - m.viewport.Width/2)
 	}
-
-	return strings.Join(highlightedLines, "\n")
 }
 
 func (m model) View() string {
@@ -359,9 +250,15 @@ func (m model) View() string {
 	}
 
 	var statusText string
-	if m.searchTerm != "" && len(m.matches) > 0 {
+	if m.tree != nil {
+		// the path comes first, so a narrow terminal does not cut it off
+		statusText = "Path: " + m.tree.current().path + " | ↑↓/kj: move | enter/space: expand/collapse | ←→/hl: close/open | y/Y/c: copy value/row/path | t: table | /: search | q: quit"
+		if m.searchTerm != "" && len(m.matches) > 0 {
+			statusText += fmt.Sprintf(" | n/p: next/prev match | Match: %d/%d", m.currentMatch+1, len(m.matches))
+		}
+	} else if m.searchTerm != "" && len(m.matches) > 0 {
 		statusText = fmt.Sprintf(
-			"↑↓/kj: vertical | ←→/hl: horizontal | g/G: jump | n/p: next/prev match | /: search | q: quit | Match: %d/%d | Line: %d/%d",
+			"↑↓/kj: vertical | ←→/hl: horizontal | g/G: jump | n/p: next/prev match | y/Y/c: copy value/row/path | s: sort | f: filter | b: base64 | t: tree | /: search | q: quit | Match: %d/%d | Line: %d/%d",
 			m.currentMatch+1,
 			len(m.matches),
 			m.viewport.YOffset+1,
@@ -369,24 +266,49 @@ func (m model) View() string {
 		)
 	} else if m.searchTerm != "" {
 		statusText = fmt.Sprintf(
-			"↑↓/kj: vertical | ←→/hl: horizontal | g/G: jump | /: search | q: quit | No matches | Line: %d/%d",
+			"↑↓/kj: vertical | ←→/hl: horizontal | g/G: jump | s: sort | f: filter | b: base64 | t: tree | /: search | q: quit | No matches | Line: %d/%d",
 			m.viewport.YOffset+1,
 			len(m.content),
 		)
 	} else {
 		statusText = fmt.Sprintf(
-			"↑↓/kj: vertical | ←→/hl: horizontal | g/G: jump | /: search | q: quit | Line: %d/%d",
+			"↑↓/kj: vertical | ←→/hl: horizontal | g/G: jump | s: sort | f: filter | b: base64 | t: tree | /: search | q: quit | Line: %d/%d",
 			m.viewport.YOffset+1,
 			len(m.content),
 		)
 	}
 
+	if m.sortSpec != "" && m.tree == nil {
+		statusText = "Sorted by: " + m.sortSpec + " | " + statusText
+	}
+	if m.filterTerm != "" && m.tree == nil {
+		statusText = fmt.Sprintf("Rows: %d/%d | ", len(m.order), len(m.rows)) + statusText
+	}
+	if m.filterMode {
+		// before the first key the rows are still those of the input
+		rows, _ := m.inputRows()
+		shown := len(rows)
+		if m.rows != nil {
+			shown = len(m.order)
+		}
+		statusText = "Filter: " + m.filterInput.View() +
+			fmt.Sprintf(" | Rows: %d/%d | enter: keep | esc: clear", shown, len(rows))
+	}
+	if m.notice != "" {
+		statusText = m.notice
+	}
 	statusBar := statusBarStyle.Render(statusText)
 
 	view := m.viewport.View() + "\n" + statusBar
 
-	if m.searchMode {
+	if m.searchMode || m.sortMode {
 		searchBox := searchBoxStyle.Render("Search: " + m.searchInput.View())
+		if m.sortMode {
+			rows, _ := m.inputRows()
+			searchBox = searchBoxStyle.Render("Sort by: " + m.sortInput.View() + "\n\n" +
+				"Columns: " + strings.Join(rowColumns(rows), ", ") + "\n" +
+				"Add :desc to reverse a column; leave empty for input order")
+		}
 
 		// Place search box in center of screen
 		view = lipgloss.Place(
@@ -405,16 +327,450 @@ func (m model) View() string {
 }
 
 func main() {
-	format := flag.String("format", "table", "Output format table/html")
+	format := flag.String("format", envOr("JT_FORMAT", "table"), "Output format table/html/markdown/pretty/json/yaml")
 	details := flag.Bool("d", false, "Show details (caption)")
-	maxWidth := flag.Int("w", maxValueWidth, "Maximum width for values")
-	flag.Parse()
+	maxWidth := flag.Int("w", jt.DefaultMaxWidth, "Maximum width for values")
+	truncInfo := flag.Bool("trunc-info", false, "Follow truncated values with their length and SHA-256 prefix, to compare them between runs")
+	fit := flag.Bool("fit", false, "Shrink and wrap wide columns so tables fit the terminal")
+	multiline := flag.String("multiline", "collapse", "Multi-line strings: collapse/keep/marker")
+	base64Mode := flag.String("base64", "summary", "Base64 and binary values: summary/decode/raw")
+	configPath := flag.String("config", defaultConfigPath(), "Path to config file")
+	xmlRaw := flag.Bool("xml-raw", false, "Show XML CDATA sections and entity references as written")
+	var from string
+	flag.StringVar(&from, "from", "auto", "Input format auto/json/ndjson/yaml/toml/xml/csv, or <format> for a jt-decode-<format> plugin")
+	flag.StringVar(&from, "in", "auto", "Input format auto/json/ndjson/yaml/toml/xml/csv, or <format> for a jt-decode-<format> plugin")
+	yamlKeepMerge := flag.Bool("yaml-keep-merge", false, "Show YAML merge keys (<<) instead of resolving them")
+	strict := flag.Bool("strict", false, "Fail on duplicate keys instead of warning")
+	viewer := flag.String("viewer", envOr("JT_VIEWER", "tui"), "How to show output wider than the terminal: "+strings.Join(viewerModes, "/"))
+	tree := flag.Bool("tree", false, "Show the data as a collapsible tree instead of nested tables")
+	themeName := flag.String("theme", envOr("JT_THEME", "dark"), "Color theme "+jt.ThemeNames())
+	maxDepth := flag.Int("max-depth", jt.DefaultMaxDepth, "Maximum nesting depth of documents")
+	decodeBase64 := flag.String("decode-base64", "", "Comma-separated keys or key paths whose values are decoded from base64, e.g. 'data.*' for a Kubernetes Secret")
+	parseJSON := flag.Bool("parse-json", false, "Parse strings holding JSON objects or arrays into nested values")
+	coerce := flag.Bool("coerce", false, "Convert strings holding numbers, booleans, timestamps or dates to those types")
+	tz := flag.String("tz", "", "Convert timestamps to a timezone: local/utc/<zone>")
+	errorsFlag := flag.String("errors", "text", "Error output format text/json")
+	verify := flag.Bool("verify", false, "Re-serialize the input and report anything a round trip would lose")
+	caption := flag.String("caption", "", "Caption for the table; {filename}, {selector}, {doc_index} and {doc_count} are replaced")
+	programFile := flag.String("f", "", "Read the selector from a file")
+	explain := flag.Bool("explain", false, "Report how the input was detected, selected and rendered instead of rendering it")
+	redactFlag := flag.String("redact", os.Getenv("JT_REDACT"), "Comma-separated keys or key paths whose values are masked, with * wildcards, e.g. 'password,token,*secret*'")
+	patchPath := flag.String("patch", "", "Apply a JSON Patch or JSON Merge Patch file to the input before selecting from it")
+	schemaPath := flag.String("schema", "", "Validate the input against a JSON Schema and show the violations")
+	grep := flag.String("grep", "", "List the keys and values containing a term, with their line in the input")
+	quickfix := flag.Bool("quickfix", false, "Print -schema violations or -grep matches as file:line:col: message, for editors")
+	showVersion := flag.Bool("version", false, "Print version and build information")
+	var outputPath string
+	flag.StringVar(&outputPath, "o", "", "Write output to a file instead of stdout")
+	flag.StringVar(&outputPath, "output", "", "Write output to a file instead of stdout")
+	copyOutput := flag.Bool("copy", false, "Also copy the output to the clipboard")
+	copyOnly := flag.Bool("copy-only", false, "Copy the output to the clipboard instead of printing it")
+	mergeArrays := flag.String("merge-arrays", "replace", "How jt merge combines arrays: replace/append/index")
+	to := flag.String("to", "", "Format jt convert emits: json/yaml")
+	emit := flag.String("emit", "", "Print the result of jt merge or jt join as json/yaml instead of a table")
+	joinOn := flag.String("on", "", "Column jt join matches rows by, or left=right for columns named differently")
+	joinLeft := flag.Bool("left", false, "Make jt join a left join: keep left rows without a match")
+	emitPatch := flag.String("emit-patch", "", "Print the differences jt diff finds as a json-patch (RFC 6902) or merge-patch (RFC 7386)")
+	var raw bool
+	flag.BoolVar(&raw, "r", false, "Print a selected string or number bare, without a table")
+	flag.BoolVar(&raw, "raw", false, "Print a selected string or number bare, without a table")
+	columns := flag.String("columns", "", "Comma-separated columns to show for arrays of objects, e.g. name,status")
+	excludeColumns := flag.String("exclude-columns", "", "Comma-separated keys to leave out of tables, e.g. metadata,managedFields")
+	transpose := flag.Bool("transpose", false, "Swap rows and columns of the top-level table")
+	levels := flag.Int("depth", 0, "Show at most N levels of nested tables, summarizing deeper values")
+	flatten := flag.Int("flatten", 0, "Flatten nested objects into dotted columns, up to N levels deep")
+	headerCase := flag.String("header-case", "none", "Column header style: "+strings.Join(jt.HeaderCases, "/"))
+	noHeader := flag.Bool("no-header", false, "Leave out the header row of the table")
+	noIndex := flag.Bool("no-index", false, "Leave out the [key] index column of array tables")
+	maxRows := flag.Int("max-rows", jt.DefaultMaxRows, "Show at most N rows per table, 0 for all; the default applies to tables shown on a terminal")
+	bars := flag.Int("bars", 0, "Draw bars N cells wide next to the numbers of numeric columns, and arrays of numbers as sparklines")
+	humanize := flag.String("humanize", "", "Show numbers of columns as bytes or durations, e.g. bytes:size,duration:elapsed_ms, or auto to guess from column names")
+	limit := flag.Int("limit", 0, "Show at most N rows of the selected array")
+	offset := flag.Int("offset", 0, "Skip the first N rows of the selected array")
+	var where stringList
+	flag.Var(&where, "where", "Keep array rows matching a condition, e.g. status=Running or size>100 (repeatable)")
+	var maps stringList
+	flag.Var(&maps, "map", "Compute fields of each row, e.g. 'row.total = row.price * row.qty' (repeatable)")
+	var addedColumns stringList
+	flag.Var(&addedColumns, "add-column", "Add a column computed from each row with a -map expression, e.g. 'ratio=.used / .total' (repeatable)")
+	dedupe := flag.Bool("dedupe", false, "Collapse identical rows of the selected array into one")
+	dedupeBy := flag.String("dedupe-by", "", "Collapse rows that agree on comma-separated columns into the first, e.g. user,action")
+	dedupeCount := flag.Bool("dedupe-count", false, "Collapse identical rows and add a count column of how many each stands for")
+	sortBy := flag.String("sort-by", "", "Sort array rows by comma-separated columns, e.g. age:desc,name")
+	stats := flag.Bool("stats", false, "Print per-column statistics of the selected array instead of its rows")
+	shape := flag.Bool("shape", false, "Print the inferred structure of the selected value instead of its data")
+	var exitStatus bool
+	flag.BoolVar(&exitStatus, "e", false, "Exit with code 7 if the result is null, false or empty")
+	flag.BoolVar(&exitStatus, "exit-status", false, "Exit with code 7 if the result is null, false or empty")
+	count := flag.Bool("count", false, "Print the number of elements or keys of the selected value")
+	keysOnly := flag.Bool("keys-only", false, "Print the keys of the selected object (or indices of an array), one per line")
+	valuesOnly := flag.Bool("values-only", false, "Print the values of the selected object or array, one per line")
+	kube := flag.Bool("kube", isKubectlPlugin(), "Kubernetes mode: unwrap List kinds, hide managedFields and last-applied annotations, show kubectl-like columns")
+	journal := flag.Bool("journal", false, "Read journalctl -o json output as log rows: timestamp, unit, priority, message and all fields")
+	listen := flag.String("listen", "localhost:8080", "Address jt serve listens on; :8080 for every network interface")
+	presetName := flag.String("preset", "", "Apply a bundle of selector and flags for a common command's output, e.g. helm-list")
+	paths := flag.Bool("paths", false, "Print every leaf path and its value, tab-separated, e.g. for fzf")
+	pick := flag.Bool("pick", false, "Read a path chosen from -paths output on stdin and show the value at it")
+	var headers stringList
+	flag.Var(&headers, "header", "HTTP header for URL input, e.g. 'Accept: application/json' (repeatable)")
+	tokenEnv := flag.String("token-env", defaultTokenEnv, "Environment variable with a bearer token sent with URL input")
+	paginate := flag.Bool("paginate", false, "Follow Link rel=next headers of URL input and join the pages")
+	next := flag.String("next", "", "Selector of the next page's URL or cursor in each page of URL input, e.g. .meta.next_cursor")
+	cursorParam := flag.String("cursor-param", "cursor", "Query parameter a cursor from -next is sent in")
+	maxPages := flag.Int("max-pages", 100, "Fetch at most N pages with -paginate or -next")
+	graphqlQuery := flag.String("query", "", "File with the query jt graphql runs, - for stdin")
+	var graphqlVars stringList
+	flag.Var(&graphqlVars, "var", "Variable for jt graphql's query, e.g. first=10 (repeatable)")
+	snapshotDir := flag.String("snapshot", "", "Record the output, without colors, in a file in this directory instead of showing it")
+	verifySnapshot := flag.String("verify-snapshot", "", "Compare the output to the one recorded with -snapshot in this directory, exit 8 if it changed")
+	watch := flag.Int("watch", 0, "Re-run the command after -- every N seconds and redraw its output")
+	flag.Usage = usage
+	parseFlags()
+
+	subcommand := parseSubcommand()
+
+	var p preset
+	if *presetName != "" {
+		p = loadPreset(*presetName, *configPath)
+		applyPreset(*presetName, p)
+	}
+
+	if *showVersion {
+		fmt.Println(versionString())
+		return
+	}
+
+	errorFormat = *errorsFlag
+	if errorFormat != "text" && errorFormat != "json" {
+		errorFormat = "text"
+		fail(exitUsage, "invalid -errors format '%s' (expected text/json)", *errorsFlag)
+	}
+
+	if err := jt.ApplyTheme(*themeName); err != nil {
+		fail(exitUsage, "%v", err)
+	}
+	var renderer string
+	if !slices.Contains(builtinRenderers, *format) {
+		renderer = rendererPlugin(*format)
+	}
+	switch *multiline {
+	case "collapse", "keep", "marker":
+	default:
+		fail(exitUsage, "invalid -multiline mode '%s' (expected collapse/keep/marker)", *multiline)
+	}
+	switch *base64Mode {
+	case "summary", "decode", "raw":
+	default:
+		fail(exitUsage, "invalid -base64 mode '%s' (expected summary/decode/raw)", *base64Mode)
+	}
+	if !slices.Contains(viewerModes, *viewer) {
+		fail(exitUsage, "invalid -viewer '%s' (expected %s)", *viewer, strings.Join(viewerModes, "/"))
+	}
+	if !slices.Contains(jt.HeaderCases, *headerCase) {
+		fail(exitUsage, "invalid -header-case '%s' (expected %s)", *headerCase, strings.Join(jt.HeaderCases, "/"))
+	}
+	if *decodeBase64 != "" && !flagSet("base64") {
+		*base64Mode = "decode"
+	}
+	humanized, humanizeAuto, err := parseHumanize(*humanize)
+	if err != nil {
+		fail(exitUsage, "invalid -humanize: %v", err)
+	}
+	cfg := loadConfig(*configPath)
+	if _, ok := cfg.Colors["priority"]; *journal && !ok {
+		if cfg.Colors == nil {
+			cfg.Colors = make(map[string]jt.RuleList)
+		}
+		cfg.Colors["priority"] = journalColors
+	}
+	jt.SetColorRules(cfg.Colors)
+	if cfg.MaxRows != nil && !flagSet("max-rows") {
+		*maxRows = *cfg.MaxRows
+	}
+
+	// auto leaves the format to be detected
+	if from = strings.ToLower(from); from == "auto" {
+		from = ""
+	}
+	popts := jt.ParseOptions{
+		Format:        from,
+		XMLRaw:        *xmlRaw,
+		YAMLKeepMerge: *yamlKeepMerge,
+		Strict:        *strict,
+		MaxDepth:      *maxDepth,
+	}
+	opts := renderOptions{
+		Options: jt.Options{
+			Format:         *format,
+			Details:        *details,
+			MaxWidth:       *maxWidth,
+			Multiline:      *multiline,
+			Base64:         *base64Mode,
+			MaxDepth:       *maxDepth,
+			Levels:         *levels,
+			Columns:        splitList(*columns),
+			Transpose:      *transpose,
+			HeaderCase:     *headerCase,
+			NoHeader:       *noHeader,
+			NoIndex:        *noIndex,
+			MaxRows:        *maxRows,
+			Bars:           *bars,
+			Humanize:       humanized,
+			HumanizeAuto:   humanizeAuto,
+			TruncationInfo: *truncInfo,
+		},
+		fit:      *fit,
+		output:   outputPath,
+		copy:     *copyOutput || *copyOnly,
+		copyOnly: *copyOnly,
+		viewer:   *viewer,
+		tree:     *tree,
+		renderer: renderer,
+	}
+	for _, key := range splitList(*excludeColumns) {
+		if opts.Exclude == nil {
+			opts.Exclude = make(map[string]bool)
+		}
+		opts.Exclude[key] = true
+	}
+
+	patterns, err := parseKeyPatterns(splitList(*redactFlag))
+	if err != nil {
+		fail(exitUsage, "invalid -redact: %v", err)
+	}
+	redactPatterns = patterns
+	decodePatterns, err := parseKeyPatterns(splitList(*decodeBase64))
+	if err != nil {
+		fail(exitUsage, "invalid -decode-base64: %v", err)
+	}
+
+	fetch = fetchOptions{
+		headers:     headers,
+		tokenEnv:    *tokenEnv,
+		paginate:    *paginate,
+		next:        *next,
+		cursorParam: *cursorParam,
+		maxPages:    *maxPages,
+		popts:       popts,
+	}
+
+	if *snapshotDir != "" || *verifySnapshot != "" {
+		switch {
+		case *snapshotDir != "" && *verifySnapshot != "":
+			fail(exitUsage, "-snapshot and -verify-snapshot cannot be combined")
+		case subcommand != "view" && subcommand != "get" && subcommand != "convert":
+			fail(exitUsage, "snapshots cannot be recorded of jt %s", subcommand)
+		}
+	}
+
+	switch subcommand {
+	case "diff":
+		if *emitPatch != "" && !slices.Contains(patchFormats, *emitPatch) {
+			fail(exitUsage, "invalid -emit-patch '%s' (expected %s)", *emitPatch, strings.Join(patchFormats, "/"))
+		}
+		runDiff(flag.Args(), *emitPatch, popts, opts)
+		return
+	case "drift":
+		runDrift(flag.Args(), popts, opts)
+		return
+	case "merge":
+		runMerge(flag.Args(), *mergeArrays, *emit, popts, opts)
+		return
+	case "join":
+		runJoin(flag.Args(), *joinOn, *joinLeft, *emit, popts, opts)
+		return
+	case "sql":
+		runSQL(flag.Args(), popts, opts)
+		return
+	case "sqlq":
+		runSQLQuery(flag.Args(), opts)
+		return
+	case "mcp":
+		runMCP(popts, opts)
+		return
+	case "graphql":
+		runGraphQL(flag.Args(), *graphqlQuery, graphqlVars, opts)
+		return
+	case "serve":
+		runServe(flag.Args(), *listen, *caption, popts, opts)
+		return
+	case "convert":
+		if *to != "json" && *to != "yaml" {
+			fail(exitUsage, "jt convert needs -to json or -to yaml")
+		}
+	}
+
+	steps := parseRowSteps(rowFlags{
+		coerce:       *coerce,
+		tz:           *tz,
+		maps:         maps,
+		addedColumns: addedColumns,
+		flatten:      *flatten,
+		where:        where,
+		dedupe:       *dedupe,
+		dedupeBy:     *dedupeBy,
+		dedupeCount:  *dedupeCount,
+		sortBy:       *sortBy,
+		offset:       *offset,
+		limit:        *limit,
+	})
+	if *watch > 0 {
+		runWatch(time.Duration(*watch)*time.Second, flag.Args(), popts, steps, opts)
+		return
+	}
+
+	input, selector, filename := readInput()
+	if *programFile != "" {
+		if selector != "." {
+			fail(exitUsage, "a selector cannot be given together with -f")
+		}
+		selector = loadProgram(*programFile)
+	} else if selector == "." && p.Selector != "" {
+		selector = p.Selector
+	}
+	popts.Filename = filename
+	opts.source = inputFormat(input, popts)
+	var data interface{}
+	var isMultiDoc bool
+	if *journal {
+		data = journalRows(input)
+		if !flagSet("depth") {
+			// Each entry has dozens of fields, kept for drilling into
+			opts.Levels = 1
+		}
+	} else {
+		data, isMultiDoc = parseInput(input, popts)
+	}
+	if *verify {
+		if decoderPlugin(popts) != "" {
+			fail(exitUsage, "-verify cannot check input decoded by a plugin")
+		}
+		runVerify(input, data, isMultiDoc, opts)
+		return
+	}
+	if *patchPath != "" {
+		data = applyPatchFile(*patchPath, data, isMultiDoc, popts)
+	}
+	if *parseJSON {
+		data = parseEmbeddedJSON(data)
+	}
+	if len(decodePatterns) > 0 {
+		data = decodeBase64Fields(data, "", decodePatterns)
+	}
+	data = redactInput(data)
+	parsed := data
+	if *kube {
+		stripKubeNoise(data)
+	}
+	if *pick {
+		if filename == "" {
+			fail(exitUsage, "-pick reads the chosen path from stdin, so the data must come from a file")
+		}
+		if selector != "." {
+			fail(exitUsage, "a selector cannot be given together with -pick")
+		}
+		data = pickPath(data, string(readStdin()))
+	} else {
+		data = applySelector(data, selector)
+	}
+	if *kube {
+		if items, ok := kubeItems(data); ok && selector == "." {
+			data = items
+		}
+		if len(opts.Columns) == 0 && !isMultiDoc {
+			data = kubeRows(data)
+		}
+	}
+	opts.Caption = expandCaption(*caption, filename, selector)
+	if dir := *snapshotDir + *verifySnapshot; dir != "" {
+		opts.snapshot, opts.snapshotOf = snapshotPath(dir, filename, selector, opts.Format)
+		opts.verifySnapshot = *verifySnapshot != ""
+	}
+	// The default cap keeps a huge array from flooding the terminal; files,
+	// pipes and other formats get every row unless a cap was asked for
+	if !flagSet("max-rows") && cfg.MaxRows == nil && (opts.Format != "table" || !colorOutput(opts)) {
+		opts.MaxRows = 0
+	}
+	var source *quickfixSource
+	if *grep != "" || *quickfix {
+		if *quickfix && (filename == "" || filename == "-") {
+			fail(exitUsage, "-quickfix reports positions in a file, so the data must come from one")
+		}
+		if *quickfix && *schemaPath == "" && *grep == "" {
+			fail(exitUsage, "-quickfix needs -schema or -grep")
+		}
+		source = newQuickfixSource(input, parsed, opts.source, filename, selector, isMultiDoc)
+	}
+	if *schemaPath != "" {
+		if !*quickfix {
+			source = nil
+		}
+		runSchema(*schemaPath, data, isMultiDoc, source, popts, opts)
+		return
+	}
+	if *grep != "" {
+		runGrep(*grep, data, source, *quickfix, opts)
+		return
+	}
+
+	data, start, footer, err := steps.apply(data)
+	if err != nil {
+		fail(exitError, "%v", err)
+	}
+	if footer != "" {
+		opts.Footer = footer
+		if !isMultiDoc {
+			opts.FirstIndex = start
+		}
+	}
+
+	// Decided on the selected rows, before they are summarized
+	empty := exitStatus && isEmptyResult(data)
+
+	if *stats {
+		rows, ok := data.([]interface{})
+		if !ok {
+			fail(exitUsage, "-stats needs an array, select one first (e.g. .items)")
+		}
+		data = columnStatistics(rows)
+	}
 
-	input, selector := readInput()
-	data, isMultiDoc := parseInput(input)
-	data = applySelector(data, selector)
+	if *shape {
+		data = inferShape(data)
+	}
 
-	render(data, *format, *details, *maxWidth, isMultiDoc)
+	switch {
+	case *explain:
+		runExplain(input, popts, selector, parsed, data, isMultiDoc, opts)
+	case subcommand == "convert":
+		printEmitted(data, *to, isMultiDoc, opts)
+	case subcommand == "get":
+		printValue(data, isMultiDoc, opts)
+	case *count:
+		printRaw(countOf(data), opts)
+	case *keysOnly:
+		keys, ok := keyList(data)
+		if !ok {
+			fail(exitUsage, "-keys-only needs an object or array, select one first (e.g. .env)")
+		}
+		printLines(keys, opts)
+	case *paths:
+		if out := pathLines(data); !deliver(out, opts) {
+			os.Stdout.Write(out)
+		}
+	case *valuesOnly:
+		values, ok := valueList(data)
+		if !ok {
+			fail(exitUsage, "-values-only needs an object or array, select one first (e.g. .env)")
+		}
+		printLines(values, opts)
+	case raw && printRaw(data, opts):
+	default:
+		render(data, opts, isMultiDoc)
+	}
+	if empty {
+		os.Exit(exitEmpty)
+	}
 }
 
 func isTerminal() bool {
@@ -433,19 +789,6 @@ func getTerminalWidth() int {
 	return width
 }
 
-func getContentWidth(content string) int {
-	maxWidth := 0
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		// Use lipgloss.Width for accurate width calculation
-		width := lipgloss.Width(line)
-		if width > maxWidth {
-			maxWidth = width
-		}
-	}
-	return maxWidth
-}
-
 func stripANSI(s string) string {
 	// Simple ANSI code stripper for search purposes
 	var result strings.Builder
@@ -471,17 +814,9 @@ func isFile(path string) bool {
 	return err == nil && !info.IsDir()
 }
 
+// isSelector reports whether an argument that is not a file is a selector.
 func isSelector(s string) bool {
-	if s == "." {
-		return true
-	}
-	if len(s) >= 2 && s[0] == '.' {
-		firstChar := s[1]
-		return (firstChar >= 'a' && firstChar <= 'z') ||
-			(firstChar >= 'A' && firstChar <= 'Z') ||
-			firstChar == '['
-	}
-	return false
+	return jt.IsSelector(s)
 }
 
 func stdinHasData() bool {
@@ -495,51 +830,62 @@ func stdinHasData() bool {
 func readStdin() []byte {
 	input, err := io.ReadAll(os.Stdin)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error reading from stdin:", err)
-		os.Exit(1)
+		fail(exitError, "reading from stdin: %v", err)
 	}
 	return input
 }
 
+// readFile reads the input at filepath, or stdin for "-". Named pipes,
+// such as those from process substitution (<(cmd)), are read like files.
 func readFile(filepath string) []byte {
+	if filepath == "-" {
+		return readStdin()
+	}
 	input, err := os.ReadFile(filepath)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error reading file:", err)
-		os.Exit(1)
+		fail(exitError, "reading file: %v", err)
 	}
 	return input
 }
 
 func handleNoArgs() ([]byte, string) {
 	if !stdinHasData() {
-		fmt.Fprintln(os.Stderr, "Usage: cat data.json | jt [selector]")
-		fmt.Fprintln(os.Stderr, "       jt <file> [selector]")
-		os.Exit(1)
+		if errorFormat == "json" {
+			fail(exitUsage, "no input: pipe data to stdin or pass a file")
+		}
+		flag.Usage()
+		os.Exit(exitUsage)
 	}
 	return readStdin(), "."
 }
 
 func handleOneArg(arg string) ([]byte, string) {
-	if isFile(arg) {
+	if isURL(arg) {
+		return fetchInput(arg, ".")
+	}
+	if arg == "-" || isFile(arg) {
 		return readFile(arg), "."
 	}
 	if isSelector(arg) {
 		if !stdinHasData() {
-			fmt.Fprintln(os.Stderr, "Error: selector provided but no data piped to stdin")
-			os.Exit(1)
+			fail(exitUsage, "selector provided but no data piped to stdin")
 		}
 		return readStdin(), arg
 	}
-	fmt.Fprintf(os.Stderr, "Error: file not found: %s\n", arg)
-	os.Exit(1)
+	fail(exitUsage, "file not found: %s", arg)
 	return nil, "" // Unreachable
 }
 
 func handleTwoOrMoreArgs(args []string) ([]byte, string) {
+	if isURL(args[0]) {
+		return fetchInput(args[0], args[1])
+	}
 	return readFile(args[0]), args[1]
 }
 
-func readInput() ([]byte, string) {
+// readInput returns the input data, the selector and the name of the file the
+// data was read from ("" for stdin).
+func readInput() ([]byte, string, string) {
 	args := flag.Args()
 	var input []byte
 	var selector string
@@ -554,410 +900,170 @@ func readInput() ([]byte, string) {
 	}
 
 	if len(input) == 0 {
-		fmt.Fprintln(os.Stderr, "Error: no data to process")
-		os.Exit(1)
-	}
-
-	return input, selector
-}
-
-func parseInput(input []byte) (interface{}, bool) {
-	var data interface{}
-	if err := json.Unmarshal(input, &data); err == nil {
-		return data, false
-	}
-
-	if xmlData, err := parseXML(input); err == nil {
-		return xmlData, false
-	}
-
-	decoder := yaml.NewDecoder(bytes.NewReader(input))
-	var documents []interface{}
-	for {
-		var doc interface{}
-		if err := decoder.Decode(&doc); err != nil {
-			if err == io.EOF {
-				break
-			}
-			fmt.Fprintln(os.Stderr, "Error: Input is not valid JSON or YAML.")
-			os.Exit(1)
-		}
-		documents = append(documents, doc)
-	}
-
-	if len(documents) == 0 {
-		return map[string]interface{}{}, false
+		fail(exitParse, "no data to process")
 	}
 
-	if len(documents) == 1 {
-		return documents[0], false
+	filename := ""
+	if len(args) > 0 && args[0] != "-" && (isFile(args[0]) || isURL(args[0])) {
+		filename = args[0]
 	}
 
-	return documents, true
+	return input, selector, filename
 }
 
-func applySelector(data interface{}, selector string) interface{} {
-	if selector == "." {
-		return data
-	}
-
-	if docs, ok := data.([]interface{}); ok {
-		trimmedSelector := strings.TrimPrefix(selector, ".")
-		if !strings.HasPrefix(trimmedSelector, "[") {
-			var results []interface{}
-			for _, doc := range docs {
-				results = append(results, applySelector(doc, selector))
-			}
-			return results
-		}
+// parseInput parses input, warning about duplicate keys and exiting if it
+// is malformed. Formats jt does not know are decoded by a plugin first.
+func parseInput(input []byte, opts jt.ParseOptions) (interface{}, bool) {
+	opts.OnDuplicate = func(key string, line int) {
+		warn("duplicate_key", line, "duplicate key '%s' on line %d", key, line)
 	}
-
-	// Normalize selector to handle array indexing
-	selector = strings.ReplaceAll(strings.TrimPrefix(selector, "."), "[", ".[")
-	path := strings.Split(selector, ".")
-
-	current := data
-	fullPath := ""
-	for _, key := range path {
-		if key == "" {
-			continue
-		}
-
-		if fullPath == "" {
-			fullPath = key
-		} else {
-			fullPath += "." + key
-		}
-
-		if strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]") {
-			indexStr := strings.Trim(key, "[]")
-			index, err := strconv.Atoi(indexStr)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: invalid array index '%s' in path '%s'\n", indexStr, fullPath)
-				os.Exit(1)
-			}
-
-			arr, ok := current.([]interface{})
-			if !ok {
-				fmt.Fprintf(os.Stderr, "Error: cannot index into non-array at path '%s'\n", fullPath)
-				os.Exit(1)
-			}
-
-			if index < 0 || index >= len(arr) {
-				fmt.Fprintf(os.Stderr, "Error: index %d out of bounds for array at path '%s'\n", index, fullPath)
-				os.Exit(1)
-			}
-			current = arr[index]
-		} else {
-			m, ok := current.(map[string]interface{})
-			if !ok {
-				fmt.Fprintf(os.Stderr, "Error: cannot traverse into non-object at path '%s'\n", fullPath)
-				os.Exit(1)
-			}
-
-			val, exists := m[key]
-			if !exists {
-				fmt.Fprintf(os.Stderr, "Error: key '%s' not found in path '%s'\n", key, fullPath)
-				os.Exit(1)
-			}
-			current = val
+	if plugin := decoderPlugin(opts); plugin != "" {
+		opts.Format = "json"
+		data, isMultiDoc, err := jt.Parse(runDecoder(plugin, input), opts)
+		if err != nil {
+			fail(exitParse, "%s wrote invalid JSON: %v", filepath.Base(plugin), err)
 		}
+		return data, isMultiDoc
 	}
-
-	return current
-}
-
-func render(data interface{}, format string, details bool, maxWidth int, isMultiDoc bool) {
-	var output string
-	docs, isSlice := data.([]interface{})
-
-	if isMultiDoc && isSlice {
-		var outputs []string
-		for _, doc := range docs {
-			outputs = append(outputs, renderRecursive(doc, details, format, maxWidth))
-		}
-		output = strings.Join(outputs, "\n")
-	} else {
-		output = renderRecursive(data, details, format, maxWidth)
+	data, isMultiDoc, err := jt.Parse(input, opts)
+	if err != nil {
+		failParse(err)
 	}
-
-	// For HTML, add CSS styling at the beginning
-	if format == "html" {
-		fmt.Println(`<style>
-.jt-table {
-	border-collapse: collapse;
-	background-color: #303446;
-	border: 1px solid #414559;
-	margin: 2px;
-}
-.jt-table th {
-	text-align: center;
-	color: #ca9ee6;
-	font-weight: bold;
-}
-.jt-table td {
-	border: 1px solid #414559;
-	padding: 8px;
-	text-align: left;
+	return data, isMultiDoc
 }
-.jt-key { color: #c6d0f5; }
-.jt-string { color: #a6d189; }
-.jt-bool { color: #ea999c; }
-.jt-number { color: #ffffff; }
-.jt-nested { color: #c6d0f5; }
-</style>`)
-		fmt.Print(output)
-		return
-	}
-
-	// Check if we should use interactive viewer
-	if format == "table" && isTerminal() {
-		termWidth := getTerminalWidth()
-		contentWidth := getContentWidth(output)
-
-		// Use interactive viewer if content is wider than terminal
-		if contentWidth > termWidth {
-			lines := strings.Split(output, "\n")
-			plainLines := make([]string, len(lines))
-			for i, line := range lines {
-				plainLines[i] = stripANSI(line)
-			}
-
-			ti := textinput.New()
-			ti.Placeholder = "Type to search..."
-			ti.CharLimit = 100
 
-			m := model{
-				content:      lines,
-				plainContent: plainLines,
-				contentWidth: contentWidth,
-				searchInput:  ti,
-			}
-			p := tea.NewProgram(m, tea.WithAltScreen())
-			if _, err := p.Run(); err != nil {
-				fmt.Fprintf(os.Stderr, "Error running interactive viewer: %v\n", err)
-				// Fallback to regular output
-				fmt.Println(output)
-			}
-			return
-		}
+// applySelector selects from data, exiting if the selector does not match.
+func applySelector(data interface{}, selector string) interface{} {
+	result, err := jt.Select(data, selector)
+	if err != nil {
+		fail(exitSelector, "%v", err)
 	}
-
-	// Regular output for non-interactive cases
-	fmt.Println(output)
+	return result
 }
 
-func renderRecursive(data interface{}, details bool, format string, maxWidth int) string {
-	var buf bytes.Buffer
-	table := createTable(&buf, format)
-
-	appendData(table, data, details, format, maxWidth)
-	table.Render()
-
-	return buf.String()
+// renderOptions adds the command's own output settings to the table
+// options.
+type renderOptions struct {
+	jt.Options
+	fit      bool   // shrink values to fit the terminal, see jt.Fit
+	output   string // file to write to instead of stdout
+	copy     bool   // copy the output to the clipboard
+	copyOnly bool   // ... without printing it
+	source   string // format the input was read in
+	viewer   string // how wide output is shown: tui, pager or none
+	tree     bool   // show the data as a tree instead of tables
+	renderer string // jt-render-<format> plugin for formats jt does not know
+	// snapshot is the file the output is recorded in instead of shown, or
+	// compared to with verifySnapshot
+	snapshot       string
+	snapshotOf     string // the input, selector and flags the snapshot records
+	verifySnapshot bool
 }
 
-func createTable(buf *bytes.Buffer, format string) *tablewriter.Table {
-	switch format {
-	case "html":
-		cfg := renderer.HTMLConfig{
-			HeaderClass:   "jt-header",
-			TableClass:    "jt-table",
-			EscapeContent: false,
-		}
-		return tablewriter.NewTable(buf, tablewriter.WithRenderer(renderer.NewHTML(cfg)))
-	default: // table
-		return tablewriter.NewTable(buf,
-			tablewriter.WithHeaderAlignment(tw.AlignLeft),
-			tablewriter.WithRowAlignment(tw.AlignLeft),
-			tablewriter.WithRendition(tw.Rendition{
-				Borders: tw.Border{Left: tw.On, Right: tw.On, Top: tw.On, Bottom: tw.On},
-				Settings: tw.Settings{
-					Separators: tw.Separators{BetweenColumns: tw.On, BetweenRows: tw.On},
-				},
-			}),
-		)
+// renderDocuments renders data as tables, in color when the output goes to
+// a terminal.
+func renderDocuments(data interface{}, opts renderOptions, isMultiDoc bool) string {
+	opts.Color = colorOutput(opts)
+	output, err := jt.RenderDocuments(data, opts.Options, isMultiDoc)
+	if err != nil {
+		fail(exitRender, "%v", err)
 	}
+	return output
 }
 
-func truncateValue(s string, maxWidth int) string {
-	// Replace newlines with spaces for single-line display
-	s = strings.ReplaceAll(s, "\n", " ")
-	s = strings.ReplaceAll(s, "\r", "")
-
-	// Collapse multiple spaces
-	for strings.Contains(s, "  ") {
-		s = strings.ReplaceAll(s, "  ", " ")
+func render(data interface{}, opts renderOptions, isMultiDoc bool) {
+	if opts.renderer != "" {
+		runRenderer(opts.renderer, data, opts)
+		return
 	}
-
-	s = strings.TrimSpace(s)
-
-	if len(s) <= maxWidth {
-		return s
+	switch opts.Format {
+	case "json", "yaml":
+		printEmitted(data, opts.Format, isMultiDoc, opts)
+		return
 	}
-
-	return s[:maxWidth-3] + "..."
-}
-
-func formatValue(val interface{}, details bool, format string, maxWidth int) string {
-	switch v := val.(type) {
-	case map[string]interface{}, []interface{}:
-		nested := renderRecursive(val, details, format, maxWidth)
-		// For HTML, ensure nested table stays as single value (no newlines that could split it)
-		if format == "html" {
-			// Remove newlines to keep nested table in one cell
-			nested = strings.ReplaceAll(nested, "\n", "")
-			return nested
-		}
-		return nested
-	default:
-		value := fmt.Sprintf("%v", v)
-		// Escape HTML entities for primitive values in HTML format
-		if format == "html" {
-			value = escapeHTML(value)
+	if opts.tree {
+		renderTreeView(data, opts, isMultiDoc)
+		return
+	}
+	if opts.Format == "pretty" {
+		output := renderPretty(data, opts, isMultiDoc)
+		if !deliver([]byte(output), opts) {
+			fmt.Print(output)
 		}
-		return truncateValue(value, maxWidth)
+		return
 	}
-}
-
-func escapeHTML(s string) string {
-	s = strings.ReplaceAll(s, "&", "&amp;")
-	s = strings.ReplaceAll(s, "<", "&lt;")
-	s = strings.ReplaceAll(s, ">", "&gt;")
-	s = strings.ReplaceAll(s, "\"", "&quot;")
-	s = strings.ReplaceAll(s, "'", "&#39;")
-	return s
-}
 
-func appendData(table *tablewriter.Table, data interface{}, details bool, format string, maxWidth int) {
-	useColor := isTerminal() && format == "table"
+	if opts.fit && opts.Format == "table" && opts.output == "" && opts.snapshot == "" && isTerminal() {
+		opts.Options = jt.Fit(data, opts.Options, isMultiDoc, getTerminalWidth())
+	}
+	rendered := renderDocuments(data, opts, isMultiDoc)
+	output := rendered
 
-	switch v := data.(type) {
-	case []interface{}:
-		handleSlice(table, v, details, format, maxWidth, useColor)
-	case map[string]interface{}:
-		handleMap(table, v, details, format, maxWidth, useColor)
-	default:
-		table.Append([]string{"value", truncateValue(fmt.Sprintf("%v", v), maxWidth)})
+	// For HTML, add CSS styling at the beginning
+	if opts.Format == "html" {
+		output = jt.StyleSheet() + "\n" + output
+	} else {
+		output += "\n"
 	}
-}
 
-func handleSlice(table *tablewriter.Table, v []interface{}, details bool, format string, maxWidth int, useColor bool) {
-	if details {
-		table.Caption(tw.Caption{Text: fmt.Sprintf("[-] array, %d items", len(v))})
+	if deliver([]byte(output), opts) {
+		return
 	}
-	if len(v) == 0 {
+	if opts.Format == "html" {
+		fmt.Print(output)
 		return
 	}
 
-	headers := buildHeaders(v)
-	table.Header(headers)
-
-	for i, item := range v {
-		if m, ok := item.(map[string]interface{}); ok {
-			row := []string{}
-
-			// Add index column with styling
-			if useColor {
-				row = append(row, keyStyle.Render(fmt.Sprintf("%d", i)))
-			} else if format == "html" {
-				row = append(row, fmt.Sprintf(`<span class="jt-key">%d</span>`, i))
-			} else {
-				row = append(row, fmt.Sprintf("%d", i))
-			}
-
-			// Add value columns with styling
-			for _, key := range headers[1:] {
-				val := m[key]
-				value := formatValue(val, details, format, maxWidth)
+	// Check if we should use interactive viewer
+	if opts.Format == "table" && isTerminal() {
+		termWidth := getTerminalWidth()
+		contentWidth := jt.ContentWidth(output)
 
-				if useColor {
-					row = append(row, getStyle(val).Render(value))
-				} else if format == "html" {
-					cssClass := getHTMLClass(val)
-					row = append(row, fmt.Sprintf(`<span class="%s">%s</span>`, cssClass, value))
-				} else {
-					row = append(row, value)
-				}
-			}
-			table.Append(row)
-		} else {
-			value := formatValue(item, details, format, maxWidth)
-			appendRow(table, fmt.Sprintf("%d", i), value, item, useColor, format)
+		// Use interactive viewer if content is wider than terminal
+		switch viewer := pickViewer(opts.viewer); {
+		case contentWidth <= termWidth, viewer == "none":
+		case viewer == "pager":
+			page(output)
+			return
+		default:
+			m := newModel(data, opts, isMultiDoc)
+			m.setOutput(rendered)
+			runViewer(m, output)
+			return
 		}
 	}
-}
 
-func handleMap(table *tablewriter.Table, v map[string]interface{}, details bool, format string, maxWidth int, useColor bool) {
-	if details {
-		table.Caption(tw.Caption{Text: fmt.Sprintf("[-] object, %d properties", len(v))})
-	}
-	keys := make([]string, 0, len(v))
-	for k := range v {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	for _, key := range keys {
-		val := v[key]
-		value := formatValue(val, details, format, maxWidth)
-		appendRow(table, key, value, val, useColor, format)
-	}
+	// Regular output for non-interactive cases
+	fmt.Print(output)
 }
 
-func buildHeaders(v []interface{}) []string {
-	headers := []string{"[key]"}
-	if first, ok := v[0].(map[string]interface{}); ok {
-		keys := make([]string, 0, len(first))
-		for k := range first {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		headers = append(headers, keys...)
-	}
-	return headers
-}
+func newModel(data interface{}, opts renderOptions, isMultiDoc bool) model {
+	ti := textinput.New()
+	ti.Placeholder = "Type to search..."
+	ti.CharLimit = 100
 
-func appendRow(table *tablewriter.Table, key, value string, originalVal interface{}, useColor bool, format string) {
-	if useColor {
-		table.Append([]string{
-			keyStyle.Render(key),
-			getStyle(originalVal).Render(value),
-		})
-	} else if format == "html" {
-		// Add color styling via CSS classes for HTML output
-		cssClass := getHTMLClass(originalVal)
+	si := textinput.New()
+	si.Placeholder = "column, column:desc"
 
-		styledKey := fmt.Sprintf(`<span class="jt-key">%s</span>`, key)
-		styledValue := fmt.Sprintf(`<span class="%s">%s</span>`, cssClass, value)
+	fi := textinput.New()
+	fi.Prompt = ""
 
-		table.Append([]string{styledKey, styledValue})
-	} else {
-		table.Append([]string{key, value})
+	return model{
+		data:        data,
+		opts:        opts,
+		isMultiDoc:  isMultiDoc,
+		searchInput: ti,
+		sortInput:   si,
+		filterInput: fi,
 	}
 }
 
-func getHTMLClass(val interface{}) string {
-	switch val.(type) {
-	case bool:
-		return "jt-bool"
-	case string:
-		return "jt-string"
-	case int, int64, float64:
-		return "jt-number"
-	case map[string]interface{}, []interface{}:
-		return "jt-nested"
-	}
-	return "jt-key"
-}
-
-func getStyle(val interface{}) lipgloss.Style {
-	switch val.(type) {
-	case bool:
-		return boolStyle
-	case string:
-		return stringStyle
-	case int, int64, float64:
-		return intStyle
+// runViewer runs the interactive viewer, falling back to paging output.
+func runViewer(m model, output string) {
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running interactive viewer: %v\n", err)
+		// Fall back to a pager rather than a wall of wrapped text
+		page(output)
 	}
-	return keyStyle
 }
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs jt itself when a test starts the test binary with
+// JT_TEST_ARGS set, so the command is tested as it is run: flags, stdin,
+// output and exit code.
+func TestMain(m *testing.M) {
+	if encoded, ok := os.LookupEnv("JT_TEST_ARGS"); ok {
+		var args []string
+		if err := json.Unmarshal([]byte(encoded), &args); err != nil {
+			panic(err)
+		}
+		os.Args = append([]string{"jt"}, args...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runJT runs jt with args, piping stdin to it, and returns what it wrote
+// to stdout and stderr and its exit code. The user's config is kept out.
+func runJT(t *testing.T, stdin string, args ...string) (string, int) {
+	t.Helper()
+	return runJTEnv(t, nil, stdin, args...)
+}
+
+// runJTEnv is runJT with env added to jt's environment.
+func runJTEnv(t *testing.T, env []string, stdin string, args ...string) (string, int) {
+	t.Helper()
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := t.TempDir()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "JT_TEST_ARGS="+string(encoded), "HOME="+home, "XDG_CONFIG_HOME="+home, "NO_COLOR=1")
+	cmd.Env = append(cmd.Env, env...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		return out.String(), exitErr.ExitCode()
+	case err != nil:
+		t.Fatal(err)
+	}
+	return out.String(), 0
+}
+
+// compactJSON returns JSON output without its indentation, or the output as
+// it is when it is not JSON.
+func compactJSON(out string) string {
+	var b bytes.Buffer
+	if json.Compact(&b, []byte(out)) != nil {
+		return out
+	}
+	return b.String()
+}
+
+func TestSelectorOnStdin(t *testing.T) {
+	tests := []struct {
+		input, selector, want string
+	}{
+		{`{"a":1,"b":2}`, ".*", "[1,2]"},
+		{`{"pods":[{"image":"web:1"},{"sidecar":{"image":"db:2"}}]}`, "..image",
+			`[{"path":".pods[0].image","value":"web:1"},{"path":".pods[1].sidecar.image","value":"db:2"}]`},
+		{`[{"a":1},{"a":2}]`, "map(.a)", "[1,2]"},
+		{`[{"a":1},{"a":2}]`, "[.[] | .a]", "[1,2]"},
+		{`{"b":3}`, "{a: .b}", `{"a":3}`},
+		{`[{"a":1},{"a":2}]`, "map(select(.a > 1))", `[{"a":2}]`},
+	}
+	for _, tt := range tests {
+		out, code := runJT(t, tt.input, "-format", "json", tt.selector)
+		if code != 0 || compactJSON(out) != tt.want {
+			t.Errorf("jt %q: got %q, exit %d; want %s", tt.selector, out, code, tt.want)
+		}
+	}
+}
+
+func TestMissingFileIsNotASelector(t *testing.T) {
+	out, code := runJT(t, `{"a":1}`, "missing.json")
+	if code != exitUsage || !strings.Contains(out, "file not found") {
+		t.Errorf("got %q, exit %d; want file not found, exit %d", out, code, exitUsage)
+	}
+}
+
+func TestFlagsAfterArguments(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cfg.yaml")
+	if err := os.WriteFile(file, []byte("env:\n  HOME: /root\n  PATH: /bin\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, code := runJT(t, "", file, ".env", "--keys-only")
+	if code != 0 || out != "HOME\nPATH\n" {
+		t.Errorf("got %q, exit %d; want the keys", out, code)
+	}
+}
+
+func TestDefaultCommandForms(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "d.json")
+	if err := os.WriteFile(file, []byte(`{"a":{"b":1}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		stdin string
+		args  []string
+	}{
+		{"", []string{"view", file, ".a", "-format", "json"}},
+		{"", []string{file, ".a", "-format", "json"}},
+		{"", []string{file, "-format", "json", ".a"}},
+		{"", []string{"-format", "json", file, ".a"}},
+		{`{"a":{"b":1}}`, []string{".a", "-format", "json"}},
+		{`{"a":{"b":1}}`, []string{"-", ".a", "-format=json"}},
+	}
+	for _, tt := range tests {
+		out, code := runJT(t, tt.stdin, tt.args...)
+		if code != 0 || compactJSON(out) != `{"b":1}` {
+			t.Errorf("jt %s: got %q, exit %d", strings.Join(tt.args, " "), out, code)
+		}
+	}
+}
+
+func TestFilterAndMapArithmetic(t *testing.T) {
+	input := `[{"a":"x","b":1}]`
+	if out, code := runJT(t, input, "-format", "json", ".[0].a + .[0].b"); code != exitSelector || !strings.Contains(out, "cannot be added") {
+		t.Errorf("filter: got %q, exit %d; want jq's error, exit %d", out, code, exitSelector)
+	}
+	out, code := runJT(t, input, "-format", "json", "-add-column", "c=.a + .b")
+	if code != 0 || compactJSON(out) != `[{"a":"x","b":1,"c":"x1"}]` {
+		t.Errorf("-add-column: got %q, exit %d; want the text joined", out, code)
+	}
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// A -map program is a list of assignments to fields of the row, separated
+// by semicolons:
+//
+//	row.total = row.price * row.qty; row.label = upper(row.name) + "!"
+//
+// Expressions have numbers, strings, true, false and null, fields of the
+// row (row.a.b, .a.b for short, or row["odd key"]), the operators + - * / % == != < <= > >=
+// && || ! and parentheses, and the functions in mapFunctions. + joins text
+// when either side is a string; the other arithmetic operators read strings
+// as numbers. Arithmetic involving null gives null.
+
+// mapExpr evaluates an expression against a row.
+type mapExpr func(row map[string]interface{}) (interface{}, error)
+
+// mapAssignment sets the field at path to the value of expr.
+type mapAssignment struct {
+	path []string
+	expr mapExpr
+}
+
+// mapFunctions are the functions -map expressions may call.
+var mapFunctions = map[string]func(args []interface{}) (interface{}, error){
+	"len": func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case string:
+			return jt.Number(float64(len([]rune(v)))), nil
+		case []interface{}:
+			return jt.Number(float64(len(v))), nil
+		case map[string]interface{}:
+			return jt.Number(float64(len(jt.OrderedKeys(v)))), nil
+		case nil:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("len of %s", typeName(args[0]))
+	},
+	"upper": func(args []interface{}) (interface{}, error) {
+		return mapText(args[0], strings.ToUpper), nil
+	},
+	"lower": func(args []interface{}) (interface{}, error) {
+		return mapText(args[0], strings.ToLower), nil
+	},
+	"round": func(args []interface{}) (interface{}, error) {
+		digits := 0.0
+		if len(args) > 1 {
+			digits, _ = jt.ToFloat(args[1])
+		}
+		return mapArithmetic(args[0], nil, func(a, _ float64) float64 {
+			scale := math.Pow(10, digits)
+			return math.Round(a*scale) / scale
+		})
+	},
+	"abs": func(args []interface{}) (interface{}, error) {
+		return mapArithmetic(args[0], nil, func(a, _ float64) float64 { return math.Abs(a) })
+	},
+	"if": func(args []interface{}) (interface{}, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("if needs 3 arguments, a condition and two values")
+		}
+		if mapTruthy(args[0]) {
+			return args[1], nil
+		}
+		return args[2], nil
+	},
+}
+
+// parseAddColumn parses an -add-column, a column name and an expression
+// for its value such as ratio=.used / .total, into an assignment.
+func parseAddColumn(s string) (mapAssignment, error) {
+	name, src, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.HasPrefix(src, "=") {
+		return mapAssignment{}, fmt.Errorf("expected name=expression, e.g. ratio=.used / .total")
+	}
+	p := &mapParser{src: src}
+	p.next()
+	expr, err := p.expr()
+	if err == nil && p.tok != "" {
+		err = fmt.Errorf("expected end of expression, got %s", p.describe())
+	}
+	if p.err != nil {
+		err = p.err
+	}
+	if err != nil {
+		return mapAssignment{}, err
+	}
+	return mapAssignment{path: []string{name}, expr: expr}, nil
+}
+
+// addColumns runs the -add-column assignments on each row, listing the new
+// columns after the existing ones even where the key order was not
+// recorded.
+func addColumns(data interface{}, program []mapAssignment) (interface{}, error) {
+	rows, ok := data.([]interface{})
+	if !ok {
+		rows = []interface{}{data}
+	}
+	for _, item := range rows {
+		if row, ok := item.(map[string]interface{}); ok {
+			if _, ok := row[jt.OrderKey]; !ok {
+				row[jt.OrderKey] = jt.OrderedKeys(row)
+			}
+		}
+	}
+	return mapRows(data, program)
+}
+
+// parseMapProgram parses the assignments of a -map.
+func parseMapProgram(s string) ([]mapAssignment, error) {
+	p := &mapParser{src: s}
+	p.next()
+	program, err := p.program()
+	if p.err != nil {
+		return nil, p.err
+	}
+	return program, err
+}
+
+func (p *mapParser) program() ([]mapAssignment, error) {
+	var program []mapAssignment
+	for p.tok != "" {
+		if p.tok == ";" {
+			p.next()
+			continue
+		}
+		path, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != "=" {
+			return nil, fmt.Errorf("expected = after row.%s, got %s", strings.Join(path, "."), p.describe())
+		}
+		p.next()
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		program = append(program, mapAssignment{path: path, expr: expr})
+		if p.tok != "" && p.tok != ";" {
+			return nil, fmt.Errorf("expected ; or end of expression, got %s", p.describe())
+		}
+	}
+	if len(program) == 0 {
+		return nil, fmt.Errorf("no assignment, e.g. row.total = row.price * row.qty")
+	}
+	return program, nil
+}
+
+// mapRows runs the program on each object of an array, or on a single
+// object. The rows are changed in place.
+func mapRows(data interface{}, program []mapAssignment) (interface{}, error) {
+	rows, ok := data.([]interface{})
+	if !ok {
+		rows = []interface{}{data}
+	}
+	for i, item := range rows {
+		row, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, a := range program {
+			value, err := a.expr(row)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", i, err)
+			}
+			setField(row, a.path, value)
+		}
+	}
+	return data, nil
+}
+
+// setField sets the value at path, creating objects along the way. Where
+// the key order was recorded, new keys are listed after the existing ones.
+func setField(m map[string]interface{}, path []string, value interface{}) {
+	for i, key := range path {
+		if i == len(path)-1 {
+			if _, exists := m[key]; !exists {
+				if order, ok := m[jt.OrderKey].([]string); ok {
+					m[jt.OrderKey] = append(order[:len(order):len(order)], key)
+				}
+			}
+			m[key] = value
+			return
+		}
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			setField(m, []string{key}, next)
+		}
+		m = next
+	}
+}
+
+// mapParser is a recursive descent parser over the tokens of a -map.
+type mapParser struct {
+	src string
+	pos int
+	tok string // "" at the end
+	str bool   // tok is a string literal, unquoted
+	err error  // from reading the tokens, such as an unterminated string
+}
+
+var mapOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "<", ">", "!", "(", ")", "[", "]", ".", ",", ";", "="}
+
+func (p *mapParser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	p.str = false
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+	rest := p.src[p.pos:]
+	switch c := rest[0]; {
+	case c == '"' || c == '\'':
+		end := strings.IndexByte(rest[1:], c)
+		if end < 0 {
+			p.err = fmt.Errorf("unterminated string starting at %s", rest)
+			p.tok, p.pos = "", len(p.src)
+			return
+		}
+		p.tok, p.str = rest[1:end+1], true
+		p.pos += end + 2
+		return
+	case c >= '0' && c <= '9':
+		n := 1
+		for n < len(rest) && (rest[n] >= '0' && rest[n] <= '9' || rest[n] == '.') {
+			n++
+		}
+		p.tok = rest[:n]
+		p.pos += n
+		return
+	case c == '_' || unicode.IsLetter(rune(c)):
+		n := 1
+		for n < len(rest) && (rest[n] == '_' || unicode.IsLetter(rune(rest[n])) || unicode.IsDigit(rune(rest[n]))) {
+			n++
+		}
+		p.tok = rest[:n]
+		p.pos += n
+		return
+	}
+	for _, op := range mapOperators {
+		if strings.HasPrefix(rest, op) {
+			p.tok = op
+			p.pos += len(op)
+			return
+		}
+	}
+	p.tok = rest[:1]
+	p.pos++
+}
+
+func isMapIdent(tok string) bool {
+	c := rune(tok[0])
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+func (p *mapParser) describe() string {
+	switch {
+	case p.tok == "":
+		return "end of expression"
+	case p.str:
+		return strconv.Quote(p.tok)
+	}
+	return "'" + p.tok + "'"
+}
+
+// field parses row.a.b, .a.b or row["a"] into its keys.
+func (p *mapParser) field() ([]string, error) {
+	switch {
+	case p.str || p.tok != "row" && p.tok != ".":
+		return nil, fmt.Errorf("expected a field such as row.name, got %s", p.describe())
+	case p.tok == "row":
+		p.next()
+	}
+	var path []string
+	for {
+		switch p.tok {
+		case ".":
+			p.next()
+			if p.str || p.tok == "" || !isMapIdent(p.tok) {
+				return nil, fmt.Errorf("expected a key after row., got %s", p.describe())
+			}
+		case "[":
+			p.next()
+			if !p.str {
+				return nil, fmt.Errorf("expected a quoted key in row[...], got %s", p.describe())
+			}
+			key := p.tok
+			p.next()
+			if p.tok != "]" {
+				return nil, fmt.Errorf("expected ] after row[%q, got %s", key, p.describe())
+			}
+			p.tok = key
+		default:
+			if len(path) == 0 {
+				return nil, fmt.Errorf("expected a field such as row.name, got row alone")
+			}
+			return path, nil
+		}
+		path = append(path, p.tok)
+		p.next()
+	}
+}
+
+// mapPrecedence lists the binary operators from loosest to tightest.
+var mapPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *mapParser) expr() (mapExpr, error) {
+	return p.binary(0)
+}
+
+func (p *mapParser) binary(level int) (mapExpr, error) {
+	if level == len(mapPrecedence) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for !p.str && slices.Contains(mapPrecedence[level], p.tok) {
+		op := p.tok
+		p.next()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = mapBinary(op, left, right)
+	}
+	return left, nil
+}
+
+func (p *mapParser) unary() (mapExpr, error) {
+	if !p.str && (p.tok == "-" || p.tok == "!") {
+		op := p.tok
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(row map[string]interface{}) (interface{}, error) {
+			v, err := operand(row)
+			if err != nil || op == "!" {
+				return !mapTruthy(v), err
+			}
+			return mapArithmetic(v, nil, func(a, _ float64) float64 { return -a })
+		}, nil
+	}
+	return p.primary()
+}
+
+func (p *mapParser) primary() (mapExpr, error) {
+	tok := p.tok
+	switch {
+	case p.str:
+		p.next()
+		return func(map[string]interface{}) (interface{}, error) { return tok, nil }, nil
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case tok[0] >= '0' && tok[0] <= '9':
+		if _, err := strconv.ParseFloat(tok, 64); err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", tok)
+		}
+		p.next()
+		return func(map[string]interface{}) (interface{}, error) { return json.Number(tok), nil }, nil
+	case tok == "(":
+		p.next()
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, fmt.Errorf("expected ), got %s", p.describe())
+		}
+		p.next()
+		return inner, nil
+	case tok == "row" || tok == ".":
+		path, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		return func(row map[string]interface{}) (interface{}, error) {
+			var current interface{} = row
+			for _, key := range path {
+				m, ok := current.(map[string]interface{})
+				if !ok {
+					return nil, nil
+				}
+				current = m[key]
+			}
+			return current, nil
+		}, nil
+	}
+	literals := map[string]interface{}{"true": true, "false": false, "null": nil}
+	if v, ok := literals[tok]; ok {
+		p.next()
+		return func(map[string]interface{}) (interface{}, error) { return v, nil }, nil
+	}
+	fn, ok := mapFunctions[tok]
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s", p.describe())
+	}
+	p.next()
+	if p.tok != "(" {
+		return nil, fmt.Errorf("expected ( after %s, got %s", tok, p.describe())
+	}
+	p.next()
+	var args []mapExpr
+	for p.tok != ")" {
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.tok == "," {
+			p.next()
+		} else if p.tok != ")" {
+			return nil, fmt.Errorf("expected , or ) in arguments of %s, got %s", tok, p.describe())
+		}
+	}
+	p.next()
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s needs an argument", tok)
+	}
+	return func(row map[string]interface{}) (interface{}, error) {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			v, err := arg(row)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		v, err := fn(values)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", tok, err)
+		}
+		return v, nil
+	}, nil
+}
+
+func mapBinary(op string, left, right mapExpr) mapExpr {
+	return func(row map[string]interface{}) (interface{}, error) {
+		a, err := left(row)
+		if err != nil {
+			return nil, err
+		}
+		// Only evaluate the right side when it decides the result
+		switch op {
+		case "&&":
+			if !mapTruthy(a) {
+				return false, nil
+			}
+		case "||":
+			if mapTruthy(a) {
+				return true, nil
+			}
+		}
+		b, err := right(row)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil || b == nil {
+			// null only equals null, and has no order
+			switch op {
+			case "==":
+				return a == b, nil
+			case "!=":
+				return a != b, nil
+			case "<", "<=", ">", ">=":
+				return false, nil
+			}
+		}
+		switch op {
+		case "&&", "||":
+			return mapTruthy(b), nil
+		case "==":
+			return compareValues(a, b) == 0, nil
+		case "!=":
+			return compareValues(a, b) != 0, nil
+		case "<":
+			return compareValues(a, b) < 0, nil
+		case "<=":
+			return compareValues(a, b) <= 0, nil
+		case ">":
+			return compareValues(a, b) > 0, nil
+		case ">=":
+			return compareValues(a, b) >= 0, nil
+		case "+":
+			_, aText := a.(string)
+			_, bText := b.(string)
+			if aText || bText {
+				return jt.ScalarString(a) + jt.ScalarString(b), nil
+			}
+			return mapArithmetic(a, b, func(x, y float64) float64 { return x + y })
+		case "-":
+			return mapArithmetic(a, b, func(x, y float64) float64 { return x - y })
+		case "*":
+			return mapArithmetic(a, b, func(x, y float64) float64 { return x * y })
+		case "/":
+			return mapArithmetic(a, b, func(x, y float64) float64 { return x / y })
+		}
+		return mapArithmetic(a, b, math.Mod)
+	}
+}
+
+// mapArithmetic applies f to the numeric values of a and b; b is nil for
+// functions of one value. Null gives null, anything else that is not a
+// number is an error.
+func mapArithmetic(a, b interface{}, f func(x, y float64) float64) (interface{}, error) {
+	if a == nil {
+		return nil, nil
+	}
+	x, ok := jt.ToFloat(a)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a number", mapDescribe(a))
+	}
+	var y float64
+	if b != nil {
+		if y, ok = jt.ToFloat(b); !ok {
+			return nil, fmt.Errorf("%s is not a number", mapDescribe(b))
+		}
+	}
+	return jt.Number(f(x, y)), nil
+}
+
+func mapText(v interface{}, f func(string) string) interface{} {
+	if v == nil {
+		return nil
+	}
+	return f(jt.ScalarString(v))
+}
+
+// mapTruthy follows the rules of -e: null, false, "" and empty collections
+// are false.
+func mapTruthy(v interface{}) bool {
+	return !isEmptyResult(v)
+}
+
+func mapDescribe(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return typeName(v)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// mcpProtocolVersion is the revision of the Model Context Protocol jt
+// implements, offered when the client does not ask for one.
+const mcpProtocolVersion = "2025-06-18"
+
+// mcpRequest is a JSON-RPC 2.0 request or notification; notifications have
+// no ID.
+type mcpRequest struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type mcpResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *mcpError       `json:"error,omitempty"`
+}
+
+type mcpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpTool describes a tool in the answer to tools/list.
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// mcpSession holds the documents an agent has loaded, by name.
+type mcpSession struct {
+	popts     jt.ParseOptions
+	opts      renderOptions
+	documents map[string]mcpDocument
+}
+
+type mcpDocument struct {
+	data       interface{}
+	isMultiDoc bool
+}
+
+var mcpTools = []mcpTool{
+	{
+		Name:        "load_document",
+		Description: "Load a JSON, YAML or XML document from a file or from text, and describe its structure. Later calls refer to it by name.",
+		InputSchema: mcpSchema(map[string]interface{}{
+			"path":    mcpProperty("string", "File to read"),
+			"content": mcpProperty("string", "Document text, instead of a path"),
+			"format":  mcpProperty("string", "json, yaml or xml; detected when omitted"),
+			"name":    mcpProperty("string", "Name to refer to the document by; defaults to the path, or \"document\""),
+		}),
+	},
+	{
+		Name:        "query",
+		Description: "Select part of a loaded document with a selector such as .items[0].metadata and return it as JSON.",
+		InputSchema: mcpSchema(map[string]interface{}{
+			"document": mcpProperty("string", "Name of a loaded document"),
+			"selector": mcpProperty("string", "Selector, . for the whole document"),
+		}, "document"),
+	},
+	{
+		Name:        "render_table",
+		Description: "Render part of a loaded document as a table, for reading or for showing to the user.",
+		InputSchema: mcpSchema(map[string]interface{}{
+			"document": mcpProperty("string", "Name of a loaded document"),
+			"selector": mcpProperty("string", "Selector, . for the whole document"),
+			"format":   mcpProperty("string", "markdown (the default), table or html"),
+			"columns": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Columns to show for an array of objects; dotted paths reach nested values",
+			},
+			"max_rows": mcpProperty("integer", "Rows to show per table, 0 for all"),
+		}, "document"),
+	},
+}
+
+func mcpSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func mcpProperty(kind, description string) map[string]interface{} {
+	return map[string]interface{}{"type": kind, "description": description}
+}
+
+// runMCP implements `jt mcp`: a Model Context Protocol server on stdin and
+// stdout, one JSON-RPC message per line, offering the tools in mcpTools.
+func runMCP(popts jt.ParseOptions, opts renderOptions) {
+	session := &mcpSession{popts: popts, opts: opts, documents: make(map[string]mcpDocument)}
+	session.opts.Color = false
+	reader := bufio.NewReader(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			if response, ok := session.handle(line); ok {
+				if err := encoder.Encode(response); err != nil {
+					fail(exitError, "writing response: %v", err)
+				}
+			}
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fail(exitError, "reading request: %v", err)
+		}
+	}
+}
+
+// handle answers one message; notifications get no answer.
+func (s *mcpSession) handle(line []byte) (mcpResponse, bool) {
+	var req mcpRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return mcpResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &mcpError{-32700, "parse error: " + err.Error()}}, true
+	}
+	if len(req.ID) == 0 {
+		return mcpResponse{}, false
+	}
+	response := mcpResponse{JSONRPC: "2.0", ID: req.ID}
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		if params.ProtocolVersion == "" {
+			params.ProtocolVersion = mcpProtocolVersion
+		}
+		response.Result = map[string]interface{}{
+			"protocolVersion": params.ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": "jt", "version": version},
+		}
+	case "ping":
+		response.Result = map[string]interface{}{}
+	case "tools/list":
+		response.Result = map[string]interface{}{"tools": mcpTools}
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			response.Error = &mcpError{-32602, "invalid params: " + err.Error()}
+			break
+		}
+		text, err := s.call(params.Name, params.Arguments)
+		if err != nil {
+			text = err.Error()
+		}
+		response.Result = map[string]interface{}{
+			"content": []interface{}{map[string]interface{}{"type": "text", "text": text}},
+			"isError": err != nil,
+		}
+	default:
+		response.Error = &mcpError{-32601, fmt.Sprintf("method '%s' not found", req.Method)}
+	}
+	return response, true
+}
+
+// call runs a tool. Errors are reported to the agent as the tool's result,
+// so it can correct the call.
+func (s *mcpSession) call(name string, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Path     string   `json:"path"`
+		Content  string   `json:"content"`
+		Format   string   `json:"format"`
+		Name     string   `json:"name"`
+		Document string   `json:"document"`
+		Selector string   `json:"selector"`
+		Columns  []string `json:"columns"`
+		MaxRows  *int     `json:"max_rows"`
+	}
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %v", err)
+		}
+	}
+
+	switch name {
+	case "load_document":
+		return s.load(args.Path, args.Content, args.Format, args.Name)
+	case "query", "render_table":
+	default:
+		return "", fmt.Errorf("unknown tool '%s'", name)
+	}
+	doc, ok := s.documents[args.Document]
+	if !ok {
+		return "", fmt.Errorf("no document named '%s' is loaded (loaded: %s)", args.Document, strings.Join(s.names(), ", "))
+	}
+	if args.Selector == "" {
+		args.Selector = "."
+	}
+	data, err := jt.Select(doc.data, args.Selector)
+	if err != nil {
+		return "", err
+	}
+
+	if name == "query" {
+		out, err := encodeJSON(data, "  ")
+		return string(out), err
+	}
+	opts := s.opts.Options
+	opts.Format = "markdown"
+	if args.Format != "" {
+		opts.Format = args.Format
+	}
+	if len(args.Columns) > 0 {
+		opts.Columns = args.Columns
+	}
+	if args.MaxRows != nil {
+		opts.MaxRows = *args.MaxRows
+	}
+	return jt.RenderDocuments(data, opts, doc.isMultiDoc)
+}
+
+// load parses a document into the session and describes it.
+func (s *mcpSession) load(path, content, format, name string) (string, error) {
+	popts := s.popts
+	popts.Format = format
+	input := []byte(content)
+	switch {
+	case path != "" && content != "":
+		return "", fmt.Errorf("give either path or content, not both")
+	case path != "":
+		var err error
+		if input, err = os.ReadFile(path); err != nil {
+			return "", err
+		}
+		popts.Filename = path
+	case content == "":
+		return "", fmt.Errorf("give the path of a file or the document as content")
+	}
+	data, isMultiDoc, err := jt.Parse(input, popts)
+	if err != nil {
+		return "", err
+	}
+	data = redactInput(data)
+	if name == "" {
+		name = path
+	}
+	if name == "" {
+		name = "document"
+	}
+	s.documents[name] = mcpDocument{data: data, isMultiDoc: isMultiDoc}
+
+	summary := describe(data)
+	if docs, ok := data.([]interface{}); ok && isMultiDoc {
+		summary = fmt.Sprintf("%d documents, the first: %s", len(docs), describe(docs[0]))
+	}
+	if format == "" {
+		format = jt.DetectFormat(input, popts.Filename)
+	}
+	return fmt.Sprintf("Loaded '%s' (%s): %s", name, strings.ToUpper(format), summary), nil
+}
+
+func (s *mcpSession) names() []string {
+	names := make([]string, 0, len(s.documents))
+	for name := range s.documents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
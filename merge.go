@@ -0,0 +1,122 @@
+package main
+
+import "github.com/obegron/jt/pkg/jt"
+
+// mergeValues deep-merges override into base: objects are merged key by
+// key, anything else in override replaces the value in base. Arrays follow
+// the given strategy: replace, append, or index (merge element by element).
+// Neither argument is modified.
+func mergeValues(base, override interface{}, arrays string) interface{} {
+	switch o := override.(type) {
+	case map[string]interface{}:
+		b, ok := base.(map[string]interface{})
+		if !ok {
+			return override
+		}
+		result := make(map[string]interface{}, len(b)+len(o))
+		for k, v := range b {
+			result[k] = v
+		}
+		for _, k := range jt.OrderedKeys(o) {
+			if existing, ok := b[k]; ok {
+				result[k] = mergeValues(existing, o[k], arrays)
+			} else {
+				result[k] = o[k]
+			}
+		}
+		mergeMeta(result, b, o)
+		return result
+	case []interface{}:
+		b, ok := base.([]interface{})
+		if !ok {
+			return override
+		}
+		switch arrays {
+		case "append":
+			result := make([]interface{}, 0, len(b)+len(o))
+			return append(append(result, b...), o...)
+		case "index":
+			result := make([]interface{}, len(b))
+			copy(result, b)
+			for i, v := range o {
+				if i < len(result) {
+					result[i] = mergeValues(result[i], v, arrays)
+				} else {
+					result = append(result, v)
+				}
+			}
+			return result
+		}
+	}
+	return override
+}
+
+// mergeMeta keeps the hidden key order and key type information of both
+// sides: keys new in override are listed after those of base.
+func mergeMeta(result, base, override map[string]interface{}) {
+	if _, ok := base[jt.OrderKey]; ok {
+		order := append([]string{}, jt.OrderedKeys(base)...)
+		for _, k := range jt.OrderedKeys(override) {
+			if _, ok := base[k]; !ok {
+				order = append(order, k)
+			}
+		}
+		result[jt.OrderKey] = order
+	} else if _, ok := override[jt.OrderKey]; ok {
+		delete(result, jt.OrderKey)
+	}
+
+	baseTypes, _ := base[jt.KeyTypesKey].(map[string]string)
+	overrideTypes, _ := override[jt.KeyTypesKey].(map[string]string)
+	if len(baseTypes)+len(overrideTypes) > 0 {
+		types := make(map[string]string, len(baseTypes)+len(overrideTypes))
+		for k, t := range baseTypes {
+			types[k] = t
+		}
+		for k, t := range overrideTypes {
+			types[k] = t
+		}
+		result[jt.KeyTypesKey] = types
+	}
+}
+
+// runMerge implements `jt merge <base> <override>...`: the documents are
+// deep-merged left to right, documents of a multi-document file in order.
+// The result is rendered, or printed as JSON or YAML with -emit.
+func runMerge(args []string, arrays, emit string, popts jt.ParseOptions, opts renderOptions) {
+	if len(args) < 2 {
+		fail(exitUsage, "usage: jt merge <base> <override>...")
+	}
+	switch arrays {
+	case "replace", "append", "index":
+	default:
+		fail(exitUsage, "invalid -merge-arrays strategy '%s' (expected replace/append/index)", arrays)
+	}
+
+	var merged interface{}
+	for i, path := range args {
+		popts.Filename = path
+		data, isMultiDoc := parseInput(readFile(path), popts)
+		data = redactInput(data)
+		docs := []interface{}{data}
+		if isMultiDoc {
+			docs = data.([]interface{})
+		}
+		for j, doc := range docs {
+			if i == 0 && j == 0 {
+				merged = doc
+				continue
+			}
+			merged = mergeValues(merged, doc, arrays)
+		}
+	}
+
+	switch emit {
+	case "":
+		render(merged, opts, false)
+	case "json", "yaml":
+		printEmitted(merged, emit, false, opts)
+	default:
+		fail(exitUsage, "invalid -emit format '%s' (expected json/yaml)", emit)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/atotto/clipboard"
+)
+
+// deliver copies finished output to the clipboard and writes it to the -o
+// file, as requested. It reports whether that took care of the output;
+// otherwise the caller prints it.
+func deliver(content []byte, opts renderOptions) bool {
+	if opts.snapshot != "" {
+		snapshot(content, opts)
+		return true
+	}
+	if opts.copy {
+		if err := clipboard.WriteAll(string(content)); err != nil {
+			fail(exitError, "copying to clipboard: %v", err)
+		}
+	}
+	if opts.output != "" {
+		writeOutput(opts.output, content)
+		return true
+	}
+	return opts.copyOnly
+}
+
+// colorOutput reports whether output goes to a terminal and nowhere else, so
+// it may carry ANSI colors. Files and the clipboard get plain text.
+func colorOutput(opts renderOptions) bool {
+	return isTerminal() && opts.output == "" && !opts.copy && opts.snapshot == ""
+}
+
+// writeOutput writes rendered output to path, creating missing parent
+// directories. The content goes to a temporary file next to the target that
+// is renamed into place, so readers never see a half-written export.
+func writeOutput(path string, content []byte) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fail(exitError, "creating output directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		fail(exitError, "writing output: %v", err)
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		fail(exitError, "writing output: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// viewerModes are the values of -viewer: how output wider than the
+// terminal is shown.
+var viewerModes = []string{"tui", "pager", "none"}
+
+// pickViewer returns the viewer to use for wide output. The interactive
+// viewer needs a capable terminal, so dumb terminals get the pager.
+func pickViewer(mode string) string {
+	if mode == "tui" && os.Getenv("TERM") == "dumb" {
+		return "pager"
+	}
+	return mode
+}
+
+// page shows output through $PAGER, or `less -RS` so colors are kept and
+// long lines scroll sideways instead of wrapping. Without a usable pager
+// the output is printed as is.
+func page(output string) {
+	command := []string{"less", "-RS"}
+	if pager := os.Getenv("PAGER"); pager != "" {
+		args, err := splitArgs(pager)
+		if err == nil && len(args) > 0 {
+			command = args
+		}
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = strings.NewReader(output)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		// Keep colors and unwrapped lines when $PAGER is less without flags
+		cmd.Env = append(cmd.Env, "LESS=-RS")
+	}
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// The pager ran; it is up to the user how it ended
+			return
+		}
+		fmt.Fprint(os.Stdout, output)
+	}
+}
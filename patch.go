@@ -0,0 +1,461 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// patchFormats are the kinds of patch jt diff -emit-patch writes: JSON Patch
+// (RFC 6902), a list of operations, and JSON Merge Patch (RFC 7386), a
+// document of the changed keys.
+var patchFormats = []string{"json-patch", "merge-patch"}
+
+// pointer appends a key or index to a JSON Pointer (RFC 6901).
+func pointer(path, key string) string {
+	return path + "/" + strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func patchOp(op, path string, value interface{}, withValue bool) map[string]interface{} {
+	o := map[string]interface{}{"op": op, "path": path, jt.OrderKey: []string{"op", "path"}}
+	if withValue {
+		o["value"] = value
+		o[jt.OrderKey] = []string{"op", "path", "value"}
+	}
+	return o
+}
+
+// jsonPatch returns the operations that turn a into b. Like jt diff, it
+// compares arrays by position: elements past the end of the shorter array
+// are added or removed, the rest patched in place.
+func jsonPatch(a, b interface{}, path string, ops []interface{}) []interface{} {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			for _, k := range jt.OrderedKeys(av) {
+				if next, ok := bv[k]; ok {
+					ops = jsonPatch(av[k], next, pointer(path, k), ops)
+				} else {
+					ops = append(ops, patchOp("remove", pointer(path, k), nil, false))
+				}
+			}
+			for _, k := range jt.OrderedKeys(bv) {
+				if _, ok := av[k]; !ok {
+					ops = append(ops, patchOp("add", pointer(path, k), bv[k], true))
+				}
+			}
+			return ops
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			for i := 0; i < len(av) && i < len(bv); i++ {
+				ops = jsonPatch(av[i], bv[i], pointer(path, strconv.Itoa(i)), ops)
+			}
+			for i := len(av); i < len(bv); i++ {
+				ops = append(ops, patchOp("add", pointer(path, strconv.Itoa(i)), bv[i], true))
+			}
+			// From the end, so the indices of the elements still to be
+			// removed do not shift
+			for i := len(av) - 1; i >= len(bv); i-- {
+				ops = append(ops, patchOp("remove", pointer(path, strconv.Itoa(i)), nil, false))
+			}
+			return ops
+		}
+	}
+	if !sameDocument(a, b) {
+		ops = append(ops, patchOp("replace", path, b, true))
+	}
+	return ops
+}
+
+// mergePatch returns the merge patch that turns a into b: objects are
+// patched key by key, with null for removed keys, and anything else is
+// replaced whole. A null value in b cannot be told apart from a removal,
+// so it is reported.
+func mergePatch(a, b interface{}, path string) interface{} {
+	av, aok := a.(map[string]interface{})
+	bv, bok := b.(map[string]interface{})
+	if !aok || !bok {
+		reportNulls(b, path)
+		return b
+	}
+	patch := make(map[string]interface{})
+	var order []string
+	for _, k := range jt.OrderedKeys(av) {
+		if _, ok := bv[k]; !ok {
+			patch[k] = nil
+			order = append(order, k)
+		}
+	}
+	for _, k := range jt.OrderedKeys(bv) {
+		old, ok := av[k]
+		switch {
+		case !ok:
+			reportNulls(bv[k], path+"."+k)
+			patch[k] = bv[k]
+		case !sameDocument(old, bv[k]):
+			patch[k] = mergePatch(old, bv[k], path+"."+k)
+		default:
+			continue
+		}
+		order = append(order, k)
+	}
+	patch[jt.OrderKey] = order
+	return patch
+}
+
+func reportNulls(v interface{}, path string) {
+	walkLeaves(v, path, func(leafPath string, leaf interface{}) {
+		if leaf == nil {
+			warn("merge_patch", 0, "null at %s cannot be expressed in a merge patch, which would remove the key", leafPath)
+		}
+	})
+}
+
+// sameDocument reports whether a and b hold the same data, compared the
+// way jt diff compares them.
+func sameDocument(a, b interface{}) bool {
+	return len(diffDocuments(a, b)) == 0
+}
+
+// diffPatch returns the patch of the given format that turns a into b,
+// and whether it changes anything.
+func diffPatch(a, b interface{}, format string) (interface{}, bool) {
+	if format == "merge-patch" {
+		if sameDocument(a, b) {
+			return map[string]interface{}{}, false
+		}
+		return mergePatch(a, b, ""), true
+	}
+	ops := jsonPatch(a, b, "", []interface{}{})
+	return ops, len(ops) > 0
+}
+
+// applyPatchFile applies the patch in path to data, or to each document of
+// multi-document input: a JSON Patch if the file holds an array of
+// operations, a JSON Merge Patch if it holds an object.
+func applyPatchFile(path string, data interface{}, isMultiDoc bool, popts jt.ParseOptions) interface{} {
+	popts.Filename = path
+	patch, _ := parseInput(readFile(path), popts)
+	apply := func(doc interface{}) interface{} {
+		switch p := patch.(type) {
+		case []interface{}:
+			result, err := applyJSONPatch(doc, p)
+			if err != nil {
+				fail(exitError, "applying %s: %v", path, err)
+			}
+			return result
+		case map[string]interface{}:
+			return applyMergePatch(doc, p)
+		}
+		fail(exitUsage, "patch %s must be a JSON Patch (an array of operations) or a merge patch (an object)", path)
+		return nil // Unreachable
+	}
+	if docs, ok := data.([]interface{}); ok && isMultiDoc {
+		for i, doc := range docs {
+			docs[i] = apply(doc)
+		}
+		return docs
+	}
+	return apply(data)
+}
+
+// applyMergePatch applies a JSON Merge Patch: objects are patched key by
+// key, null removes a key, and anything else replaces the target.
+func applyMergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	result := make(map[string]interface{}, len(t))
+	for k, v := range t {
+		result[k] = v
+	}
+	for _, k := range jt.OrderedKeys(p) {
+		if p[k] == nil {
+			deleteKey(result, k)
+		} else {
+			setKey(result, k, applyMergePatch(t[k], p[k]))
+		}
+	}
+	return result
+}
+
+// setKey sets a key of an object, listing it last if the object records
+// its key order.
+func setKey(obj map[string]interface{}, key string, value interface{}) {
+	if order, ok := obj[jt.OrderKey].([]string); ok {
+		if _, exists := obj[key]; !exists {
+			// The order may be shared with other objects, so it is copied
+			obj[jt.OrderKey] = append(append([]string{}, order...), key)
+		}
+	}
+	obj[key] = value
+}
+
+func deleteKey(obj map[string]interface{}, key string) {
+	if order, ok := obj[jt.OrderKey].([]string); ok {
+		kept := make([]string, 0, len(order))
+		for _, k := range order {
+			if k != key {
+				kept = append(kept, k)
+			}
+		}
+		obj[jt.OrderKey] = kept
+	}
+	delete(obj, key)
+}
+
+// applyJSONPatch applies the operations of a JSON Patch in order; the
+// first one that fails, including a failed test, stops the patch.
+func applyJSONPatch(doc interface{}, ops []interface{}) (interface{}, error) {
+	for i, o := range ops {
+		op, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operation %d is not an object", i)
+		}
+		name := jt.ScalarString(op["op"])
+		path, ok := op["path"].(string)
+		if !ok {
+			return nil, fmt.Errorf("operation %d (%s) has no path", i, name)
+		}
+		var err error
+		doc, err = applyOperation(doc, name, path, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %v", i, name, path, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyOperation(doc interface{}, name, path string, op map[string]interface{}) (interface{}, error) {
+	value, hasValue := op["value"]
+	from, hasFrom := op["from"].(string)
+	switch name {
+	case "add", "replace", "test":
+		if !hasValue {
+			return nil, fmt.Errorf("value is missing")
+		}
+	case "move", "copy":
+		if !hasFrom {
+			return nil, fmt.Errorf("from is missing")
+		}
+	}
+
+	switch name {
+	case "add":
+		return addAt(doc, path, value)
+	case "remove":
+		return removeAt(doc, path)
+	case "replace":
+		if _, err := getAt(doc, path); err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return value, nil
+		}
+		return updateAt(doc, path, func(parent interface{}, key string) (interface{}, error) {
+			return setChild(parent, key, value)
+		})
+	case "move":
+		if strings.HasPrefix(path, from+"/") {
+			return nil, fmt.Errorf("cannot move %s into itself", from)
+		}
+		moved, err := getAt(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = removeAt(doc, from); err != nil {
+			return nil, err
+		}
+		return addAt(doc, path, moved)
+	case "copy":
+		copied, err := getAt(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return addAt(doc, path, copyValue(copied))
+	case "test":
+		current, err := getAt(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !sameDocument(current, value) {
+			return nil, fmt.Errorf("test failed: the value is %s", jsonText(current))
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation (expected add/remove/replace/move/copy/test)")
+}
+
+// pointerTokens splits a JSON Pointer into its unescaped reference tokens.
+func pointerTokens(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path must be empty or start with /")
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func getAt(doc interface{}, path string) (interface{}, error) {
+	tokens, err := pointerTokens(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		if doc, err = childOf(doc, t); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// updateAt calls fn with the parent of the value at path and the last
+// reference token, and returns doc with the parent fn returns in its
+// place.
+func updateAt(doc interface{}, path string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	tokens, err := pointerTokens(path)
+	if err != nil {
+		return nil, err
+	}
+	var update func(node interface{}, tokens []string) (interface{}, error)
+	update = func(node interface{}, tokens []string) (interface{}, error) {
+		if len(tokens) == 1 {
+			return fn(node, tokens[0])
+		}
+		child, err := childOf(node, tokens[0])
+		if err != nil {
+			return nil, err
+		}
+		if child, err = update(child, tokens[1:]); err != nil {
+			return nil, err
+		}
+		return setChild(node, tokens[0], child)
+	}
+	return update(doc, tokens)
+}
+
+func addAt(doc interface{}, path string, value interface{}) (interface{}, error) {
+	if path == "" {
+		return value, nil
+	}
+	return updateAt(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			setKey(p, key, value)
+			return p, nil
+		case []interface{}:
+			i := len(p)
+			if key != "-" {
+				var err error
+				if i, err = arrayIndex(key, len(p)+1); err != nil {
+					return nil, err
+				}
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		}
+		return nil, fmt.Errorf("cannot add to a %s", typeName(parent))
+	})
+}
+
+func removeAt(doc interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+	return updateAt(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		if _, err := childOf(parent, key); err != nil {
+			return nil, err
+		}
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			deleteKey(p, key)
+			return p, nil
+		case []interface{}:
+			i, _ := arrayIndex(key, len(p))
+			return append(p[:i], p[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove from a %s", typeName(parent))
+	})
+}
+
+func childOf(node interface{}, key string) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		v, ok := n[key]
+		if !ok || key == jt.OrderKey {
+			return nil, fmt.Errorf("key '%s' not found", key)
+		}
+		return v, nil
+	case []interface{}:
+		i, err := arrayIndex(key, len(n))
+		if err != nil {
+			return nil, err
+		}
+		return n[i], nil
+	}
+	return nil, fmt.Errorf("cannot look up '%s' in a %s", key, typeName(node))
+}
+
+func setChild(node interface{}, key string, value interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[key] = value
+		return n, nil
+	case []interface{}:
+		i, err := arrayIndex(key, len(n))
+		if err != nil {
+			return nil, err
+		}
+		n[i] = value
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot set '%s' in a %s", key, typeName(node))
+}
+
+// arrayIndex parses an array index below limit, without the leading zeros
+// and signs RFC 6901 does not allow.
+func arrayIndex(key string, limit int) (int, error) {
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || strconv.Itoa(i) != key {
+		return 0, fmt.Errorf("invalid array index '%s'", key)
+	}
+	if i >= limit {
+		return 0, fmt.Errorf("index %d out of bounds", i)
+	}
+	return i, nil
+}
+
+// copyValue copies objects and arrays deeply, so a copied value can be
+// patched on its own.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			c[k] = copyValue(item)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = copyValue(item)
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// walkLeaves calls fn for every scalar in v, and for every empty object or
+// array, with its path in selector syntax (.key.list[0]). The root itself
+// has the path ".".
+func walkLeaves(v interface{}, path string, fn func(path string, v interface{})) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := jt.OrderedKeys(v)
+		if len(keys) == 0 {
+			fn(orRoot(path), v)
+		}
+		for _, k := range keys {
+			walkLeaves(v[k], path+"."+k, fn)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			fn(orRoot(path), v)
+		}
+		for i, item := range v {
+			walkLeaves(item, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	default:
+		fn(orRoot(path), v)
+	}
+}
+
+// orRoot returns path, or "." for the empty path of the root.
+func orRoot(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+// pathLines lists every leaf of data as its path and value separated by a
+// tab, one per line, for fzf and the like. Tabs and line breaks in values
+// are escaped so each leaf stays on one line.
+func pathLines(data interface{}) []byte {
+	escape := strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+	var buf bytes.Buffer
+	walkLeaves(data, "", func(path string, v interface{}) {
+		value := jt.ScalarString(v)
+		switch v := v.(type) {
+		case map[string]interface{}:
+			value = "{}"
+		case []interface{}:
+			value = "[]"
+		case []byte:
+			value = string(v)
+		}
+		buf.WriteString(path + "\t" + escape.Replace(value) + "\n")
+	})
+	return buf.Bytes()
+}
+
+// pickPath returns the value at the path of a line chosen from -paths
+// output; anything after a tab is ignored. Paths are matched as -paths
+// wrote them, so keys that selectors cannot express are found as well;
+// other paths are read as selectors.
+func pickPath(data interface{}, line string) interface{} {
+	path, _, _ := strings.Cut(strings.TrimRight(line, "\r\n"), "\t")
+	if path = strings.TrimSpace(path); path == "" {
+		fail(exitUsage, "-pick: no path on stdin")
+	}
+	if v, ok := findPath(data, "", path); ok {
+		return v
+	}
+	return applySelector(data, path)
+}
+
+// findPath looks for the value whose path, spelled as by walkLeaves, is
+// target.
+func findPath(v interface{}, path, target string) (interface{}, bool) {
+	if orRoot(path) == target {
+		return v, true
+	}
+	if !strings.HasPrefix(target, path) {
+		return nil, false
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, k := range jt.OrderedKeys(v) {
+			if found, ok := findPath(v[k], path+"."+k, target); ok {
+				return found, true
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if found, ok := findPath(item, fmt.Sprintf("%s[%d]", path, i), target); ok {
+				return found, true
+			}
+		}
+	}
+	return nil, false
+}
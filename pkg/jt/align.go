@@ -0,0 +1,72 @@
+package jt
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+)
+
+// numericColumn reports whether a column holds only numbers (and nulls), and
+// the most digits any of them has after the decimal point. Such columns are
+// right-aligned with their decimal points lined up, so magnitudes can be
+// compared at a glance.
+func numericColumn(values []interface{}) (bool, int) {
+	numbers, decimals := 0, 0
+	for _, val := range values {
+		switch val.(type) {
+		case nil:
+			continue
+		case json.Number, float64, int, int64, uint64:
+			numbers++
+			decimals = max(decimals, fractionDigits(ScalarString(val)))
+		default:
+			return false, 0
+		}
+	}
+	return numbers > 0, decimals
+}
+
+// fractionDigits returns the number of digits after the decimal point of a
+// formatted number. Exponent notation is not padded.
+func fractionDigits(s string) int {
+	if strings.ContainsAny(s, "eE") {
+		return 0
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// padDecimals pads a formatted number on the right so that, right-aligned,
+// its decimal point lines up with numbers that have decimals digits after it.
+func padDecimals(s string, decimals int) string {
+	if decimals == 0 || strings.ContainsAny(s, "eE") {
+		return s
+	}
+	pad := decimals - fractionDigits(s)
+	if !strings.Contains(s, ".") {
+		pad++
+	}
+	if pad <= 0 {
+		return s
+	}
+	return s + strings.Repeat(" ", pad)
+}
+
+// alignColumns right-aligns the numeric columns of a table, headers included.
+func alignColumns(table *tablewriter.Table, numeric []bool) {
+	aligns := make([]tw.Align, len(numeric))
+	for i, n := range numeric {
+		aligns[i] = tw.AlignLeft
+		if n {
+			aligns[i] = tw.AlignRight
+		}
+	}
+	table.Configure(func(cfg *tablewriter.Config) {
+		cfg.Header.Alignment.PerColumn = aligns
+		cfg.Row.Alignment.PerColumn = aligns
+	})
+}
@@ -0,0 +1,89 @@
+package jt
+
+import (
+	"math"
+	"strings"
+)
+
+// barBlocks are the partial blocks a bar ends in, by eighths of a cell.
+var barBlocks = []rune(" ▏▎▍▌▋▊▉")
+
+// sparkBlocks are the heights of a sparkline, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// barScale is the range the bars of a numeric column are drawn against:
+// from zero, or the lowest value when it is negative, to the highest.
+type barScale struct {
+	lo, hi float64
+}
+
+func newBarScale(values []interface{}) barScale {
+	var s barScale
+	for _, val := range values {
+		if f, ok := numberValue(val); ok {
+			s.lo = math.Min(s.lo, f)
+			s.hi = math.Max(s.hi, f)
+		}
+	}
+	return s
+}
+
+// bar draws val as a bar width cells wide at most, padded to width so the
+// numbers of a right-aligned column stay lined up.
+func (s barScale) bar(val interface{}, width int) string {
+	f, ok := numberValue(val)
+	if !ok || s.hi <= s.lo {
+		return strings.Repeat(" ", width)
+	}
+	eighths := int(math.Round((f - s.lo) / (s.hi - s.lo) * float64(width*8)))
+	b := strings.Repeat("█", eighths/8)
+	if eighths%8 > 0 {
+		b += string(barBlocks[eighths%8])
+	}
+	return b + strings.Repeat(" ", width-DisplayWidth(b))
+}
+
+// withBar appends the bar for val to its formatted value.
+func withBar(value string, val interface{}, scale barScale, opts Options) string {
+	if opts.Bars <= 0 {
+		return value
+	}
+	return value + " " + scale.bar(val, opts.Bars)
+}
+
+// sparkline draws an array of numbers as one block per number, scaled to
+// their range, or returns false when v holds anything else.
+func sparkline(v []interface{}, maxWidth int) (string, bool) {
+	if len(v) < 2 {
+		return "", false
+	}
+	values := make([]float64, len(v))
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i, val := range v {
+		f, ok := numberValue(val)
+		if !ok {
+			return "", false
+		}
+		values[i] = f
+		lo, hi = math.Min(lo, f), math.Max(hi, f)
+	}
+	var b strings.Builder
+	for _, f := range values {
+		level := 0
+		if hi > lo {
+			level = int(math.Round((f - lo) / (hi - lo) * float64(len(sparkBlocks)-1)))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return TruncateWidth(b.String(), maxWidth), true
+}
+
+// numberValue is the value of a number; unlike ToFloat, it does not parse
+// strings.
+func numberValue(val interface{}) (float64, bool) {
+	if _, ok := val.(string); ok {
+		return 0, false
+	}
+	f, ok := ToFloat(val)
+	return f, ok && !math.IsNaN(f) && !math.IsInf(f, 0)
+}
@@ -0,0 +1,102 @@
+package jt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Strings shorter than this are never treated as base64; short tokens and
+// words are far more likely to be ordinary text.
+const minBase64Length = 64
+
+// decodeBase64Value returns the decoded bytes when s looks like a base64
+// blob (standard or URL alphabet, optionally wrapped over several lines).
+func decodeBase64Value(s string) ([]byte, bool) {
+	compact := strings.Join(strings.Fields(s), "")
+	if len(compact) < minBase64Length {
+		return nil, false
+	}
+
+	hasUpper, hasLower, onlyHex := false, false, true
+	for _, r := range compact {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= '0' && r <= '9', r == '+', r == '/', r == '-', r == '_', r == '=':
+		default:
+			return nil, false
+		}
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			onlyHex = false
+		}
+	}
+	// Hex digests use the same alphabet but are not base64
+	if onlyHex || !hasUpper || !hasLower {
+		return nil, false
+	}
+
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err := enc.DecodeString(compact); err == nil {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// FormatBytes formats a byte count using binary units, e.g. "4.2 KiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// isPrintableText reports whether data is UTF-8 text without control
+// characters other than common whitespace.
+func isPrintableText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// binaryString formats binary data for display: decoded text when requested
+// and printable, otherwise a summary of its encoding and size.
+func binaryString(data []byte, encoding string, mode string) string {
+	if mode == "decode" && isPrintableText(data) {
+		return string(data)
+	}
+	return fmt.Sprintf("%s, %s", encoding, FormatBytes(int64(len(data))))
+}
+
+// displayString formats a scalar for display, summarizing binary content
+// according to the Options.Base64 mode.
+func displayString(v interface{}, mode string) string {
+	switch v := v.(type) {
+	case string:
+		if mode != "raw" {
+			if data, ok := decodeBase64Value(v); ok {
+				return binaryString(data, "base64", mode)
+			}
+		}
+	case []byte:
+		return binaryString(v, "binary", mode)
+	}
+	return ScalarString(v)
+}
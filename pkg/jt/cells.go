@@ -0,0 +1,105 @@
+package jt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Cell is where a value is shown on a line of a table rendered by
+// RenderCells: the terminal columns it spans, from Start up to End, and its
+// path in the data, spelled like .items[0].name, or [2].name for a row of
+// an array.
+type Cell struct {
+	Start, End int
+	Path       string
+}
+
+// cellMark opens the invisible marks RenderCells puts around each line of
+// a value. tablewriter takes OSC sequences such as these to be zero width,
+// so the table is laid out as it is without them.
+const cellMark = "\x1b]jt;"
+
+// RenderCells renders data as a terminal table like RenderDocuments, also
+// returning the values shown on each line of the output, so a position in
+// the table can be traced back to the data. Values are found however they
+// are shown: truncated, humanized, with a bar or wrapped over several lines.
+func RenderCells(data interface{}, opts Options, isMultiDoc bool) (string, [][]Cell, error) {
+	opts.Format = "table"
+	opts.cells = &[]string{}
+	output, err := RenderDocuments(data, opts, isMultiDoc)
+	if err != nil {
+		return "", nil, err
+	}
+	output, cells := unmarkCells(output, *opts.cells)
+	return output, cells, nil
+}
+
+// atKey returns opts for rendering the value of key in the one at opts.path.
+// Paths are only kept track of for RenderCells.
+func (opts Options) atKey(key string) Options {
+	if opts.cells != nil {
+		opts.path += "." + key
+	}
+	return opts
+}
+
+// atIndex returns opts for rendering item i of the array at opts.path.
+func (opts Options) atIndex(i int) Options {
+	if opts.cells != nil {
+		opts.path += "[" + strconv.Itoa(i) + "]"
+	}
+	return opts
+}
+
+// markCell marks each line of a cell showing the value at opts.path, for
+// RenderCells. A cell holding a nested table is left as it is, its own
+// values being marked instead.
+func markCell(value string, opts Options) string {
+	if opts.cells == nil || strings.Contains(value, cellMark) {
+		return value
+	}
+	path := opts.path
+	if path == "" {
+		path = "."
+	}
+	open := cellMark + strconv.Itoa(len(*opts.cells)) + "\a"
+	*opts.cells = append(*opts.cells, path)
+	lines := strings.Split(value, "\n")
+	for i, line := range lines {
+		lines[i] = open + line + cellMark + "\a"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unmarkCells removes the marks of markCell from output, returning where
+// the values they marked ended up on each line.
+func unmarkCells(output string, paths []string) (string, [][]Cell) {
+	lines := strings.Split(output, "\n")
+	cells := make([][]Cell, len(lines))
+	for n, line := range lines {
+		var b strings.Builder
+		col, start, open := 0, 0, -1
+		for {
+			i := strings.Index(line, cellMark)
+			if i < 0 {
+				break
+			}
+			b.WriteString(line[:i])
+			col += DisplayWidth(line[:i])
+			line = line[i+len(cellMark):]
+			end := strings.IndexByte(line, '\a')
+			id := line[:end]
+			line = line[end+1:]
+			if id != "" {
+				open, _ = strconv.Atoi(id)
+				start = col
+			} else if open >= 0 {
+				cells[n] = append(cells[n], Cell{Start: start, End: col, Path: paths[open]})
+				open = -1
+			}
+		}
+		b.WriteString(line)
+		lines[n] = b.String()
+	}
+	return strings.Join(lines, "\n"), cells
+}
@@ -0,0 +1,59 @@
+package jt
+
+import "strings"
+
+// tableHeaders returns the headers of the table for an array of objects:
+// the union of their keys, or the columns asked for with Options.Columns.
+// Only the top-level table is projected; nested tables keep all their
+// columns. Keys in Options.Exclude are dropped at any level.
+func tableHeaders(v []interface{}, opts Options) []string {
+	headers := buildHeaders(v)
+	if opts.depth == 0 && len(opts.Columns) > 0 {
+		headers = append([]string{"[key]"}, opts.Columns...)
+	}
+	return excludeKeys(headers, opts)
+}
+
+// TableColumns returns the columns of the table drawn for an array of
+// objects at the top level, without the index column.
+func TableColumns(v []interface{}, opts Options) []string {
+	opts.depth = 0
+	headers := tableHeaders(v, opts)
+	if len(headers) > 0 && headers[0] == "[key]" {
+		headers = headers[1:]
+	}
+	return headers
+}
+
+// excludeKeys drops the keys in Options.Exclude.
+func excludeKeys(keys []string, opts Options) []string {
+	if len(opts.Exclude) == 0 {
+		return keys
+	}
+	kept := keys[:0:0]
+	for _, key := range keys {
+		if !opts.Exclude[key] {
+			kept = append(kept, key)
+		}
+	}
+	return kept
+}
+
+// LookupColumn returns the value of a column in a row: the key itself, or
+// for a dotted column such as metadata.name, the nested value it names.
+func LookupColumn(m map[string]interface{}, column string) (interface{}, bool) {
+	if val, ok := m[column]; ok {
+		return val, true
+	}
+	var current interface{} = m
+	for _, key := range strings.Split(column, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
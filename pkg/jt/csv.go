@@ -0,0 +1,68 @@
+package jt
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+)
+
+// parseCSV decodes CSV with a header row as an array of objects, one per
+// record, keyed by the header in column order. Values stay strings, as CSV
+// has no types; a header that repeats a column is reported as a duplicate
+// key, the later column winning.
+func parseCSV(input []byte) ([]interface{}, []duplicateKey, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(input, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []interface{}{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, csvParseError(input, err)
+	}
+
+	var duplicates []duplicateKey
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range header {
+		if seen[key] {
+			duplicates = append(duplicates, duplicateKey{key: key, line: 1})
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	rows := []interface{}{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, duplicates, nil
+		}
+		if err != nil {
+			return nil, nil, csvParseError(input, err)
+		}
+		if len(record) > len(header) {
+			line, _ := reader.FieldPos(len(header))
+			return nil, nil, &ParseError{Format: "CSV", Msg: "more fields than the header has columns", Input: input, Line: line}
+		}
+		row := map[string]interface{}{OrderKey: keys}
+		for i, key := range header {
+			value := ""
+			if i < len(record) {
+				value = record[i]
+			}
+			row[key] = value
+		}
+		rows = append(rows, row)
+	}
+}
+
+func csvParseError(input []byte, err error) *ParseError {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &ParseError{Format: "CSV", Msg: parseErr.Err.Error(), Input: input, Line: parseErr.Line, Column: parseErr.Column}
+	}
+	return &ParseError{Format: "CSV", Msg: err.Error(), Input: input}
+}
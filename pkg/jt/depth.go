@@ -0,0 +1,56 @@
+package jt
+
+import "fmt"
+
+// DefaultMaxDepth bounds how deeply documents may nest. Parsing and rendering
+// are recursive, so a deeply nested or crafted document could otherwise
+// exhaust the stack.
+const DefaultMaxDepth = 1000
+
+func depthError(format string, input []byte, maxDepth int) *ParseError {
+	return &ParseError{
+		Format: format,
+		Input:  input,
+		Msg:    fmt.Sprintf("document is nested more than %d levels deep (see -max-depth)", maxDepth),
+	}
+}
+
+// exceedsDepth reports whether v nests more than maxDepth levels deep.
+func exceedsDepth(v interface{}, maxDepth int) bool {
+	if maxDepth < 0 {
+		return true
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if !IsMetaKey(k) && exceedsDepth(val, maxDepth-1) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, val := range v {
+			if exceedsDepth(val, maxDepth-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Summary describes an object or array compactly, for places where it
+// is not expanded into a nested table.
+func Summary(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if n := len(OrderedKeys(v)); n != 1 {
+			return fmt.Sprintf("{%d keys}", n)
+		}
+		return "{1 key}"
+	case []interface{}:
+		if len(v) != 1 {
+			return fmt.Sprintf("[%d items]", len(v))
+		}
+		return "[1 item]"
+	}
+	return ScalarString(v)
+}
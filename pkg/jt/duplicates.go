@@ -0,0 +1,86 @@
+package jt
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// duplicateKey describes a key that appears more than once in one object.
+// Decoders silently keep the last value, which usually means a broken config.
+type duplicateKey struct {
+	key  string
+	line int
+}
+
+type jsonFrame struct {
+	isObject  bool
+	expectKey bool
+	keys      map[string]bool
+}
+
+// findJSONDuplicates scans input, which must be valid JSON, for duplicate
+// object keys.
+func findJSONDuplicates(input []byte) []duplicateKey {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	var duplicates []duplicateKey
+	var stack []*jsonFrame
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return duplicates
+		}
+
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				stack = append(stack, &jsonFrame{isObject: true, expectKey: true, keys: map[string]bool{}})
+			case '[':
+				stack = append(stack, &jsonFrame{})
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+				if len(stack) > 0 && stack[len(stack)-1].isObject {
+					stack[len(stack)-1].expectKey = true
+				}
+			}
+		case string:
+			if top != nil && top.isObject && top.expectKey {
+				if top.keys[t] {
+					line := bytes.Count(input[:decoder.InputOffset()], []byte("\n")) + 1
+					duplicates = append(duplicates, duplicateKey{key: t, line: line})
+				}
+				top.keys[t] = true
+				top.expectKey = false
+				continue
+			}
+			if top != nil && top.isObject {
+				top.expectKey = true
+			}
+		default:
+			if top != nil && top.isObject {
+				top.expectKey = true
+			}
+		}
+	}
+}
+
+// reportDuplicates passes duplicate keys to opts.OnDuplicate, or returns the
+// first as an error when strict.
+func reportDuplicates(duplicates []duplicateKey, opts ParseOptions) error {
+	if opts.Strict && len(duplicates) > 0 {
+		d := duplicates[0]
+		return &DuplicateKeyError{Key: d.key, Line: d.line}
+	}
+	if opts.OnDuplicate != nil {
+		for _, d := range duplicates {
+			opts.OnDuplicate(d.key, d.line)
+		}
+	}
+	return nil
+}
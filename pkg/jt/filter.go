@@ -0,0 +1,1526 @@
+package jt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A filter is a selector in the style of jq: paths such as .items[0].name,
+// [from:to] slices, .[] or .* to iterate over an array or object, .. to
+// recurse into it, pipes, comma-separated outputs, comparisons, arithmetic,
+// and the functions in filterFunctions:
+//
+//	.items[] | select(.status == "active") | {name, age: .spec.age}
+//
+// Every filter maps its input to a stream of outputs. Accessing a missing
+// key gives null, as in jq; a ? after a step drops its errors.
+
+// filter evaluates a parsed filter against its input.
+type filter func(v interface{}) ([]interface{}, error)
+
+// filterFunctions are the functions filters may call, by name and number of
+// arguments. Arguments are filters evaluated against the input of the call.
+var filterFunctions = map[string]func(v interface{}, args []filter) ([]interface{}, error){}
+
+func init() {
+	one := func(f func(v interface{}) (interface{}, error)) func(interface{}, []filter) ([]interface{}, error) {
+		return func(v interface{}, _ []filter) ([]interface{}, error) {
+			out, err := f(v)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{out}, nil
+		}
+	}
+	// withArg calls f with each output of the argument
+	withArg := func(f func(v, arg interface{}) (interface{}, error)) func(interface{}, []filter) ([]interface{}, error) {
+		return func(v interface{}, args []filter) ([]interface{}, error) {
+			values, err := args[0](v)
+			if err != nil {
+				return nil, err
+			}
+			var outs []interface{}
+			for _, arg := range values {
+				out, err := f(v, arg)
+				if err != nil {
+					return nil, err
+				}
+				outs = append(outs, out)
+			}
+			return outs, nil
+		}
+	}
+	text := func(name string, f func(s string) interface{}) func(interface{}, []filter) ([]interface{}, error) {
+		return one(func(v interface{}) (interface{}, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s cannot be applied to %s", name, filterDescribe(v))
+			}
+			return f(s), nil
+		})
+	}
+	textArg := func(name string, f func(s, arg string) interface{}) func(interface{}, []filter) ([]interface{}, error) {
+		return withArg(func(v, arg interface{}) (interface{}, error) {
+			s, ok := v.(string)
+			a, argOK := arg.(string)
+			if !ok || !argOK {
+				return nil, fmt.Errorf("%s needs strings, got %s and %s", name, filterDescribe(v), filterDescribe(arg))
+			}
+			return f(s, a), nil
+		})
+	}
+
+	filterFunctions = map[string]func(v interface{}, args []filter) ([]interface{}, error){
+		"empty/0": func(interface{}, []filter) ([]interface{}, error) { return nil, nil },
+		"not/0":   one(func(v interface{}) (interface{}, error) { return !filterTruthy(v), nil }),
+		"length/0": one(func(v interface{}) (interface{}, error) {
+			switch v := v.(type) {
+			case nil:
+				return Number(0), nil
+			case string:
+				return Number(float64(len([]rune(v)))), nil
+			case []interface{}:
+				return Number(float64(len(v))), nil
+			case map[string]interface{}:
+				return Number(float64(len(OrderedKeys(v)))), nil
+			}
+			if f, ok := ToFloat(v); ok && !isText(v) {
+				return Number(math.Abs(f)), nil
+			}
+			return nil, fmt.Errorf("%s has no length", filterDescribe(v))
+		}),
+		"keys/0": one(func(v interface{}) (interface{}, error) {
+			return filterKeys(v, true)
+		}),
+		"keys_unsorted/0": one(func(v interface{}) (interface{}, error) {
+			return filterKeys(v, false)
+		}),
+		"has/1": withArg(func(v, key interface{}) (interface{}, error) {
+			switch v := v.(type) {
+			case map[string]interface{}:
+				if k, ok := key.(string); ok {
+					_, exists := v[k]
+					return exists && !IsMetaKey(k), nil
+				}
+			case []interface{}:
+				if i, ok := ToFloat(key); ok && !isText(key) {
+					return i >= 0 && int(i) < len(v), nil
+				}
+			}
+			return nil, fmt.Errorf("cannot check whether %s has a key %s", filterDescribe(v), filterDescribe(key))
+		}),
+		"select/1": func(v interface{}, args []filter) ([]interface{}, error) {
+			conds, err := args[0](v)
+			if err != nil {
+				return nil, err
+			}
+			var outs []interface{}
+			for _, c := range conds {
+				if filterTruthy(c) {
+					outs = append(outs, v)
+				}
+			}
+			return outs, nil
+		},
+		"map/1": func(v interface{}, args []filter) ([]interface{}, error) {
+			items, err := filterIterate(v)
+			if err != nil {
+				return nil, err
+			}
+			result := []interface{}{}
+			for _, item := range items {
+				outs, err := args[0](item)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, outs...)
+			}
+			return []interface{}{result}, nil
+		},
+		"type/0": one(func(v interface{}) (interface{}, error) { return filterType(v), nil }),
+		"tostring/0": one(func(v interface{}) (interface{}, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return filterJSON(v), nil
+		}),
+		"tonumber/0": one(func(v interface{}) (interface{}, error) {
+			f, ok := ToFloat(v)
+			if !ok {
+				return nil, fmt.Errorf("cannot parse %s as a number", filterDescribe(v))
+			}
+			return Number(f), nil
+		}),
+		"ascii_downcase/0": text("ascii_downcase", func(s string) interface{} { return strings.ToLower(s) }),
+		"ascii_upcase/0":   text("ascii_upcase", func(s string) interface{} { return strings.ToUpper(s) }),
+		"startswith/1":     textArg("startswith", func(s, arg string) interface{} { return strings.HasPrefix(s, arg) }),
+		"endswith/1":       textArg("endswith", func(s, arg string) interface{} { return strings.HasSuffix(s, arg) }),
+		"ltrimstr/1":       textArg("ltrimstr", func(s, arg string) interface{} { return strings.TrimPrefix(s, arg) }),
+		"rtrimstr/1":       textArg("rtrimstr", func(s, arg string) interface{} { return strings.TrimSuffix(s, arg) }),
+		"split/1": textArg("split", func(s, sep string) interface{} {
+			parts := []interface{}{}
+			for _, p := range strings.Split(s, sep) {
+				parts = append(parts, p)
+			}
+			return parts
+		}),
+		"test/1": withArg(func(v, arg interface{}) (interface{}, error) {
+			s, ok := v.(string)
+			pattern, argOK := arg.(string)
+			if !ok || !argOK {
+				return nil, fmt.Errorf("test needs strings, got %s and %s", filterDescribe(v), filterDescribe(arg))
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression '%s': %v", pattern, err)
+			}
+			return re.MatchString(s), nil
+		}),
+		"contains/1": withArg(func(v, arg interface{}) (interface{}, error) {
+			if filterType(v) != filterType(arg) {
+				return nil, fmt.Errorf("cannot check whether %s contains %s", filterDescribe(v), filterDescribe(arg))
+			}
+			return filterContains(v, arg), nil
+		}),
+		"join/1": withArg(func(v, sep interface{}) (interface{}, error) {
+			items, ok := v.([]interface{})
+			s, sepOK := sep.(string)
+			if !ok || !sepOK {
+				return nil, fmt.Errorf("join needs an array and a string, got %s and %s", filterDescribe(v), filterDescribe(sep))
+			}
+			parts := make([]string, len(items))
+			for i, item := range items {
+				if item != nil {
+					parts[i] = ScalarString(item)
+				}
+			}
+			return strings.Join(parts, s), nil
+		}),
+		"sort/0": one(func(v interface{}) (interface{}, error) {
+			return filterSortBy(v, nil)
+		}),
+		"sort_by/1": func(v interface{}, args []filter) ([]interface{}, error) {
+			sorted, err := filterSortBy(v, args[0])
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{sorted}, nil
+		},
+		"group_by/1": func(v interface{}, args []filter) ([]interface{}, error) {
+			groups, err := filterGroupBy(v, args[0], false)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{groups}, nil
+		},
+		"unique/0": one(func(v interface{}) (interface{}, error) {
+			return filterGroupBy(v, nil, true)
+		}),
+		"unique_by/1": func(v interface{}, args []filter) ([]interface{}, error) {
+			unique, err := filterGroupBy(v, args[0], true)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{unique}, nil
+		},
+		"reverse/0": one(func(v interface{}) (interface{}, error) {
+			switch v := v.(type) {
+			case nil:
+				return []interface{}{}, nil
+			case string:
+				r := []rune(v)
+				slices.Reverse(r)
+				return string(r), nil
+			case []interface{}:
+				r := slices.Clone(v)
+				slices.Reverse(r)
+				return r, nil
+			}
+			return nil, fmt.Errorf("cannot reverse %s", filterDescribe(v))
+		}),
+		"first/0": one(func(v interface{}) (interface{}, error) { return filterIndex(v, Number(0)) }),
+		"last/0":  one(func(v interface{}) (interface{}, error) { return filterIndex(v, Number(-1)) }),
+		"min/0": one(func(v interface{}) (interface{}, error) {
+			return filterExtreme(v, -1)
+		}),
+		"max/0": one(func(v interface{}) (interface{}, error) {
+			return filterExtreme(v, 1)
+		}),
+		"add/0": one(func(v interface{}) (interface{}, error) {
+			items, err := filterIterate(v)
+			if err != nil {
+				return nil, err
+			}
+			var sum interface{}
+			for _, item := range items {
+				if sum, err = filterArithmetic("+", sum, item); err != nil {
+					return nil, err
+				}
+			}
+			return sum, nil
+		}),
+		"any/0": one(func(v interface{}) (interface{}, error) {
+			items, err := filterIterate(v)
+			return slices.ContainsFunc(items, filterTruthy), err
+		}),
+		"all/0": one(func(v interface{}) (interface{}, error) {
+			items, err := filterIterate(v)
+			return !slices.ContainsFunc(items, func(item interface{}) bool { return !filterTruthy(item) }), err
+		}),
+		"values/0": func(v interface{}, _ []filter) ([]interface{}, error) {
+			if v == nil {
+				return nil, nil
+			}
+			return []interface{}{v}, nil
+		},
+		"to_entries/0": one(filterToEntries),
+		"from_entries/0": one(func(v interface{}) (interface{}, error) {
+			return filterFromEntries(v)
+		}),
+		"with_entries/1": func(v interface{}, args []filter) ([]interface{}, error) {
+			entries, err := filterToEntries(v)
+			if err != nil {
+				return nil, err
+			}
+			var mapped []interface{}
+			for _, e := range entries.([]interface{}) {
+				outs, err := args[0](e)
+				if err != nil {
+					return nil, err
+				}
+				mapped = append(mapped, outs...)
+			}
+			result, err := filterFromEntries(mapped)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{result}, nil
+		},
+		"floor/0": one(func(v interface{}) (interface{}, error) {
+			f, ok := ToFloat(v)
+			if !ok || isText(v) {
+				return nil, fmt.Errorf("floor of %s", filterDescribe(v))
+			}
+			return Number(math.Floor(f)), nil
+		}),
+		"recurse/0": func(v interface{}, _ []filter) ([]interface{}, error) { return filterRecurse(v) },
+		"tojson/0":  one(func(v interface{}) (interface{}, error) { return filterJSON(v), nil }),
+	}
+}
+
+// IsFilter reports whether selector uses more than the keys and [index]
+// steps of a path, and is evaluated as a filter.
+func IsFilter(selector string) bool {
+	s := strings.TrimSpace(selector)
+	for _, marker := range []string{"|", "[]", "(", "{", ",", "\"", "==", "!=", "<", ">", "?", " + ", " - ", " * ", " / ", " and ", " or ", " // "} {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	if strings.HasPrefix(s, "[") && !strings.HasPrefix(s, "[0") && (len(s) < 2 || s[1] < '0' || s[1] > '9') {
+		return true
+	}
+	name, _, _ := strings.Cut(s, " ")
+	_, ok := filterFunctions[name+"/0"]
+	return ok
+}
+
+// selectFilter evaluates a filter. Like a path, one that starts with a key
+// is applied to each element of an array, such as the documents of a
+// multi-document file. A single output is returned as it is, and several,
+// or none, as an array; so is the output of a filter that iterates.
+func selectFilter(data interface{}, selector string) (interface{}, error) {
+	f, p, err := parseFilter(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := []interface{}{data}
+	trimmed := strings.TrimSpace(selector)
+	if docs, ok := data.([]interface{}); ok && strings.HasPrefix(trimmed, ".") && len(trimmed) > 1 && (trimmed[1] == '"' || trimmed[1] == '_' || unicode.IsLetter(rune(trimmed[1]))) {
+		inputs = docs
+		p.streams = true
+	}
+	var outs []interface{}
+	for _, input := range inputs {
+		results, err := f(input)
+		if err != nil {
+			return nil, err
+		}
+		outs = append(outs, results...)
+	}
+	if len(outs) == 1 && !p.streams {
+		return outs[0], nil
+	}
+	if outs == nil {
+		outs = []interface{}{}
+	}
+	return outs, nil
+}
+
+// parseFilter parses selector as a filter, returning the parser too for
+// what it found out about the filter's outputs.
+func parseFilter(selector string) (filter, *filterParser, error) {
+	p := &filterParser{src: selector}
+	p.next()
+	f, err := p.pipe()
+	if err == nil && p.tok != "" {
+		err = fmt.Errorf("unexpected %s", p.describe())
+	}
+	if p.err != nil {
+		err = p.err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid filter '%s': %v", selector, err)
+	}
+	return f, p, nil
+}
+
+// filterParser is a recursive descent parser over the tokens of a filter.
+type filterParser struct {
+	src     string
+	pos     int
+	tok     string // "" at the end
+	str     bool   // tok is a string literal, unquoted
+	field   bool   // tok is a .key, without the dot
+	err     error  // from reading the tokens, such as an unterminated string
+	streams bool   // the filter may have other than one output
+}
+
+var filterOperators = []string{".*", "..", "==", "!=", "<=", ">=", "//", "|", ",", "+", "-", "*", "/", "%", "<", ">", "(", ")", "[", "]", "{", "}", ":", ";", "?", "."}
+
+func isFilterIdent(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
+
+func (p *filterParser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	p.str, p.field = false, false
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+	rest := p.src[p.pos:]
+	switch c := rest[0]; {
+	case c == '"':
+		end := 1
+		for end < len(rest) && rest[end] != '"' {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(rest) {
+			p.err = fmt.Errorf("unterminated string starting at %s", rest)
+			p.tok, p.pos = "", len(p.src)
+			return
+		}
+		s, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			p.err = fmt.Errorf("invalid string %s", rest[:end+1])
+			p.tok, p.pos = "", len(p.src)
+			return
+		}
+		p.tok, p.str = s, true
+		p.pos += end + 1
+		return
+	case c == '.' && len(rest) > 1 && isFilterIdent(rest[1], true):
+		n := 2
+		for n < len(rest) && isFilterIdent(rest[n], false) {
+			n++
+		}
+		p.tok, p.field = rest[1:n], true
+		p.pos += n
+		return
+	case c >= '0' && c <= '9':
+		n := 1
+		for n < len(rest) && (rest[n] >= '0' && rest[n] <= '9' || rest[n] == '.' || rest[n] == 'e' || rest[n] == 'E') {
+			n++
+		}
+		p.tok = rest[:n]
+		p.pos += n
+		return
+	case isFilterIdent(c, true):
+		n := 1
+		for n < len(rest) && isFilterIdent(rest[n], false) {
+			n++
+		}
+		p.tok = rest[:n]
+		p.pos += n
+		return
+	}
+	for _, op := range filterOperators {
+		if strings.HasPrefix(rest, op) {
+			p.tok = op
+			p.pos += len(op)
+			return
+		}
+	}
+	p.tok = rest[:1]
+	p.pos++
+}
+
+// is reports whether the current token is the operator or keyword op.
+func (p *filterParser) is(op string) bool {
+	return !p.str && !p.field && p.tok == op
+}
+
+func (p *filterParser) expect(op, after string) error {
+	if !p.is(op) {
+		return fmt.Errorf("expected %s after %s, got %s", op, after, p.describe())
+	}
+	p.next()
+	return nil
+}
+
+func (p *filterParser) describe() string {
+	switch {
+	case p.tok == "":
+		return "end of filter"
+	case p.str:
+		return strconv.Quote(p.tok)
+	case p.field:
+		return "'." + p.tok + "'"
+	}
+	return "'" + p.tok + "'"
+}
+
+func (p *filterParser) pipe() (filter, error) {
+	left, err := p.comma()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("|") {
+		p.next()
+		right, err := p.comma()
+		if err != nil {
+			return nil, err
+		}
+		left = filterPipe(left, right)
+	}
+	return left, nil
+}
+
+func (p *filterParser) comma() (filter, error) {
+	left, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	for p.is(",") {
+		p.streams = true
+		p.next()
+		right, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		first := left
+		left = func(v interface{}) ([]interface{}, error) {
+			a, err := first(v)
+			if err != nil {
+				return nil, err
+			}
+			b, err := right(v)
+			return append(a, b...), err
+		}
+	}
+	return left, nil
+}
+
+// filterPrecedence lists the binary operators from loosest to tightest.
+var filterPrecedence = [][]string{
+	{"//"},
+	{"or"},
+	{"and"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *filterParser) binary(level int) (filter, error) {
+	if level == len(filterPrecedence) {
+		return p.postfix()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for !p.str && !p.field && slices.Contains(filterPrecedence[level], p.tok) {
+		op := p.tok
+		p.next()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = filterBinary(op, left, right)
+	}
+	return left, nil
+}
+
+// postfix parses a term followed by any number of .key, [index], [] and ?
+// steps.
+func (p *filterParser) postfix() (filter, error) {
+	// f is the filter up to the last step, which ? applies to
+	f := filter(filterIdentity)
+	last, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var step filter
+		switch {
+		case p.field:
+			step = filterKey(p.tok)
+			p.next()
+		case p.is(".") && p.peekString():
+			p.next()
+			step = filterKey(p.tok)
+			p.next()
+		case p.is(".") && p.peek() == '[':
+			p.next()
+			continue
+		case p.is(".*"):
+			p.next()
+			p.streams = true
+			step = filterEach
+		case p.is("["):
+			if step, err = p.bracket(); err != nil {
+				return nil, err
+			}
+		case p.is("?"):
+			p.next()
+			last = filterTry(last)
+			continue
+		default:
+			return filterPipe(f, last), nil
+		}
+		f, last = filterPipe(f, last), step
+	}
+}
+
+// peek returns the next character of the source after the current token.
+func (p *filterParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *filterParser) peekString() bool {
+	return p.peek() == '"'
+}
+
+// bracket parses [], [index] and [from:to] steps, from the [.
+func (p *filterParser) bracket() (filter, error) {
+	p.next()
+	if p.is("*") && p.peek() == ']' {
+		p.next()
+	}
+	if p.is("]") {
+		p.next()
+		p.streams = true
+		return filterEach, nil
+	}
+	var index, end filter
+	var err error
+	if !p.is(":") {
+		if index, err = p.pipe(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is(":") {
+		p.next()
+		if !p.is("]") {
+			if end, err = p.pipe(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("]", "slice"); err != nil {
+			return nil, err
+		}
+		return filterSlice(index, end), nil
+	}
+	if err := p.expect("]", "index"); err != nil {
+		return nil, err
+	}
+	return func(v interface{}) ([]interface{}, error) {
+		keys, err := index(v)
+		if err != nil {
+			return nil, err
+		}
+		var outs []interface{}
+		for _, k := range keys {
+			out, err := filterIndex(v, k)
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, out)
+		}
+		return outs, nil
+	}, nil
+}
+
+// filterSlice is the [from:to] step of arrays and strings; a nil bound is
+// left out.
+func filterSlice(from, to filter) filter {
+	bound := func(f filter, v interface{}) (*int, error) {
+		if f == nil {
+			return nil, nil
+		}
+		outs, err := f(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(outs) != 1 || outs[0] == nil {
+			return nil, nil
+		}
+		n, ok := ToFloat(outs[0])
+		if !ok || isText(outs[0]) {
+			return nil, fmt.Errorf("cannot slice with %s", filterDescribe(outs[0]))
+		}
+		i := int(math.Floor(n))
+		return &i, nil
+	}
+	return func(v interface{}) ([]interface{}, error) {
+		start, err := bound(from, v)
+		if err != nil {
+			return nil, err
+		}
+		end, err := bound(to, v)
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case nil:
+			return []interface{}{nil}, nil
+		case []interface{}:
+			i, j := SliceBounds(len(v), start, end)
+			return []interface{}{v[i:j]}, nil
+		case string:
+			r := []rune(v)
+			i, j := SliceBounds(len(r), start, end)
+			return []interface{}{string(r[i:j])}, nil
+		}
+		return nil, fmt.Errorf("cannot slice %s", filterDescribe(v))
+	}
+}
+
+func (p *filterParser) term() (filter, error) {
+	tok := p.tok
+	switch {
+	case p.str:
+		p.next()
+		return filterConst(tok), nil
+	case p.field:
+		p.next()
+		return filterKey(tok), nil
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of filter")
+	case tok == "..":
+		start := p.pos
+		p.next()
+		p.streams = true
+		if p.pos-len(p.tok) == start && !p.str && p.tok != "" && isFilterIdent(p.tok[0], true) {
+			key := p.tok
+			p.next()
+			return filterPipe(filterRecurse, func(v interface{}) ([]interface{}, error) {
+				if m, ok := v.(map[string]interface{}); ok {
+					if value, exists := m[key]; exists && !IsMetaKey(key) {
+						return []interface{}{value}, nil
+					}
+				}
+				return nil, nil
+			}), nil
+		}
+		return filterRecurse, nil
+	case tok == ".*":
+		p.next()
+		p.streams = true
+		return filterEach, nil
+	case tok == ".":
+		p.next()
+		if p.str {
+			key := p.tok
+			p.next()
+			return filterKey(key), nil
+		}
+		return filterIdentity, nil
+	case tok == "-":
+		p.next()
+		operand, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		return filterBinary("-", filterConst(Number(0)), operand), nil
+	case tok[0] >= '0' && tok[0] <= '9':
+		if _, err := strconv.ParseFloat(tok, 64); err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", tok)
+		}
+		p.next()
+		return filterConst(json.Number(tok)), nil
+	case tok == "(":
+		p.next()
+		inner, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")", "the expression in parentheses")
+	case tok == "[" && strings.HasPrefix(p.src[p.pos:], "*]"):
+		return p.bracket()
+	case tok == "[":
+		return p.array()
+	case tok == "{":
+		return p.object()
+	}
+	literals := map[string]interface{}{"true": true, "false": false, "null": nil}
+	if v, ok := literals[tok]; ok {
+		p.next()
+		return filterConst(v), nil
+	}
+	if !isFilterIdent(tok[0], true) {
+		return nil, fmt.Errorf("unexpected %s", p.describe())
+	}
+	return p.call()
+}
+
+// call parses a function call: a name, with arguments separated by ; in
+// parentheses.
+func (p *filterParser) call() (filter, error) {
+	name := p.tok
+	p.next()
+	var args []filter
+	if p.is("(") {
+		p.next()
+		// what the arguments output is the function's business, as with
+		// map(select(f)); the functions that stream are named below
+		streams := p.streams
+		for {
+			arg, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.is(";") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")", "the arguments of "+name); err != nil {
+			return nil, err
+		}
+		p.streams = streams
+	}
+	fn, ok := filterFunctions[fmt.Sprintf("%s/%d", name, len(args))]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s/%d", name, len(args))
+	}
+	switch name {
+	case "select", "empty", "values", "recurse":
+		p.streams = true
+	}
+	return func(v interface{}) ([]interface{}, error) {
+		outs, err := fn(v, args)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return outs, nil
+	}, nil
+}
+
+// array parses [f], which collects the outputs of f into an array.
+func (p *filterParser) array() (filter, error) {
+	p.next()
+	if p.is("]") {
+		p.next()
+		return func(interface{}) ([]interface{}, error) { return []interface{}{[]interface{}{}}, nil }, nil
+	}
+	streams := p.streams
+	inner, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	p.streams = streams
+	if err := p.expect("]", "the array"); err != nil {
+		return nil, err
+	}
+	return func(v interface{}) ([]interface{}, error) {
+		outs, err := inner(v)
+		if outs == nil {
+			outs = []interface{}{}
+		}
+		return []interface{}{outs}, err
+	}, nil
+}
+
+// object parses {key: f, ...}. A key is a name or string, taken from the
+// input when it has no value, or an expression in parentheses.
+func (p *filterParser) object() (filter, error) {
+	p.next()
+	type entry struct {
+		key   filter
+		value filter
+	}
+	var entries []entry
+	for !p.is("}") {
+		var e entry
+		switch {
+		case p.str || p.field || p.tok != "" && isFilterIdent(p.tok[0], true):
+			key := p.tok
+			if p.field {
+				key = "." + key
+			}
+			p.next()
+			e.key, e.value = filterConst(key), filterKey(key)
+		case p.is("("):
+			p.next()
+			key, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")", "the key"); err != nil {
+				return nil, err
+			}
+			e.key = key
+		default:
+			return nil, fmt.Errorf("expected a key in the object, got %s", p.describe())
+		}
+		if p.is(":") {
+			p.next()
+			value, err := p.binary(0)
+			if err != nil {
+				return nil, err
+			}
+			e.value = value
+		} else if e.value == nil {
+			return nil, fmt.Errorf("expected : after the key in parentheses, got %s", p.describe())
+		}
+		entries = append(entries, e)
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect("}", "the object"); err != nil {
+		return nil, err
+	}
+	return func(v interface{}) ([]interface{}, error) {
+		results := []map[string]interface{}{{OrderKey: []string{}}}
+		for _, e := range entries {
+			keys, err := e.key(v)
+			if err != nil {
+				return nil, err
+			}
+			values, err := e.value(v)
+			if err != nil {
+				return nil, err
+			}
+			var next []map[string]interface{}
+			for _, result := range results {
+				for _, k := range keys {
+					key, ok := k.(string)
+					if !ok {
+						return nil, fmt.Errorf("object keys must be strings, got %s", filterDescribe(k))
+					}
+					for _, value := range values {
+						m := make(map[string]interface{}, len(result)+1)
+						for rk, rv := range result {
+							m[rk] = rv
+						}
+						order := result[OrderKey].([]string)
+						if _, exists := result[key]; !exists {
+							order = append(order[:len(order):len(order)], key)
+						}
+						m[key], m[OrderKey] = value, order
+						next = append(next, m)
+					}
+				}
+			}
+			results = next
+		}
+		outs := make([]interface{}, len(results))
+		for i, m := range results {
+			outs[i] = m
+		}
+		return outs, nil
+	}, nil
+}
+
+func filterIdentity(v interface{}) ([]interface{}, error) {
+	return []interface{}{v}, nil
+}
+
+func filterConst(c interface{}) filter {
+	return func(interface{}) ([]interface{}, error) { return []interface{}{c}, nil }
+}
+
+func filterKey(key string) filter {
+	return func(v interface{}) ([]interface{}, error) {
+		out, err := filterIndex(v, key)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{out}, nil
+	}
+}
+
+// filterEach is the [] step: the elements of an array or the values of
+// an object.
+func filterEach(v interface{}) ([]interface{}, error) {
+	return filterIterate(v)
+}
+
+func filterIterate(v interface{}) ([]interface{}, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		return v, nil
+	case map[string]interface{}:
+		keys := OrderedKeys(v)
+		values := make([]interface{}, len(keys))
+		for i, k := range keys {
+			values[i] = v[k]
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s", filterDescribe(v))
+}
+
+// filterRecurse is the .. step: the input and every value inside it, each
+// before the values inside it.
+func filterRecurse(v interface{}) ([]interface{}, error) {
+	outs := []interface{}{v}
+	children, err := filterIterate(v)
+	if err != nil {
+		return outs, nil
+	}
+	for _, child := range children {
+		nested, _ := filterRecurse(child)
+		outs = append(outs, nested...)
+	}
+	return outs, nil
+}
+
+func filterPipe(left, right filter) filter {
+	return func(v interface{}) ([]interface{}, error) {
+		ins, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		var outs []interface{}
+		for _, in := range ins {
+			results, err := right(in)
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, results...)
+		}
+		return outs, nil
+	}
+}
+
+// filterTry drops the error of f, and with it its outputs.
+func filterTry(f filter) filter {
+	return func(v interface{}) ([]interface{}, error) {
+		outs, err := f(v)
+		if err != nil {
+			return nil, nil
+		}
+		return outs, nil
+	}
+}
+
+// filterIndex looks key up in v: a string in an object, or a number in an
+// array, counted from the end when negative. Missing keys, indices out of
+// range and anything looked up in null give null.
+func filterIndex(v, key interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if k, ok := key.(string); ok {
+			if IsMetaKey(k) {
+				return nil, nil
+			}
+			return v[k], nil
+		}
+	case []interface{}:
+		if f, ok := ToFloat(key); ok && !isText(key) {
+			i := int(math.Floor(f))
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return nil, nil
+			}
+			return v[i], nil
+		}
+	}
+	if k, ok := key.(string); ok {
+		return nil, fmt.Errorf("cannot index %s with \"%s\"", filterDescribe(v), k)
+	}
+	return nil, fmt.Errorf("cannot index %s with %s", filterDescribe(v), filterDescribe(key))
+}
+
+func filterBinary(op string, left, right filter) filter {
+	return func(v interface{}) ([]interface{}, error) {
+		as, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "and", "or":
+			var outs []interface{}
+			for _, a := range as {
+				if op == "and" && !filterTruthy(a) || op == "or" && filterTruthy(a) {
+					outs = append(outs, op == "or")
+					continue
+				}
+				bs, err := right(v)
+				if err != nil {
+					return nil, err
+				}
+				for _, b := range bs {
+					outs = append(outs, filterTruthy(b))
+				}
+			}
+			return outs, nil
+		case "//":
+			var outs []interface{}
+			for _, a := range as {
+				if filterTruthy(a) {
+					outs = append(outs, a)
+				}
+			}
+			if len(outs) > 0 {
+				return outs, nil
+			}
+			return right(v)
+		}
+		bs, err := right(v)
+		if err != nil {
+			return nil, err
+		}
+		var outs []interface{}
+		for _, b := range bs {
+			for _, a := range as {
+				out, err := filterOperate(op, a, b)
+				if err != nil {
+					return nil, err
+				}
+				outs = append(outs, out)
+			}
+		}
+		return outs, nil
+	}
+}
+
+func filterOperate(op string, a, b interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return filterCompare(a, b) == 0, nil
+	case "!=":
+		return filterCompare(a, b) != 0, nil
+	case "<":
+		return filterCompare(a, b) < 0, nil
+	case "<=":
+		return filterCompare(a, b) <= 0, nil
+	case ">":
+		return filterCompare(a, b) > 0, nil
+	case ">=":
+		return filterCompare(a, b) >= 0, nil
+	}
+	return filterArithmetic(op, a, b)
+}
+
+// filterArithmetic applies + - * / or % as jq does: + adds numbers, joins
+// strings and arrays and merges objects, and null is the identity of +.
+func filterArithmetic(op string, a, b interface{}) (interface{}, error) {
+	if op == "+" {
+		switch {
+		case a == nil:
+			return b, nil
+		case b == nil:
+			return a, nil
+		}
+	}
+	x, aNum := ToFloat(a)
+	y, bNum := ToFloat(b)
+	if aNum && bNum && !isText(a) && !isText(b) {
+		switch op {
+		case "+":
+			return Number(x + y), nil
+		case "-":
+			return Number(x - y), nil
+		case "*":
+			return Number(x * y), nil
+		case "/":
+			if y == 0 {
+				return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", filterDescribe(a), filterDescribe(b))
+			}
+			return Number(x / y), nil
+		case "%":
+			if int64(y) == 0 {
+				return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", filterDescribe(a), filterDescribe(b))
+			}
+			return Number(float64(int64(x) % int64(y))), nil
+		}
+	}
+	switch a := a.(type) {
+	case string:
+		if s, ok := b.(string); ok && op == "+" {
+			return a + s, nil
+		}
+	case []interface{}:
+		if list, ok := b.([]interface{}); ok {
+			switch op {
+			case "+":
+				return append(slices.Clone(a), list...), nil
+			case "-":
+				return slices.DeleteFunc(slices.Clone(a), func(item interface{}) bool {
+					return slices.ContainsFunc(list, func(other interface{}) bool { return filterCompare(item, other) == 0 })
+				}), nil
+			}
+		}
+	case map[string]interface{}:
+		if m, ok := b.(map[string]interface{}); ok && op == "+" {
+			merged := make(map[string]interface{}, len(a)+len(m))
+			order := OrderedKeys(a)
+			for _, k := range order {
+				merged[k] = a[k]
+			}
+			for _, k := range OrderedKeys(m) {
+				if _, exists := merged[k]; !exists {
+					order = append(order, k)
+				}
+				merged[k] = m[k]
+			}
+			merged[OrderKey] = order
+			return merged, nil
+		}
+	}
+	verbs := map[string]string{"+": "added", "-": "subtracted", "*": "multiplied", "/": "divided", "%": "divided"}
+	return nil, fmt.Errorf("%s and %s cannot be %s", filterDescribe(a), filterDescribe(b), verbs[op])
+}
+
+// filterTypeOrder orders values of different types as jq does.
+var filterTypeOrder = map[string]int{"null": 0, "boolean": 1, "number": 3, "string": 4, "array": 5, "object": 6}
+
+// filterCompare orders two values: by type first, then numbers by value,
+// strings by their text, arrays element by element and objects by their
+// keys, then their values.
+func filterCompare(a, b interface{}) int {
+	ta, tb := filterType(a), filterType(b)
+	oa, ob := filterTypeOrder[ta], filterTypeOrder[tb]
+	if ta == "boolean" && a == true {
+		oa = 2
+	}
+	if tb == "boolean" && b == true {
+		ob = 2
+	}
+	if oa != ob {
+		return oa - ob
+	}
+	switch ta {
+	case "number":
+		x, _ := ToFloat(a)
+		y, _ := ToFloat(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case "string":
+		return strings.Compare(filterText(a), filterText(b))
+	case "array":
+		x, y := a.([]interface{}), b.([]interface{})
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := filterCompare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return len(x) - len(y)
+	case "object":
+		x, y := a.(map[string]interface{}), b.(map[string]interface{})
+		kx, _ := filterKeys(x, true)
+		ky, _ := filterKeys(y, true)
+		if c := filterCompare(kx, ky); c != 0 {
+			return c
+		}
+		for _, k := range kx.([]interface{}) {
+			if c := filterCompare(x[k.(string)], y[k.(string)]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// filterType returns the jq type of v; timestamps and binary values are
+// strings.
+func filterType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64, int, int64, uint64:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return "string"
+}
+
+// isText reports whether v is a string, which ToFloat would read as a
+// number.
+func isText(v interface{}) bool {
+	return filterType(v) == "string"
+}
+
+func filterText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ScalarString(v)
+}
+
+// filterTruthy follows jq: only false and null are false.
+func filterTruthy(v interface{}) bool {
+	return v != nil && v != false
+}
+
+func filterDescribe(v interface{}) string {
+	t := filterType(v)
+	switch t {
+	case "null":
+		return "null"
+	case "object", "array":
+		return t
+	}
+	return fmt.Sprintf("%s (%s)", t, TruncateWidth(filterJSON(v), 20))
+}
+
+// filterJSON writes v as compact JSON, keys in their order.
+func filterJSON(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		parts := []string{}
+		for _, k := range OrderedKeys(v) {
+			parts = append(parts, strconv.Quote(k)+":"+filterJSON(v[k]))
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = filterJSON(item)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	case string:
+		b, _ := json.Marshal(v)
+		return string(b)
+	case time.Time, []byte:
+		b, _ := json.Marshal(ScalarString(v))
+		return string(b)
+	case nil:
+		return "null"
+	}
+	return ScalarString(v)
+}
+
+func filterKeys(v interface{}, sorted bool) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := OrderedKeys(v)
+		if sorted {
+			keys = slices.Clone(keys)
+			sort.Strings(keys)
+		}
+		out := make([]interface{}, len(keys))
+		for i, k := range keys {
+			out[i] = k
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = Number(float64(i))
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s has no keys", filterDescribe(v))
+}
+
+// filterContains follows jq: strings contain substrings, arrays contain
+// arrays whose every element is contained in one of theirs, and objects
+// contain objects whose values they contain under the same keys.
+func filterContains(a, b interface{}) bool {
+	switch a := a.(type) {
+	case string:
+		return strings.Contains(a, b.(string))
+	case []interface{}:
+		for _, item := range b.([]interface{}) {
+			if !slices.ContainsFunc(a, func(x interface{}) bool {
+				return filterType(x) == filterType(item) && filterContains(x, item)
+			}) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		for _, k := range OrderedKeys(b.(map[string]interface{})) {
+			value, ok := a[k]
+			other := b.(map[string]interface{})[k]
+			if !ok || filterType(value) != filterType(other) || !filterContains(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return filterCompare(a, b) == 0
+}
+
+// filterSortKeys evaluates by on each element of v, or uses the element
+// itself when by is nil.
+func filterSortKeys(v interface{}, by filter) ([]interface{}, []interface{}, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("%s cannot be sorted, as it is not an array", filterDescribe(v))
+	}
+	keys := make([]interface{}, len(items))
+	for i, item := range items {
+		if by == nil {
+			keys[i] = item
+			continue
+		}
+		outs, err := by(item)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i] = outs
+	}
+	return items, keys, nil
+}
+
+func filterSortBy(v interface{}, by filter) (interface{}, error) {
+	items, keys, err := filterSortKeys(v, by)
+	if err != nil {
+		return nil, err
+	}
+	index := make([]int, len(items))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool { return filterCompare(keys[index[i]], keys[index[j]]) < 0 })
+	sorted := make([]interface{}, len(items))
+	for i, k := range index {
+		sorted[i] = items[k]
+	}
+	return sorted, nil
+}
+
+// filterGroupBy groups the elements of v by the key by gives them, in the
+// order of the keys; with unique, only the first of each group is kept.
+func filterGroupBy(v interface{}, by filter, unique bool) (interface{}, error) {
+	items, keys, err := filterSortKeys(v, by)
+	if err != nil {
+		return nil, err
+	}
+	index := make([]int, len(items))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool { return filterCompare(keys[index[i]], keys[index[j]]) < 0 })
+	groups := []interface{}{}
+	for n, i := range index {
+		if n > 0 && filterCompare(keys[index[n-1]], keys[i]) == 0 {
+			if !unique {
+				last := len(groups) - 1
+				groups[last] = append(groups[last].([]interface{}), items[i])
+			}
+			continue
+		}
+		if unique {
+			groups = append(groups, items[i])
+		} else {
+			groups = append(groups, []interface{}{items[i]})
+		}
+	}
+	return groups, nil
+}
+
+// filterExtreme returns the least (sign -1) or greatest (sign 1) element of
+// an array, or null for an empty one.
+func filterExtreme(v interface{}, sign int) (interface{}, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s has no minimum or maximum, as it is not an array", filterDescribe(v))
+	}
+	var best interface{}
+	for i, item := range items {
+		if i == 0 || filterCompare(item, best)*sign > 0 {
+			best = item
+		}
+	}
+	return best, nil
+}
+
+func filterToEntries(v interface{}) (interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s has no entries, as it is not an object", filterDescribe(v))
+	}
+	entries := []interface{}{}
+	for _, k := range OrderedKeys(m) {
+		entries = append(entries, map[string]interface{}{"key": k, "value": m[k], OrderKey: []string{"key", "value"}})
+	}
+	return entries, nil
+}
+
+func filterFromEntries(v interface{}) (interface{}, error) {
+	entries, ok := v.([]interface{})
+	if !ok && v != nil {
+		return nil, fmt.Errorf("cannot make an object of %s, as it is not an array", filterDescribe(v))
+	}
+	result := map[string]interface{}{}
+	var order []string
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot make an object of %s, as it is not an entry", filterDescribe(e))
+		}
+		var key interface{}
+		for _, name := range []string{"key", "k", "name", "Name", "Key", "K"} {
+			if k, ok := entry[name]; ok && k != nil {
+				key = k
+				break
+			}
+		}
+		if key == nil {
+			return nil, fmt.Errorf("entry %s has no key", filterJSON(entry))
+		}
+		k := filterText(key)
+		if _, exists := result[k]; !exists {
+			order = append(order, k)
+		}
+		value, ok := entry["value"]
+		if !ok {
+			value = entry["v"]
+		}
+		result[k] = value
+	}
+	result[OrderKey] = order
+	return result, nil
+}
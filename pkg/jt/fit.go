@@ -0,0 +1,78 @@
+package jt
+
+import (
+	"math"
+	"strings"
+
+	"github.com/olekukonko/tablewriter/pkg/twwarp"
+)
+
+// minFitWidth is the narrowest Fit lets a value column become; below that
+// wrapped values turn into a column of fragments.
+const minFitWidth = 8
+
+// Fit returns opts with the widest value width at which data renders within
+// width terminal cells, wrapping values that are longer. Only the widest
+// columns shrink, so short ones keep their natural size. When the table
+// cannot fit even at minFitWidth (e.g. too many columns), it is left at that
+// width.
+func Fit(data interface{}, opts Options, isMultiDoc bool, width int) Options {
+	fits := func(opts Options) bool {
+		output, err := RenderDocuments(data, opts, isMultiDoc)
+		return err == nil && ContentWidth(output) <= width
+	}
+	opts.Wrap = true
+	if fits(opts) {
+		return opts
+	}
+
+	lo, hi := minFitWidth, opts.MaxWidth-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		opts.MaxWidth = mid
+		if fits(opts) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	opts.MaxWidth = lo
+	return opts
+}
+
+// wrapValue is truncateValue for Options.Wrap: long values are wrapped onto several
+// lines of at most maxWidth cells rather than cut off.
+func wrapValue(s string, maxWidth int, multiline string) string {
+	s = truncateValue(s, math.MaxInt, multiline)
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if DisplayWidth(line) <= maxWidth {
+			lines = append(lines, line)
+			continue
+		}
+		wrapped, _ := twwarp.WrapString(line, maxWidth)
+		for _, w := range wrapped {
+			lines = append(lines, breakWidth(w, maxWidth)...)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// breakWidth splits s into pieces of at most width cells, for words too long
+// to wrap at a space.
+func breakWidth(s string, width int) []string {
+	var pieces []string
+	var piece strings.Builder
+	used := 0
+	for _, r := range s {
+		w := DisplayWidth(string(r))
+		if used+w > width && used > 0 {
+			pieces = append(pieces, piece.String())
+			piece.Reset()
+			used = 0
+		}
+		piece.WriteRune(r)
+		used += w
+	}
+	return append(pieces, piece.String())
+}
@@ -0,0 +1,71 @@
+package jt
+
+import (
+	"strings"
+	"unicode"
+)
+
+// HeaderCases are the styles accepted by Options.HeaderCase.
+var HeaderCases = []string{"none", "upper", "title", "snake"}
+
+// applyHeaderCase restyles a column header. jt's own bracketed headers,
+// such as [key], are left as they are.
+func applyHeaderCase(header, mode string) string {
+	if mode == "none" || mode == "" || (strings.HasPrefix(header, "[") && strings.HasSuffix(header, "]")) {
+		return header
+	}
+	switch mode {
+	case "upper":
+		return strings.ToUpper(header)
+	case "title":
+		words := headerWords(header)
+		for i, w := range words {
+			r := []rune(strings.ToLower(w))
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		}
+		return strings.Join(words, " ")
+	case "snake":
+		words := headerWords(header)
+		for i, w := range words {
+			words[i] = strings.ToLower(w)
+		}
+		return strings.Join(words, "_")
+	}
+	return header
+}
+
+// headerWords splits a key into words at separators (space, _, -, .) and
+// camelCase boundaries: "metadata.creationTimestamp" is metadata, creation,
+// Timestamp.
+func headerWords(s string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(s)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case r == ' ' || r == '_' || r == '-' || r == '.':
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(current) > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// Break before the start of a word: fooBar, and HTTPServer
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	if len(words) == 0 {
+		return []string{s}
+	}
+	return words
+}
@@ -0,0 +1,149 @@
+package jt
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// HumanizeKinds are the kinds of numbers Options.Humanize can show in a
+// readable form.
+var HumanizeKinds = []string{"bytes", "duration"}
+
+// byteUnits are the binary prefixes bytes are shown with.
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// durationUnits are the suffixes of column names that give the unit of a
+// duration, after _, - or in camel case as in elapsedMs.
+var durationUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"ns", time.Nanosecond},
+	{"nanos", time.Nanosecond},
+	{"us", time.Microsecond},
+	{"micros", time.Microsecond},
+	{"ms", time.Millisecond},
+	{"millis", time.Millisecond},
+	{"s", time.Second},
+	{"sec", time.Second},
+	{"secs", time.Second},
+	{"seconds", time.Second},
+}
+
+// humanizeKind returns the kind of number the values of a column are shown
+// as, or "" to show them as they are.
+func humanizeKind(key string, opts Options) string {
+	if kind, ok := opts.Humanize[key]; ok {
+		return kind
+	}
+	if !opts.HumanizeAuto {
+		return ""
+	}
+	lower := strings.ToLower(key)
+	switch {
+	case lower == "size" || lower == "bytes" || nameSuffix(key, "bytes") || nameSuffix(key, "size"):
+		return "bytes"
+	}
+	if _, ok := durationUnit(key); ok {
+		return "duration"
+	}
+	return ""
+}
+
+// humanizedValue formats the value of a column as its humanize kind, or
+// returns false when it is not a number or the column is shown as is.
+func humanizedValue(key string, val interface{}, opts Options) (string, bool) {
+	kind := humanizeKind(key, opts)
+	if kind == "" {
+		return "", false
+	}
+	n, ok := numberValue(val)
+	if !ok {
+		return "", false
+	}
+	switch kind {
+	case "bytes":
+		return humanBytes(n), true
+	case "duration":
+		unit, ok := durationUnit(key)
+		if !ok {
+			unit = time.Second
+		}
+		return humanDuration(n, unit), true
+	}
+	return "", false
+}
+
+// cellValue formats the value of a column, humanized if it asks to be.
+func cellValue(key string, val interface{}, opts Options) string {
+	if value, ok := humanizedValue(key, val, opts); ok {
+		return value
+	}
+	return formatValue(val, opts)
+}
+
+// humanBytes formats a number of bytes with the largest binary unit it has
+// at least one of, with one decimal: 1.4 GiB.
+func humanBytes(n float64) string {
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	unit := 0
+	for n >= 1024 && unit < len(byteUnits)-1 {
+		n /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%s%g %s", sign, n, byteUnits[0])
+	}
+	text := strings.TrimSuffix(fmt.Sprintf("%.1f", n), ".0")
+	return sign + text + " " + byteUnits[unit]
+}
+
+// humanDuration formats n units of time as Go writes durations, 2m13s,
+// rounded to the second from a minute up and to the millisecond from a
+// second up.
+func humanDuration(n float64, unit time.Duration) string {
+	f := n * float64(unit)
+	if math.Abs(f) > math.MaxInt64 {
+		return fmt.Sprintf("%gs", n*unit.Seconds())
+	}
+	d := time.Duration(f)
+	switch abs := d.Abs(); {
+	case abs >= time.Minute:
+		d = d.Round(time.Second)
+	case abs >= time.Second:
+		d = d.Round(time.Millisecond)
+	}
+	return d.String()
+}
+
+// durationUnit returns the unit a column name ends in, such as elapsed_ms.
+func durationUnit(key string) (time.Duration, bool) {
+	for _, u := range durationUnits {
+		if nameSuffix(key, u.suffix) {
+			return u.unit, true
+		}
+	}
+	return 0, false
+}
+
+// nameSuffix reports whether key ends in the word suffix: after _ or -, or
+// capitalized after a lowercase letter.
+func nameSuffix(key, suffix string) bool {
+	lower := strings.ToLower(key)
+	if !strings.HasSuffix(lower, suffix) || len(key) == len(suffix) {
+		return false
+	}
+	i := len(key) - len(suffix)
+	switch {
+	case key[i-1] == '_' || key[i-1] == '-':
+		return true
+	case key[i] >= 'A' && key[i] <= 'Z' && key[i-1] >= 'a' && key[i-1] <= 'z':
+		return key[i+1:] == strings.ToLower(key[i+1:])
+	}
+	return false
+}
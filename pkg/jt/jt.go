@@ -0,0 +1,64 @@
+// Package jt renders JSON, YAML and XML documents as nested tables, as the jt
+// command does.
+//
+// Parse decodes a document, Select picks part of it with a selector such as
+// .items[0].metadata, and RenderTable, RenderHTML and RenderMarkdown turn the
+// result into a terminal table, an HTML table or a Markdown table:
+//
+//	data, _, err := jt.Parse(input, jt.ParseOptions{Filename: "pods.json"})
+//	if err != nil {
+//		return err
+//	}
+//	items, err := jt.Select(data, ".items")
+//	if err != nil {
+//		return err
+//	}
+//	table, err := jt.RenderTable(items, jt.DefaultOptions())
+//
+// Styles and color rules are package-wide: ApplyTheme and SetColorRules
+// affect every table rendered afterwards.
+package jt
+
+import "fmt"
+
+// RenderTable renders data as a terminal table, drawn with box characters
+// and, with opts.Color, styled with ANSI colors.
+func RenderTable(data interface{}, opts Options) (string, error) {
+	opts.Format = "table"
+	return RenderDocuments(data, opts, false)
+}
+
+// RenderHTML renders data as HTML tables. The classes they use are styled by
+// StyleSheet, which is not included.
+func RenderHTML(data interface{}, opts Options) (string, error) {
+	opts.Format = "html"
+	return RenderDocuments(data, opts, false)
+}
+
+// RenderMarkdown renders data as a Markdown table. Markdown has no nested
+// tables, so nested objects and arrays are summarized, e.g. {3 keys}.
+func RenderMarkdown(data interface{}, opts Options) (string, error) {
+	opts.Format = "markdown"
+	return RenderDocuments(data, opts, false)
+}
+
+// RenderDocuments renders data in opts.Format. When isMultiDoc is set, data
+// is an array of documents as returned by Parse, and each is rendered as a
+// table of its own.
+func RenderDocuments(data interface{}, opts Options, isMultiDoc bool) (output string, err error) {
+	switch opts.Format {
+	case "table", "html", "markdown":
+	default:
+		return "", fmt.Errorf("unknown format '%s' (expected table/html/markdown)", opts.Format)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(renderError)
+			if !ok {
+				panic(r)
+			}
+			err = e.err
+		}
+	}()
+	return renderDocuments(data, opts, isMultiDoc), nil
+}
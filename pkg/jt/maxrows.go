@@ -0,0 +1,51 @@
+package jt
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DefaultMaxRows caps the rows of each table the jt command writes to a
+// terminal unless -max-rows says otherwise, so an accidental render of a
+// huge array does not flood it. Other output has all rows.
+const DefaultMaxRows = 1000
+
+// capRows returns at most maxRows of n rows (all for maxRows <= 0) and how
+// many were left out.
+func capRows(n, maxRows int) (int, int) {
+	if maxRows <= 0 || n <= maxRows {
+		return n, 0
+	}
+	return maxRows, n - maxRows
+}
+
+// omittedNote is the line below a table saying how many of its rows were
+// left out, or "" if none were.
+func omittedNote(omitted int, useColor bool, format string) string {
+	if omitted == 0 {
+		return ""
+	}
+	note := fmt.Sprintf("… and %s more rows", formatCount(omitted))
+	if omitted == 1 {
+		note = "… and 1 more row"
+	}
+	switch {
+	case useColor:
+		return nullStyle.Render(note) + "\n"
+	case format == "html":
+		return `<p class="jt-footer">` + note + "</p>\n"
+	case format == "markdown":
+		// without the blank line, the note would be read as a table row
+		return "\n" + note + "\n"
+	}
+	return note + "\n"
+}
+
+// formatCount formats n with thousands separators, e.g. 4,321.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
@@ -0,0 +1,264 @@
+package jt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseJSON decodes a single JSON value, keeping numbers as json.Number so
+// 64-bit IDs and decimals are not rounded through float64.
+func parseJSON(input []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		offset := decoder.InputOffset()
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			offset = syntaxErr.Offset - 1
+		} else if err == io.ErrUnexpectedEOF {
+			offset = int64(len(input))
+		}
+		return nil, newParseError("JSON", input, offset, err.Error())
+	}
+	offset := decoder.InputOffset()
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, newParseError("JSON", input, offset+int64(countLeadingSpace(input[offset:])), "unexpected data after top-level value")
+	}
+	return data, nil
+}
+
+// parseNDJSON decodes newline-delimited JSON, as written by jq -c and log
+// pipelines: one value per line, returned as an array of them.
+func parseNDJSON(input []byte) ([]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+
+	rows := []interface{}{}
+	for {
+		var row interface{}
+		if err := decoder.Decode(&row); err != nil {
+			if err == io.EOF {
+				return rows, nil
+			}
+			offset := decoder.InputOffset()
+			if syntaxErr, ok := err.(*json.SyntaxError); ok {
+				offset = syntaxErr.Offset - 1
+			} else if err == io.ErrUnexpectedEOF {
+				offset = int64(len(input))
+			}
+			return nil, newParseError("NDJSON", input, offset, err.Error())
+		}
+		rows = append(rows, row)
+	}
+}
+
+// isNDJSON reports whether input looks like newline-delimited JSON: its
+// first line is a whole JSON value and more follows it. The first line of
+// indented JSON is only an opening bracket.
+func isNDJSON(input []byte) bool {
+	first, rest, found := bytes.Cut(bytes.TrimSpace(input), []byte("\n"))
+	return found && json.Valid(first) && len(bytes.TrimSpace(rest)) > 0
+}
+
+// ParseOptions controls how input documents are decoded.
+type ParseOptions struct {
+	Filename      string // used to detect the format from the extension
+	Format        string // json, ndjson, yaml, toml, xml or csv; detected when empty
+	XMLRaw        bool   // keep CDATA sections and entity references as written
+	YAMLKeepMerge bool   // keep YAML merge keys (<<) instead of resolving them
+	Strict        bool   // treat duplicate keys as errors
+	MaxDepth      int    // 0 for DefaultMaxDepth
+	// OnDuplicate is called for each duplicate key when not Strict
+	OnDuplicate func(key string, line int)
+}
+
+// DuplicateKeyError reports a key that appears twice in one object, when
+// parsing with Strict.
+type DuplicateKeyError struct {
+	Key  string
+	Line int
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key '%s' on line %d", e.Key, e.Line)
+}
+
+// DetectFormat picks the format input is meant to be in: by file extension
+// when there is one, otherwise by the first non-space character. Only that
+// format is tried, so a malformed JSON file is reported as such instead of
+// being misread as a YAML or XML document that happens to parse. The one
+// exception is input starting with '{' or '[' that is not JSON but is YAML
+// in flow style, such as {a: 1, b: [x, y]}.
+func DetectFormat(input []byte, filename string) string {
+	format, _ := ExplainFormat(input, filename)
+	return format
+}
+
+// ExplainFormat returns the format DetectFormat picks and the reason.
+func ExplainFormat(input []byte, filename string) (string, string) {
+	if format := ExtensionFormat(filename); format != "" {
+		return format, "file extension " + strings.ToLower(filepath.Ext(filename))
+	}
+
+	trimmed := bytes.TrimSpace(input)
+	if len(trimmed) == 0 {
+		return "yaml", "input is empty"
+	}
+	if isTOML(trimmed) {
+		return "toml", "first line is a TOML [table] header or key = value, and a key = value follows"
+	}
+	switch trimmed[0] {
+	case '{', '[':
+		if isNDJSON(trimmed) {
+			return "ndjson", "first line is a whole JSON value and more lines follow"
+		}
+		if !json.Valid(trimmed) && isYAML(trimmed) {
+			return "yaml", fmt.Sprintf("first non-space character is '%c', but it is YAML in flow style, not JSON", trimmed[0])
+		}
+		return "json", fmt.Sprintf("first non-space character is '%c'", trimmed[0])
+	case '<':
+		return "xml", "first non-space character is '<'"
+	}
+	return "yaml", "first non-space character is not '{', '[' or '<'"
+}
+
+// isYAML reports whether input decodes as YAML documents.
+func isYAML(input []byte) bool {
+	decoder := yaml.NewDecoder(bytes.NewReader(input))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			return err == io.EOF
+		}
+	}
+}
+
+// ExtensionFormat returns the format files named like filename are in, or
+// "" if the extension is not one jt knows.
+func ExtensionFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return "json"
+	case ".jsonl", ".ndjson":
+		return "ndjson"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	case ".csv":
+		return "csv"
+	case ".xml", ".pom", ".svg", ".xsd", ".wsdl", ".plist", ".csproj":
+		return "xml"
+	}
+	return ""
+}
+
+// Parse decodes input as JSON, NDJSON, YAML, TOML, XML or CSV: opts.Format,
+// or whichever DetectFormat picks. NDJSON is decoded as an array of its
+// lines, and CSV as an array of objects keyed by its header row.
+// Objects are map[string]interface{} with their keys in source order (see
+// OrderedKeys), arrays []interface{}, and numbers json.Number. The second
+// result reports whether the input held several YAML documents, in which
+// case the data is an array of them.
+//
+// Malformed input is reported as a *ParseError.
+func Parse(input []byte, opts ParseOptions) (interface{}, bool, error) {
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	format := opts.Format
+	if format == "" {
+		format = DetectFormat(input, opts.Filename)
+	}
+	switch format {
+	case "json":
+		data, err := parseJSON(input)
+		if err != nil {
+			return nil, false, err
+		}
+		if exceedsDepth(data, opts.MaxDepth) {
+			return nil, false, depthError("JSON", input, opts.MaxDepth)
+		}
+		if err := reportDuplicates(findJSONDuplicates(input), opts); err != nil {
+			return nil, false, err
+		}
+		return data, false, nil
+	case "ndjson":
+		rows, err := parseNDJSON(input)
+		if err != nil {
+			return nil, false, err
+		}
+		if exceedsDepth(rows, opts.MaxDepth+1) {
+			return nil, false, depthError("NDJSON", input, opts.MaxDepth)
+		}
+		if err := reportDuplicates(findJSONDuplicates(input), opts); err != nil {
+			return nil, false, err
+		}
+		return rows, false, nil
+	case "toml":
+		data, err := parseTOML(input)
+		if err != nil {
+			return nil, false, err
+		}
+		if exceedsDepth(data, opts.MaxDepth) {
+			return nil, false, depthError("TOML", input, opts.MaxDepth)
+		}
+		return data, false, nil
+	case "csv":
+		rows, duplicates, err := parseCSV(input)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := reportDuplicates(duplicates, opts); err != nil {
+			return nil, false, err
+		}
+		return rows, false, nil
+	case "xml":
+		data, err := parseXML(input, opts)
+		if err != nil {
+			return nil, false, err
+		}
+		return data, false, nil
+	case "yaml":
+	default:
+		return nil, false, fmt.Errorf("unknown input format '%s' (expected json/ndjson/yaml/toml/xml/csv)", format)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(input))
+	// The document node itself counts as one level
+	converter := &yamlConverter{keepMerge: opts.YAMLKeepMerge, maxDepth: opts.MaxDepth + 1}
+	var documents []interface{}
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, false, yamlParseError(input, err)
+		}
+		documents = append(documents, converter.value(&doc))
+		if converter.tooDeep {
+			return nil, false, depthError("YAML", input, opts.MaxDepth)
+		}
+	}
+	if err := reportDuplicates(converter.duplicates, opts); err != nil {
+		return nil, false, err
+	}
+
+	if len(documents) == 0 {
+		return map[string]interface{}{}, false, nil
+	}
+
+	if len(documents) == 1 {
+		return documents[0], false, nil
+	}
+
+	return documents, true, nil
+}
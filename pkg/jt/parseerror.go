@@ -0,0 +1,68 @@
+package jt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseError is a decoding failure located in the source document.
+type ParseError struct {
+	Format string // JSON, YAML or XML
+	Msg    string
+	Input  []byte
+	Line   int // 1-based, 0 when unknown
+	Column int // 1-based, 0 when unknown
+}
+
+func (e *ParseError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("invalid %s at line %d, column %d: %s", e.Format, e.Line, e.Column, e.Msg)
+	case e.Line > 0:
+		return fmt.Sprintf("invalid %s at line %d: %s", e.Format, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("invalid %s: %s", e.Format, e.Msg)
+}
+
+// newParseError builds a ParseError for the byte offset into input.
+func newParseError(format string, input []byte, offset int64, msg string) *ParseError {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > int64(len(input)) {
+		offset = int64(len(input))
+	}
+	before := input[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	column := utf8.RuneCount(before[lineStart:]) + 1
+	return &ParseError{Format: format, Msg: msg, Input: input, Line: line, Column: column}
+}
+
+var yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+): (?:column (\d+): )?`)
+
+func yamlParseError(input []byte, err error) *ParseError {
+	e := &ParseError{Format: "YAML", Msg: strings.TrimPrefix(err.Error(), "yaml: "), Input: input}
+	if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+		e.Column, _ = strconv.Atoi(m[2])
+		e.Msg = strings.TrimPrefix(err.Error(), m[0])
+	}
+	return e
+}
+
+func xmlParseError(input []byte, err error) *ParseError {
+	if syntaxErr, ok := err.(*xml.SyntaxError); ok {
+		return &ParseError{Format: "XML", Msg: syntaxErr.Msg, Input: input, Line: syntaxErr.Line}
+	}
+	return &ParseError{Format: "XML", Msg: err.Error(), Input: input}
+}
+
+func countLeadingSpace(b []byte) int {
+	return len(b) - len(bytes.TrimLeft(b, " \t\r\n"))
+}
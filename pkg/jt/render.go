@@ -0,0 +1,595 @@
+package jt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/olekukonko/tablewriter/tw"
+)
+
+// DefaultMaxWidth is the widest a value is shown, in terminal cells, unless
+// Options.MaxWidth says otherwise.
+const DefaultMaxWidth = 80
+
+// NullToken is displayed for explicit null values, so they can be told apart
+// from empty strings and missing fields (which render as empty cells).
+const NullToken = "null"
+
+// Options controls how parsed data is turned into tables.
+type Options struct {
+	Format     string // table, html or markdown
+	Details    bool   // caption each table with its type and size
+	MaxWidth   int    // widest a value is shown, in terminal cells
+	Wrap       bool   // wrap values longer than MaxWidth instead of truncating
+	Multiline  string // collapse, keep or marker
+	Base64     string // summary, decode or raw
+	Color      bool   // style table output with ANSI colors
+	MaxDepth   int    // nesting levels rendered before values are summarized
+	Levels     int    // levels of tables shown, 0 for all
+	Columns    []string
+	Exclude    map[string]bool // keys left out of tables at any level
+	Transpose  bool
+	HeaderCase string // none, upper, title or snake
+	NoHeader   bool   // leave out the header row of the top-level table
+	NoIndex    bool   // leave out the index column of the top-level table
+	MaxRows    int    // rows per table, 0 for all
+	// Bars is the width of the bars drawn next to the numbers of numeric
+	// columns, and turns arrays of numbers into sparklines; 0 for neither
+	Bars int
+	// Humanize shows the numbers of the named columns as bytes or
+	// durations (see HumanizeKinds); HumanizeAuto guesses the kind from
+	// column names such as size or elapsed_ms
+	Humanize     map[string]string
+	HumanizeAuto bool
+	// TruncationInfo follows truncated values with their length and the
+	// start of their SHA-256, so they can be compared between runs
+	TruncationInfo bool
+	// Caption titles the top-level table; {doc_index} and {doc_count} are
+	// replaced for each document of multi-document input
+	Caption string
+	Footer  string // note printed below the output, e.g. on omitted rows
+	// index of the first row of the top-level array, when it is a window
+	FirstIndex int
+
+	depth int // nesting level of the table being rendered
+	// path of the value being rendered, and the paths of the values marked
+	// so far, while rendering for RenderCells
+	path  string
+	cells *[]string
+}
+
+// DefaultOptions returns the options the jt command renders with when no
+// flags are given.
+func DefaultOptions() Options {
+	return Options{
+		Format:     "table",
+		MaxWidth:   DefaultMaxWidth,
+		Multiline:  "collapse",
+		Base64:     "summary",
+		MaxDepth:   DefaultMaxDepth,
+		HeaderCase: "none",
+	}
+}
+
+// renderError carries a failure out of the recursive rendering functions to
+// RenderDocuments.
+type renderError struct{ err error }
+
+// renderDocuments renders data as one table, or one table per document for
+// multi-document input.
+func renderDocuments(data interface{}, opts Options, isMultiDoc bool) string {
+	var output string
+	docs, isSlice := data.([]interface{})
+	if isMultiDoc && isSlice {
+		var outputs []string
+		caption := opts.Caption
+		for i, doc := range docs {
+			opts.Caption = expandDocCaption(caption, i, len(docs))
+			outputs = append(outputs, renderRecursive(doc, opts.atIndex(i)))
+		}
+		output = strings.Join(outputs, "\n")
+	} else {
+		opts.Caption = expandDocCaption(opts.Caption, 0, 1)
+		output = renderRecursive(data, opts)
+	}
+
+	if opts.Footer != "" {
+		if opts.Format == "html" {
+			output += fmt.Sprintf("<p class=\"jt-footer\">%s</p>\n", escapeHTML(opts.Footer))
+		} else {
+			output += opts.Footer + "\n"
+		}
+	}
+	return output
+}
+
+// expandDocCaption fills in {doc_index} (counting from 0) and {doc_count}
+// for one document of the input.
+func expandDocCaption(caption string, index, count int) string {
+	return strings.NewReplacer("{doc_index}", strconv.Itoa(index), "{doc_count}", strconv.Itoa(count)).Replace(caption)
+}
+
+func renderRecursive(data interface{}, opts Options) string {
+	var buf bytes.Buffer
+	table := createTable(&buf, opts.Format)
+
+	omitted := appendData(table, data, opts)
+	if opts.Caption != "" && opts.depth == 0 {
+		table.Caption(tw.Caption{Text: opts.Caption})
+	}
+	if err := table.Render(); err != nil {
+		panic(renderError{fmt.Errorf("rendering table: %w", err)})
+	}
+	buf.WriteString(omittedNote(omitted, opts.Format == "table" && opts.Color, opts.Format))
+
+	return buf.String()
+}
+
+func createTable(buf *bytes.Buffer, format string) *tablewriter.Table {
+	switch format {
+	case "html":
+		cfg := renderer.HTMLConfig{
+			HeaderClass:   "jt-header",
+			TableClass:    "jt-table",
+			EscapeContent: false,
+		}
+		return tablewriter.NewTable(buf, tablewriter.WithRenderer(renderer.NewHTML(cfg)))
+	case "markdown":
+		return tablewriter.NewTable(buf,
+			tablewriter.WithRenderer(renderer.NewMarkdown()),
+			tablewriter.WithHeaderAlignment(tw.AlignLeft),
+			tablewriter.WithRowAlignment(tw.AlignLeft),
+			tablewriter.WithTrimSpace(tw.Off),
+		)
+	default: // table
+		return tablewriter.NewTable(buf,
+			tablewriter.WithHeaderAlignment(tw.AlignLeft),
+			tablewriter.WithRowAlignment(tw.AlignLeft),
+			// Cell text is trimmed by formatValue already; keep the padding
+			// that lines up decimal points (see padDecimals)
+			tablewriter.WithTrimSpace(tw.Off),
+			tablewriter.WithRendition(tw.Rendition{
+				Borders: tw.Border{Left: tw.On, Right: tw.On, Top: tw.On, Bottom: tw.On},
+				Settings: tw.Settings{
+					Separators: tw.Separators{BetweenColumns: tw.On, BetweenRows: tw.On},
+				},
+			}),
+		)
+	}
+}
+
+func truncateValue(s string, maxWidth int, multiline string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "")
+	s = escapeControl(s, true)
+
+	if strings.Contains(strings.TrimSpace(s), "\n") {
+		switch multiline {
+		case "keep":
+			// Keep line breaks, truncating each line on its own
+			lines := strings.Split(strings.TrimSpace(s), "\n")
+			for i, line := range lines {
+				lines[i] = TruncateWidth(strings.TrimRight(line, " \t"), maxWidth)
+			}
+			return strings.Join(lines, "\n")
+		case "marker":
+			// Show the first line followed by the number of lines hidden
+			lines := strings.Split(strings.TrimSpace(s), "\n")
+			marker := fmt.Sprintf(" ↵×%d", len(lines)-1)
+			return TruncateWidth(strings.TrimSpace(lines[0]), maxWidth-DisplayWidth(marker)) + marker
+		}
+	}
+
+	// Replace newlines with spaces for single-line display
+	s = strings.ReplaceAll(s, "\n", " ")
+
+	// Collapse multiple spaces
+	for strings.Contains(s, "  ") {
+		s = strings.ReplaceAll(s, "  ", " ")
+	}
+
+	s = strings.TrimSpace(s)
+
+	return TruncateWidth(s, maxWidth)
+}
+
+func formatValue(val interface{}, opts Options) string {
+	if v, ok := val.([]interface{}); ok && opts.Bars > 0 {
+		if line, ok := sparkline(v, opts.MaxWidth); ok {
+			return line
+		}
+	}
+	switch v := val.(type) {
+	case map[string]interface{}, []interface{}:
+		// Markdown has no nested tables
+		if opts.depth >= opts.MaxDepth || (opts.Levels > 0 && opts.depth+1 >= opts.Levels) || opts.Format == "markdown" {
+			return Summary(val)
+		}
+		nestedOpts := opts
+		nestedOpts.depth++
+		nested := renderRecursive(val, nestedOpts)
+		// For HTML, ensure nested table stays as single value (no newlines that could split it)
+		if opts.Format == "html" {
+			// Remove newlines to keep nested table in one cell
+			nested = strings.ReplaceAll(nested, "\n", "")
+			return nested
+		}
+		return nested
+	default:
+		// Truncate before escaping so entities are never cut in half
+		text := displayString(v, opts.Base64)
+		value := truncateValue(text, opts.MaxWidth, opts.Multiline)
+		if opts.Wrap {
+			value = wrapValue(text, opts.MaxWidth, opts.Multiline)
+		} else if opts.TruncationInfo && value != truncateValue(text, math.MaxInt, opts.Multiline) {
+			value = withTruncationInfo(text, opts)
+		}
+		// Escape HTML entities for primitive values in HTML format
+		switch opts.Format {
+		case "html":
+			value = strings.ReplaceAll(escapeHTML(value), "\n", "<br>")
+		case "markdown":
+			value = escapeMarkdown(value)
+		}
+		return value
+	}
+}
+
+// ScalarString formats a scalar value for display. Special values that Go
+// would print in its own syntax are given stable representations.
+func ScalarString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return NullToken
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		if IsDateOnly(v) {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		return binaryString(v, "binary", "summary")
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// IsDateOnly reports whether t is a date without a time of day, as YAML
+// dates are decoded.
+func IsDateOnly(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 && t.Location() == time.UTC
+}
+
+func escapeHTML(s string) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
+	s = strings.ReplaceAll(s, "\"", "&quot;")
+	s = strings.ReplaceAll(s, "'", "&#39;")
+	return s
+}
+
+// appendData fills table with data, returning how many of its rows were
+// left out by MaxRows.
+func appendData(table *tablewriter.Table, data interface{}, opts Options) int {
+	useColor := opts.Format == "table" && opts.Color
+
+	if opts.Transpose && opts.depth == 0 {
+		switch v := data.(type) {
+		case []interface{}:
+			if len(v) > 0 && IsObjectArray(v) {
+				appendTransposed(table, v, opts, useColor)
+				return 0
+			}
+		case map[string]interface{}:
+			appendTransposedMap(table, v, opts, useColor)
+			return 0
+		}
+	}
+
+	switch v := data.(type) {
+	case []interface{}:
+		return handleSlice(table, v, opts, useColor)
+	case map[string]interface{}:
+		return handleMap(table, v, opts, useColor)
+	default:
+		if opts.Format == "markdown" {
+			table.Header([]string{"[key]", "[value]"})
+		}
+		table.Append([]string{"value", markCell(formatValue(v, opts), opts)})
+	}
+	return 0
+}
+
+func handleSlice(table *tablewriter.Table, v []interface{}, opts Options, useColor bool) int {
+	if opts.Details {
+		table.Caption(tw.Caption{Text: fmt.Sprintf("[-] array, %d items", len(v))})
+	}
+	if len(v) == 0 {
+		return 0
+	}
+	first := 0
+	top := opts.depth == 0
+	if top {
+		first = opts.FirstIndex
+	}
+	showHeader := !(top && opts.NoHeader)
+	showIndex := !(top && opts.NoIndex)
+	shown, omitted := capRows(len(v), opts.MaxRows)
+
+	// Arrays that are not made up entirely of objects use an index/value
+	// layout, so every row has the same number of cells as the header.
+	// Objects among them are shown as nested tables in the value column.
+	if !IsObjectArray(v) {
+		if showHeader && showIndex {
+			table.Header([]string{"[key]", "[value]"})
+		} else if showHeader {
+			table.Header([]string{"[value]"})
+		}
+		numeric, decimals := numericColumn(v[:shown])
+		scale := newBarScale(v[:shown])
+		if showIndex {
+			alignColumns(table, []bool{false, numeric})
+		} else {
+			alignColumns(table, []bool{numeric})
+		}
+		for i, item := range v[:shown] {
+			itemOpts := opts.atIndex(i)
+			value := formatValue(item, itemOpts)
+			if numeric && opts.Format == "table" {
+				value = padDecimals(value, decimals)
+			}
+			if numeric {
+				value = withBar(value, item, scale, opts)
+			}
+			index := fmt.Sprintf("%d", first+i)
+			if showIndex {
+				appendRow(table, index, index, value, item, useColor, itemOpts)
+			} else {
+				table.Append([]string{markCell(styledValue(index, item, value, useColor, opts.Format), itemOpts)})
+			}
+		}
+		return omitted
+	}
+
+	headers := tableHeaders(v, opts)
+	columns := headers
+	if !showIndex {
+		columns = headers[1:]
+	}
+	if showHeader {
+		table.Header(displayHeaders(columns, opts))
+	}
+
+	numeric := make([]bool, len(headers))
+	decimals := make([]int, len(headers))
+	scales := make([]barScale, len(headers))
+	for c, key := range headers[1:] {
+		var values []interface{}
+		for _, item := range v[:shown] {
+			if val, exists := LookupColumn(item.(map[string]interface{}), key); exists {
+				values = append(values, val)
+			}
+		}
+		numeric[c+1], decimals[c+1] = numericColumn(values)
+		scales[c+1] = newBarScale(values)
+	}
+	if showIndex {
+		alignColumns(table, numeric)
+	} else {
+		alignColumns(table, numeric[1:])
+	}
+
+	for i, item := range v[:shown] {
+		m := item.(map[string]interface{})
+		row := []string{}
+
+		// Add index column with styling
+		if showIndex {
+			row = append(row, styledKey(fmt.Sprintf("%d", first+i), useColor, opts.Format))
+		}
+
+		// Add value columns with styling
+		for c, key := range headers[1:] {
+			val, exists := LookupColumn(m, key)
+			if !exists {
+				row = append(row, "")
+				continue
+			}
+			cellOpts := opts.atIndex(i).atKey(key)
+			value, humanized := humanizedValue(key, val, opts)
+			if !humanized {
+				value = formatValue(val, cellOpts)
+			}
+			if numeric[c+1] && !humanized && opts.Format == "table" {
+				value = padDecimals(value, decimals[c+1])
+			}
+			if numeric[c+1] {
+				value = withBar(value, val, scales[c+1], opts)
+			}
+
+			if useColor {
+				value = StyleFor(key, val).Render(value)
+			} else if opts.Format == "html" {
+				value = htmlValue(key, val, value)
+			}
+			row = append(row, markCell(value, cellOpts))
+		}
+		table.Append(row)
+	}
+	return omitted
+}
+
+// IsObjectArray reports whether every element of v is an object.
+func IsObjectArray(v []interface{}) bool {
+	for _, item := range v {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func handleMap(table *tablewriter.Table, v map[string]interface{}, opts Options, useColor bool) int {
+	keys := excludeKeys(OrderedKeys(v), opts)
+	if opts.Details {
+		table.Caption(tw.Caption{Text: fmt.Sprintf("[-] object, %d properties", len(keys))})
+	}
+	// Markdown tables cannot do without a header row
+	if opts.Format == "markdown" {
+		table.Header([]string{"[key]", "[value]"})
+	}
+	shown, omitted := capRows(len(keys), opts.MaxRows)
+	values := make([]interface{}, shown)
+	for i, key := range keys[:shown] {
+		values[i] = v[key]
+	}
+	numeric, decimals := numericColumn(values)
+	alignColumns(table, []bool{false, numeric})
+	for _, key := range keys[:shown] {
+		val := v[key]
+		valOpts := opts.atKey(key)
+		value, humanized := humanizedValue(key, val, opts)
+		if !humanized {
+			value = formatValue(val, valOpts)
+		}
+		if numeric && !humanized && opts.Format == "table" {
+			value = padDecimals(value, decimals)
+		}
+		appendRow(table, key, keyLabel(v, key), value, val, useColor, valOpts)
+	}
+	return omitted
+}
+
+// buildHeaders returns the index column followed by the columns of v.
+func buildHeaders(v []interface{}) []string {
+	return append([]string{"[key]"}, ColumnNames(v)...)
+}
+
+// ColumnNames returns the union of keys across all objects in v: the first
+// object's keys in display order, then keys first seen in later objects in
+// the order they appear.
+func ColumnNames(v []interface{}) []string {
+	var headers []string
+	seen := make(map[string]bool)
+	for _, item := range v {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range OrderedKeys(m) {
+			if !seen[key] {
+				seen[key] = true
+				headers = append(headers, key)
+			}
+		}
+	}
+	return headers
+}
+
+// OrderedKeys returns the keys of m in display order: source order when the
+// parser recorded one (see OrderKey), alphabetical otherwise.
+func OrderedKeys(m map[string]interface{}) []string {
+	order, _ := m[OrderKey].([]string)
+	keys := make([]string, 0, len(m))
+	listed := make(map[string]bool, len(order))
+	for _, k := range order {
+		if _, exists := m[k]; exists && !listed[k] {
+			keys = append(keys, k)
+			listed[k] = true
+		}
+	}
+
+	var rest []string
+	for k := range m {
+		if !IsMetaKey(k) && !listed[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// IsMetaKey reports whether k holds parser metadata rather than data.
+func IsMetaKey(k string) bool {
+	return k == OrderKey || k == KeyTypesKey
+}
+
+// keyLabel returns key as displayed in m, with a type hint for keys that were
+// not strings in the source document.
+func keyLabel(m map[string]interface{}, key string) string {
+	if types, ok := m[KeyTypesKey].(map[string]string); ok {
+		if t, ok := types[key]; ok {
+			return key + " (" + t + ")"
+		}
+	}
+	return key
+}
+
+// appendRow appends a key/value row. label is the key as displayed, which
+// may carry a type hint; key is used to look up color rules. opts are those
+// of the value.
+func appendRow(table *tablewriter.Table, key, label, value string, originalVal interface{}, useColor bool, opts Options) {
+	format := opts.Format
+	if useColor {
+		table.Append([]string{
+			keyStyle.Render(displayKey(label, format)),
+			markCell(StyleFor(key, originalVal).Render(value), opts),
+		})
+	} else if format == "html" {
+		// Add color styling via CSS classes for HTML output
+		styledKey := fmt.Sprintf(`<span class="jt-key">%s</span>`, displayKey(label, format))
+		styledValue := htmlValue(key, originalVal, value)
+
+		table.Append([]string{styledKey, styledValue})
+	} else {
+		table.Append([]string{displayKey(label, format), markCell(value, opts)})
+	}
+}
+
+func getHTMLClass(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "jt-null"
+	case bool:
+		return "jt-bool"
+	case string, time.Time:
+		return "jt-string"
+	case int, int64, float64, json.Number:
+		return "jt-number"
+	case map[string]interface{}, []interface{}:
+		return "jt-nested"
+	}
+	return "jt-key"
+}
+
+func getStyle(val interface{}) lipgloss.Style {
+	switch val.(type) {
+	case nil:
+		return nullStyle
+	case bool:
+		return boolStyle
+	case string, time.Time:
+		return stringStyle
+	case int, int64, float64, json.Number:
+		return intStyle
+	}
+	return keyStyle
+}
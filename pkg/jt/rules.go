@@ -0,0 +1,184 @@
+package jt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+// Named colors usable in color rules, taken from the same palette as the
+// built-in styles. Anything else is passed through as-is (hex or ANSI code).
+var namedColors = map[string]string{
+	"red":     "#e78284",
+	"green":   "#a6d189",
+	"yellow":  "#e5c890",
+	"blue":    "#8caaee",
+	"magenta": "#ca9ee6",
+	"cyan":    "#99d1db",
+	"orange":  "#ef9f76",
+	"gray":    "#737994",
+	"white":   "#c6d0f5",
+}
+
+type colorRule struct {
+	match string
+	color string
+}
+
+// RuleList keeps rules in the order they appear in the config file, so the
+// first matching rule wins (e.g. ">90" before ">50").
+type RuleList []colorRule
+
+func (r *RuleList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: color rules must be a mapping of value to color", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		*r = append(*r, colorRule{
+			match: node.Content[i].Value,
+			color: node.Content[i+1].Value,
+		})
+	}
+	return nil
+}
+
+// NewRuleList builds rules from pairs of a match and a color, for rules
+// set in code rather than read from a config file.
+func NewRuleList(matchColor ...string) RuleList {
+	var r RuleList
+	for i := 0; i+1 < len(matchColor); i += 2 {
+		r = append(r, colorRule{match: matchColor[i], color: matchColor[i+1]})
+	}
+	return r
+}
+
+var colorRules map[string]RuleList
+
+// SetColorRules sets the color rules, keyed by column or property name, that
+// values are styled with in terminal and HTML output.
+func SetColorRules(rules map[string]RuleList) {
+	colorRules = rules
+}
+
+func resolveColor(name string) string {
+	if c, ok := namedColors[strings.ToLower(name)]; ok {
+		return c
+	}
+	return name
+}
+
+// ruleColor returns the color of the first rule configured for key that
+// matches val. Only scalar values are considered.
+func ruleColor(key string, val interface{}) (string, bool) {
+	rules, ok := colorRules[key]
+	if !ok {
+		return "", false
+	}
+	switch val.(type) {
+	case map[string]interface{}, []interface{}:
+		return "", false
+	}
+	for _, rule := range rules {
+		if rule.matches(val) {
+			return resolveColor(rule.color), true
+		}
+	}
+	return "", false
+}
+
+func (r colorRule) matches(val interface{}) bool {
+	op, operand := splitOperator(r.match)
+	if op == "" {
+		return ScalarString(val) == r.match
+	}
+
+	threshold, err := strconv.ParseFloat(operand, 64)
+	n, isNum := ToFloat(val)
+	if err != nil || !isNum {
+		switch op {
+		case "==":
+			return ScalarString(val) == operand
+		case "!=":
+			return ScalarString(val) != operand
+		}
+		return false
+	}
+
+	switch op {
+	case ">":
+		return n > threshold
+	case ">=":
+		return n >= threshold
+	case "<":
+		return n < threshold
+	case "<=":
+		return n <= threshold
+	case "==":
+		return n == threshold
+	case "!=":
+		return n != threshold
+	}
+	return false
+}
+
+func splitOperator(s string) (string, string) {
+	for _, op := range []string{">=", "<=", "==", "!=", ">", "<"} {
+		if strings.HasPrefix(s, op) {
+			return op, strings.TrimSpace(s[len(op):])
+		}
+	}
+	return "", s
+}
+
+// Number returns n as a number like those read from the input, the way
+// filters and -map expressions compute them. NaN and infinities, such as
+// from a division by zero, become null.
+func Number(n float64) interface{} {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil
+	}
+	return json.Number(strconv.FormatFloat(n, 'f', -1, 64))
+}
+
+// ToFloat returns the numeric value of a number, or of a string holding one.
+func ToFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// StyleFor returns the terminal style for a value, honoring color rules.
+func StyleFor(key string, val interface{}) lipgloss.Style {
+	if c, ok := ruleColor(key, val); ok {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(c))
+	}
+	return getStyle(val)
+}
+
+// htmlValue wraps a formatted value in a span, honoring color rules.
+func htmlValue(key string, val interface{}, value string) string {
+	cssClass := getHTMLClass(val)
+	if c, ok := ruleColor(key, val); ok {
+		return fmt.Sprintf(`<span class="%s" style="color: %s">%s</span>`, cssClass, c, value)
+	}
+	return fmt.Sprintf(`<span class="%s">%s</span>`, cssClass, value)
+}
@@ -0,0 +1,69 @@
+package jt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// escapeControl replaces control characters in s with visible escapes such as
+// \x1b and \t, so raw escape sequences in the data can neither corrupt the
+// table layout nor reach the terminal. Line feeds are kept when keepNewlines
+// is set, for callers that lay out multi-line cells themselves.
+func escapeControl(s string, keepNewlines bool) string {
+	if !strings.ContainsFunc(s, isControl) {
+		return s
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\n' && keepNewlines:
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x80 && isControl(r):
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case isControl(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isControl reports whether r is a C0 or C1 control character or DEL.
+func isControl(r rune) bool {
+	return r < 0x20 || (r >= 0x7f && r <= 0x9f)
+}
+
+// displayKey formats an object key or column name for the given format.
+func displayKey(key, format string) string {
+	key = escapeControl(key, false)
+	switch format {
+	case "html":
+		return escapeHTML(key)
+	case "markdown":
+		return escapeMarkdown(key)
+	}
+	return key
+}
+
+// escapeMarkdown keeps a value inside its Markdown table cell: pipes would
+// start a new cell and line breaks a new row.
+func escapeMarkdown(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+func displayHeaders(headers []string, opts Options) []string {
+	result := make([]string, len(headers))
+	for i, h := range headers {
+		result[i] = displayKey(applyHeaderCase(h, opts.HeaderCase), opts.Format)
+	}
+	return result
+}
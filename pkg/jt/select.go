@@ -0,0 +1,258 @@
+package jt
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Select returns the part of data a selector such as .items[0].name points
+// to; "." selects everything. Applied to an array with a selector that does
+// not start with an index, it selects from each element. Selectors using
+// more than keys and indices are filters, evaluated as jq would.
+func Select(data interface{}, selector string) (interface{}, error) {
+	if selector == "." {
+		return data, nil
+	}
+	if IsFilter(selector) {
+		return selectFilter(data, selector)
+	}
+	if strings.HasPrefix(selector, "..") {
+		return selectRecursive(data, strings.TrimPrefix(selector, ".."))
+	}
+
+	if docs, ok := data.([]interface{}); ok {
+		trimmedSelector := strings.TrimPrefix(selector, ".")
+		if !strings.HasPrefix(trimmedSelector, "[") {
+			var results []interface{}
+			for _, doc := range docs {
+				result, err := Select(doc, selector)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, result)
+			}
+			return results, nil
+		}
+	}
+
+	return selectPath(data, SelectorSteps(selector), "")
+}
+
+// selectPath resolves steps against current. A * or [*] step collects what
+// the rest of the steps select from each value of an object or array into
+// an array, leaving out the values they do not match.
+func selectPath(current interface{}, steps []string, fullPath string) (interface{}, error) {
+	for i, key := range steps {
+		if fullPath == "" {
+			fullPath = key
+		} else {
+			fullPath += "." + key
+		}
+
+		if key == "*" || key == "[*]" {
+			return selectWildcard(current, steps[i+1:], fullPath)
+		}
+
+		var err error
+		if current, err = SelectStep(current, key, fullPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return current, nil
+}
+
+func selectWildcard(current interface{}, rest []string, fullPath string) (interface{}, error) {
+	var values []interface{}
+	switch v := current.(type) {
+	case map[string]interface{}:
+		for _, k := range OrderedKeys(v) {
+			values = append(values, v[k])
+		}
+	case []interface{}:
+		values = v
+	default:
+		return nil, fmt.Errorf("cannot expand wildcard on non-container at path '%s'", fullPath)
+	}
+
+	// further wildcards add their matches to the same array
+	nested := slices.ContainsFunc(rest, func(step string) bool { return step == "*" || step == "[*]" })
+	results := []interface{}{}
+	var firstErr error
+	for _, value := range values {
+		result, err := selectPath(value, rest, fullPath)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if matches, ok := result.([]interface{}); ok && nested {
+			results = append(results, matches...)
+		} else {
+			results = append(results, result)
+		}
+	}
+	if len(results) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
+
+// IsSelector reports whether s is a selector Select accepts: a path such
+// as .items[0].name, .* or ..image, or a filter that parses, such as
+// map(.name) or [.[] | .id]. Bare words are left out, as they are more
+// likely a file name.
+func IsSelector(s string) bool {
+	s = strings.TrimSpace(s)
+	if IsFilter(s) {
+		_, _, err := parseFilter(s)
+		return err == nil
+	}
+	return strings.HasPrefix(s, ".")
+}
+
+// SelectorSteps splits a selector into its keys and [index] steps.
+func SelectorSteps(selector string) []string {
+	// Normalize selector to handle array indexing
+	selector = strings.ReplaceAll(strings.TrimPrefix(selector, "."), "[", ".[")
+	var steps []string
+	for _, key := range strings.Split(selector, ".") {
+		if key != "" {
+			steps = append(steps, key)
+		}
+	}
+	return steps
+}
+
+// SelectStep resolves one step of a selector against current. fullPath is
+// the selector up to and including the step, for error messages.
+func SelectStep(current interface{}, key, fullPath string) (interface{}, error) {
+	if strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]") {
+		indexStr := strings.Trim(key, "[]")
+		if from, to, ok := strings.Cut(indexStr, ":"); ok {
+			return selectSlice(current, from, to, fullPath)
+		}
+		index, err := strconv.Atoi(indexStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid array index '%s' in path '%s'", indexStr, fullPath)
+		}
+
+		arr, ok := current.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot index into non-array at path '%s'", fullPath)
+		}
+
+		if index < 0 {
+			index += len(arr)
+		}
+		if index < 0 || index >= len(arr) {
+			return nil, fmt.Errorf("index %d out of bounds for array at path '%s'", index, fullPath)
+		}
+		return arr[index], nil
+	}
+
+	m, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot traverse into non-object at path '%s'", fullPath)
+	}
+
+	val, exists := m[key]
+	if !exists {
+		return nil, fmt.Errorf("key '%s' not found in path '%s'", key, fullPath)
+	}
+	return val, nil
+}
+
+// selectSlice resolves a [from:to] step, either bound of which may be left
+// out, of an array or a string.
+func selectSlice(current interface{}, from, to, fullPath string) (interface{}, error) {
+	var bounds [2]*int
+	for i, text := range []string{from, to} {
+		if text = strings.TrimSpace(text); text == "" {
+			continue
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slice bound '%s' in path '%s'", text, fullPath)
+		}
+		bounds[i] = &n
+	}
+	switch v := current.(type) {
+	case []interface{}:
+		start, end := SliceBounds(len(v), bounds[0], bounds[1])
+		return v[start:end], nil
+	case string:
+		r := []rune(v)
+		start, end := SliceBounds(len(r), bounds[0], bounds[1])
+		return string(r[start:end]), nil
+	}
+	return nil, fmt.Errorf("cannot slice non-array at path '%s'", fullPath)
+}
+
+// SliceBounds returns the bounds of a slice of n elements from start up to
+// end, which count from the end when negative and are clamped to the
+// elements there are. A nil start is the first element, a nil end the last.
+func SliceBounds(n int, start, end *int) (int, int) {
+	bound := func(b *int, open int) int {
+		if b == nil {
+			return open
+		}
+		i := *b
+		if i < 0 {
+			i += n
+		}
+		return min(max(i, 0), n)
+	}
+	from, to := bound(start, 0), bound(end, n)
+	return from, max(from, to)
+}
+
+// selectRecursive resolves a ..key selector: every value of a key with that
+// name at any depth, with its path, as a table of path and value rows. The
+// rest of the selector after the key, as in ..image.tag, is applied to each
+// value, leaving out those it does not match.
+func selectRecursive(data interface{}, selector string) (interface{}, error) {
+	key, rest := selector, ""
+	if i := strings.IndexAny(selector, ".["); i >= 0 {
+		key, rest = selector[:i], selector[i:]
+	}
+	if key == "" {
+		return nil, fmt.Errorf("missing key after '..' in '..%s'", selector)
+	}
+
+	rows := []interface{}{}
+	var firstErr error
+	var walk func(v interface{}, path string)
+	walk = func(v interface{}, path string) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for _, k := range OrderedKeys(v) {
+				if k == key {
+					value, err := selectPath(v[k], SelectorSteps(rest), "")
+					if err == nil {
+						rows = append(rows, map[string]interface{}{
+							"path":   path + "." + k + rest,
+							"value":  value,
+							OrderKey: []string{"path", "value"},
+						})
+					} else if firstErr == nil {
+						firstErr = err
+					}
+				}
+				walk(v[k], path+"."+k)
+			}
+		case []interface{}:
+			for i, item := range v {
+				walk(item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+	walk(data, "")
+	if len(rows) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return rows, nil
+}
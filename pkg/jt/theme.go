@@ -0,0 +1,137 @@
+package jt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Styles of table output, set by ApplyTheme.
+var (
+	headerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ca9ee6"))
+	keyStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#c6d0f5"))
+	stringStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#a6d189"))
+	boolStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#ea999c"))
+	intStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+	nullStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#737994")).Italic(true)
+)
+
+type theme struct {
+	header  string
+	key     string
+	str     string
+	boolean string
+	number  string
+	null    string
+	css     string // stylesheet prepended to HTML output
+}
+
+var themes = map[string]theme{
+	"dark": {
+		header:  "#ca9ee6",
+		key:     "#c6d0f5",
+		str:     "#a6d189",
+		boolean: "#ea999c",
+		number:  "15",
+		null:    "#737994",
+		css: `<style>
+.jt-table {
+	border-collapse: collapse;
+	background-color: #303446;
+	border: 1px solid #414559;
+	margin: 2px;
+}
+.jt-table th {
+	text-align: center;
+	color: #ca9ee6;
+	font-weight: bold;
+}
+.jt-table td {
+	border: 1px solid #414559;
+	padding: 8px;
+	text-align: left;
+}
+.jt-key { color: #c6d0f5; }
+.jt-string { color: #a6d189; }
+.jt-bool { color: #ea999c; }
+.jt-number { color: #ffffff; }
+.jt-nested { color: #c6d0f5; }
+.jt-null { color: #737994; font-style: italic; }
+.jt-footer { color: #737994; font-style: italic; }
+</style>`,
+	},
+	"light": {
+		header:  "#8839ef",
+		key:     "#4c4f69",
+		str:     "#40a02b",
+		boolean: "#e64553",
+		number:  "0",
+		null:    "#9ca0b0",
+		css: `<style>
+.jt-table {
+	border-collapse: collapse;
+	background-color: #eff1f5;
+	border: 1px solid #ccd0da;
+	margin: 2px;
+}
+.jt-table th {
+	text-align: center;
+	color: #8839ef;
+	font-weight: bold;
+}
+.jt-table td {
+	border: 1px solid #ccd0da;
+	padding: 8px;
+	text-align: left;
+}
+.jt-key { color: #4c4f69; }
+.jt-string { color: #40a02b; }
+.jt-bool { color: #e64553; }
+.jt-number { color: #000000; }
+.jt-nested { color: #4c4f69; }
+.jt-null { color: #9ca0b0; font-style: italic; }
+.jt-footer { color: #9ca0b0; font-style: italic; }
+</style>`,
+	},
+}
+
+var htmlStyleSheet = themes["dark"].css
+
+// ThemeNames lists the available themes, separated by slashes.
+func ThemeNames() string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "/")
+}
+
+// ApplyTheme switches table and HTML output to the named theme.
+func ApplyTheme(name string) error {
+	t, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme '%s' (available: %s)", name, ThemeNames())
+	}
+	headerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.header))
+	keyStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.key))
+	stringStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.str))
+	boolStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.boolean))
+	intStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.number))
+	nullStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.null)).Italic(true)
+	htmlStyleSheet = t.css
+	return nil
+}
+
+// StyleSheet returns the CSS of the current theme, for pages that include
+// HTML output.
+func StyleSheet() string {
+	return htmlStyleSheet
+}
+
+// KeyStyle returns the terminal style keys are shown in.
+func KeyStyle() lipgloss.Style {
+	return keyStyle
+}
@@ -0,0 +1,161 @@
+package jt
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// parseTOML decodes a TOML 1.0 document: tables become objects with their
+// keys in source order, offset date-times and dates time.Time values, and
+// local date-times and times strings, as they have no time zone.
+func parseTOML(input []byte) (interface{}, error) {
+	var root map[string]interface{}
+	md, err := toml.Decode(string(input), &root)
+	if err != nil {
+		var perr toml.ParseError
+		if errors.As(err, &perr) {
+			return nil, &ParseError{Format: "TOML", Msg: perr.Message, Input: input, Line: perr.Position.Line, Column: perr.Position.Col}
+		}
+		return nil, &ParseError{Format: "TOML", Msg: err.Error(), Input: input}
+	}
+	// the order the keys of each table were first given in, by the path of
+	// the table, each [[array]] table being told apart by its index
+	order := map[string][]string{}
+	seen := map[string]bool{}
+	tables := map[string]int{}
+	for _, key := range md.Keys() {
+		parent := ""
+		for i, k := range key {
+			path := k
+			if parent != "" {
+				path = parent + "\x00" + k
+			}
+			if !seen[path] {
+				seen[path] = true
+				order[parent] = append(order[parent], k)
+			}
+			if i == len(key)-1 {
+				if md.Type(key...) == "ArrayHash" {
+					tables[path]++
+				}
+			} else if n, ok := tables[path]; ok {
+				path += "[" + strconv.Itoa(n-1) + "]"
+			}
+			parent = path
+		}
+	}
+	return fromTOML(root, "", order), nil
+}
+
+// fromTOML converts a value decoded at path to the values jt renders.
+func fromTOML(v interface{}, path string, order map[string][]string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for _, k := range order[path] {
+			if _, ok := v[k]; ok {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) < len(v) {
+			listed := map[string]bool{}
+			for _, k := range keys {
+				listed[k] = true
+			}
+			var rest []string
+			for k := range v {
+				if !listed[k] {
+					rest = append(rest, k)
+				}
+			}
+			sort.Strings(rest)
+			keys = append(keys, rest...)
+		}
+		table := make(map[string]interface{}, len(v)+1)
+		for _, k := range keys {
+			child := k
+			if path != "" {
+				child = path + "\x00" + k
+			}
+			table[k] = fromTOML(v[k], child, order)
+		}
+		table[OrderKey] = keys
+		return table
+	case []map[string]interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = fromTOML(item, path+"["+strconv.Itoa(i)+"]", order)
+		}
+		return items
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = fromTOML(item, path, order)
+		}
+		return items
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return v
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return json.Number(s)
+	case time.Time:
+		// local values are decoded in a zone named after their kind
+		switch v.Location().String() {
+		case "date-local":
+			return time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+		case "datetime-local":
+			return v.Format("2006-01-02T15:04:05.999999999")
+		case "time-local":
+			return v.Format("15:04:05.999999999")
+		}
+		return v
+	}
+	return v
+}
+
+var (
+	tomlHeaderLine = regexp.MustCompile(`^\[\[?\s*[A-Za-z_][A-Za-z0-9_-]*(\s*\.\s*[A-Za-z0-9_"'-][^\]]*)?\]\]?\s*(#.*)?$`)
+	tomlKeyLine    = regexp.MustCompile(`^[A-Za-z0-9_-]+(\s*\.\s*[A-Za-z0-9_-]+)*\s*=`)
+)
+
+// isTOML reports whether the first line of input that is not blank or a
+// comment is a TOML [table] header or key = value pair, and some line is a
+// key = value pair. A header alone is as likely a YAML flow sequence, such
+// as [items].
+func isTOML(input []byte) bool {
+	first := true
+	for _, line := range strings.Split(string(input), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if tomlKeyLine.MatchString(line) {
+			return true
+		}
+		if first {
+			switch strings.Trim(line, "[] ") {
+			case "true", "false", "null":
+				return false
+			}
+			if !tomlHeaderLine.MatchString(line) {
+				return false
+			}
+			first = false
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package jt
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// appendTransposed renders an array of objects with one row per key and one
+// column per element, which reads better for a few records with many fields.
+func appendTransposed(table *tablewriter.Table, v []interface{}, opts Options, useColor bool) {
+	columns := []string{"[key]"}
+	for i := range v {
+		columns = append(columns, strconv.Itoa(opts.FirstIndex+i))
+	}
+	if !opts.NoHeader {
+		table.Header(displayHeaders(columns, opts))
+	}
+
+	for _, key := range tableHeaders(v, opts)[1:] {
+		row := []string{styledKey(key, useColor, opts.Format)}
+		for i, item := range v {
+			val, exists := LookupColumn(item.(map[string]interface{}), key)
+			if !exists {
+				row = append(row, "")
+				continue
+			}
+			cellOpts := opts.atIndex(i).atKey(key)
+			row = append(row, markCell(styledValue(key, val, cellValue(key, val, cellOpts), useColor, opts.Format), cellOpts))
+		}
+		table.Append(row)
+	}
+}
+
+// appendTransposedMap renders an object as a single row with its keys as
+// columns.
+func appendTransposedMap(table *tablewriter.Table, v map[string]interface{}, opts Options, useColor bool) {
+	keys := excludeKeys(OrderedKeys(v), opts)
+	labels := make([]string, len(keys))
+	row := make([]string, len(keys))
+	for i, key := range keys {
+		labels[i] = keyLabel(v, key)
+		cellOpts := opts.atKey(key)
+		row[i] = markCell(styledValue(key, v[key], cellValue(key, v[key], cellOpts), useColor, opts.Format), cellOpts)
+	}
+	if !opts.NoHeader {
+		table.Header(displayHeaders(labels, opts))
+	}
+	table.Append(row)
+}
+
+func styledKey(key string, useColor bool, format string) string {
+	switch {
+	case useColor:
+		return keyStyle.Render(displayKey(key, format))
+	case format == "html":
+		return fmt.Sprintf(`<span class="jt-key">%s</span>`, displayKey(key, format))
+	}
+	return displayKey(key, format)
+}
+
+func styledValue(key string, val interface{}, value string, useColor bool, format string) string {
+	switch {
+	case useColor:
+		return StyleFor(key, val).Render(value)
+	case format == "html":
+		return htmlValue(key, val, value)
+	}
+	return value
+}
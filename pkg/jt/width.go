@@ -0,0 +1,61 @@
+package jt
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/olekukonko/tablewriter/pkg/twwidth"
+)
+
+const ellipsis = "..."
+
+// DisplayWidth returns the number of terminal cells s occupies. ANSI escape
+// sequences take no space, and wide characters (CJK, emoji) take two cells.
+//
+// It deliberately uses the same width function tablewriter lays out cells
+// with, so truncation, search columns and highlighting agree with the
+// rendered table on every character.
+func DisplayWidth(s string) int {
+	return twwidth.Width(s)
+}
+
+// TruncateWidth shortens s to at most width cells, ending it with an
+// ellipsis. It never splits a multi-byte character or grapheme cluster.
+func TruncateWidth(s string, width int) string {
+	if DisplayWidth(s) <= width {
+		return s
+	}
+	if width <= len(ellipsis) {
+		return twwidth.Truncate(s, width)
+	}
+	return twwidth.Truncate(s, width, ellipsis)
+}
+
+// ContentWidth returns the width of the widest line of rendered output.
+func ContentWidth(content string) int {
+	maxWidth := 0
+	lines := strings.Split(content, "\n")
+	for _, line := range lines {
+		width := DisplayWidth(line)
+		if width > maxWidth {
+			maxWidth = width
+		}
+	}
+	return maxWidth
+}
+
+// minTruncatedWidth is the least of a value shown next to its truncation
+// info, however narrow the column.
+const minTruncatedWidth = 8
+
+// withTruncationInfo truncates text so that it fits the column together with
+// its length in characters and the start of its SHA-256: (len=18234,
+// sha256=ab12cd34…).
+func withTruncationInfo(text string, opts Options) string {
+	sum := sha256.Sum256([]byte(text))
+	info := fmt.Sprintf("(len=%d, sha256=%x%s)", utf8.RuneCountInString(text), sum[:4], ellipsis)
+	width := max(opts.MaxWidth-DisplayWidth(info)-1, minTruncatedWidth)
+	return truncateValue(text, width, opts.Multiline) + " " + info
+}
@@ -0,0 +1,154 @@
+package jt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// OrderKey holds the source order of a converted element's keys, so tables
+// list attributes and children as they appear in the document rather than
+// alphabetically. It is stored as []string, never as data, and is hidden
+// from rendering.
+const OrderKey = "#order"
+
+type xmlParser struct {
+	decoder  *xml.Decoder
+	input    []byte
+	raw      bool // keep CDATA sections and entity references as written
+	depth    int
+	maxDepth int
+}
+
+func parseXML(input []byte, opts ParseOptions) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(input))
+	decoder.Entity = xml.HTMLEntity
+	p := &xmlParser{decoder: decoder, input: input, raw: opts.XMLRaw, maxDepth: opts.MaxDepth}
+	var result interface{}
+	foundStartElement := false // New flag
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, xmlParseError(input, err)
+		}
+
+		if se, ok := token.(xml.StartElement); ok {
+			result, err = p.parseElement(se)
+			if pe, ok := err.(*ParseError); ok {
+				return nil, pe
+			}
+			if err != nil {
+				return nil, xmlParseError(input, err)
+			}
+			foundStartElement = true // Set flag
+			break
+		}
+	}
+
+	if !foundStartElement && result == nil { // If no start element found and result is still nil
+		return nil, fmt.Errorf("no XML start element found") // Return an explicit error
+	}
+
+	return result, nil
+}
+
+func (p *xmlParser) parseElement(start xml.StartElement) (interface{}, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > p.maxDepth {
+		return nil, depthError("XML", p.input, p.maxDepth)
+	}
+
+	children := make(map[string][]interface{})
+	var childOrder []string // child names in order of first appearance
+	var content []interface{}
+	var text, segment strings.Builder
+	hasAttributes := len(start.Attr) > 0
+
+	// content keeps text and child elements interleaved, for mixed content
+	flushSegment := func() {
+		if s := strings.TrimSpace(segment.String()); s != "" {
+			content = append(content, s)
+		}
+		segment.Reset()
+	}
+
+	for {
+		offset := p.decoder.InputOffset()
+		token, err := p.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := p.parseElement(t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			if _, seen := children[name]; !seen {
+				childOrder = append(childOrder, name)
+			}
+			children[name] = append(children[name], child)
+			flushSegment()
+			content = append(content, map[string]interface{}{name: child})
+		case xml.CharData:
+			if p.raw {
+				// The decoder has already resolved entities and unwrapped
+				// CDATA, so take the token's bytes straight from the input
+				t = p.input[offset:p.decoder.InputOffset()]
+			}
+			text.Write(t)
+			segment.Write(t)
+		case xml.EndElement:
+			flushSegment()
+			textContent := strings.TrimSpace(text.String())
+
+			// If we have no children and no attributes, just return text
+			if len(children) == 0 && !hasAttributes {
+				return textContent, nil
+			}
+
+			result := make(map[string]interface{})
+			var keys []string
+			add := func(key string, val interface{}) {
+				if _, exists := result[key]; !exists {
+					keys = append(keys, key)
+				}
+				result[key] = val
+			}
+
+			// Add attributes first (prefixed with @)
+			for _, attr := range start.Attr {
+				add("@"+attr.Name.Local, attr.Value)
+			}
+
+			if len(children) > 0 && textContent != "" {
+				// Mixed content: grouping children by name would lose where
+				// the text sits between them, so keep everything in order
+				add("#content", content)
+			} else {
+				for _, name := range childOrder {
+					if values := children[name]; len(values) == 1 {
+						add(name, values[0])
+					} else {
+						add(name, values)
+					}
+				}
+				if textContent != "" {
+					add("#text", textContent)
+				}
+			}
+
+			result[OrderKey] = keys
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package jt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyTypesKey records the original type of mapping keys that were not
+// strings, such as integer or boolean keys, so they can be displayed with a
+// type hint. It is stored as map[string]string, never as data.
+const KeyTypesKey = "#keytypes"
+
+// yamlConverter converts YAML nodes into the same generic structure produced
+// by the JSON decoder. Numbers become json.Number so that large integers and
+// decimal literals are printed exactly as written.
+type yamlConverter struct {
+	keepMerge  bool // show merge keys (<<) as-is instead of resolving them
+	duplicates []duplicateKey
+	depth      int
+	maxDepth   int
+	tooDeep    bool // set when the document nests more than maxDepth levels
+}
+
+func (c *yamlConverter) value(node *yaml.Node) interface{} {
+	c.depth++
+	defer func() { c.depth-- }()
+	if c.depth > c.maxDepth {
+		c.tooDeep = true
+		return nil
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return c.value(node.Content[0])
+	case yaml.AliasNode:
+		return c.value(node.Alias)
+	case yaml.SequenceNode:
+		result := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			result = append(result, c.value(item))
+		}
+		return result
+	case yaml.MappingNode:
+		return c.mapping(node)
+	}
+	return yamlScalar(node)
+}
+
+func (c *yamlConverter) mapping(node *yaml.Node) map[string]interface{} {
+	result := make(map[string]interface{})
+	keyTypes := make(map[string]string)
+	var merges []*yaml.Node
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.ShortTag() == "!!merge" && !c.keepMerge {
+			merges = append(merges, value)
+			continue
+		}
+		name, keyType := c.keyString(key)
+		if _, exists := result[name]; exists {
+			c.addDuplicate(duplicateKey{key: name, line: key.Line})
+		}
+		result[name] = c.value(value)
+		if keyType != "" {
+			keyTypes[name] = keyType
+		} else {
+			delete(keyTypes, name)
+		}
+	}
+
+	// Explicit keys win over merged ones, and earlier merge sources win
+	// over later ones.
+	for _, merge := range merges {
+		for _, source := range mergeSources(merge) {
+			merged := c.mapping(source)
+			mergedTypes, _ := merged[KeyTypesKey].(map[string]string)
+			for k, v := range merged {
+				if _, exists := result[k]; !exists && k != KeyTypesKey {
+					result[k] = v
+					if t, ok := mergedTypes[k]; ok {
+						keyTypes[k] = t
+					}
+				}
+			}
+		}
+	}
+
+	if len(keyTypes) > 0 {
+		result[KeyTypesKey] = keyTypes
+	}
+	return result
+}
+
+// keyString normalizes a mapping key to a string. Keys that are not strings
+// get a canonical form (0x1F becomes 31, yes stays a bool key "true", ...)
+// and their type is returned as a hint; string keys return an empty hint.
+func (c *yamlConverter) keyString(key *yaml.Node) (string, string) {
+	if key.Kind == yaml.AliasNode {
+		key = key.Alias
+	}
+	switch key.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		out, err := json.Marshal(stripMeta(c.value(key)))
+		if err != nil {
+			return key.Value, "key"
+		}
+		if key.Kind == yaml.MappingNode {
+			return string(out), "map"
+		}
+		return string(out), "seq"
+	}
+
+	switch tag := key.ShortTag(); tag {
+	case "!!str", "!!merge":
+		return key.Value, ""
+	case "!!int", "!!float", "!!bool", "!!null", "!!timestamp":
+		return ScalarString(yamlScalar(key)), strings.TrimPrefix(tag, "!!")
+	default:
+		return key.Value, strings.TrimPrefix(tag, "!")
+	}
+}
+
+// stripMeta returns v without parser metadata keys, for re-encoding.
+func stripMeta(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			if !IsMetaKey(k) {
+				result[k] = stripMeta(val)
+			}
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, val := range v {
+			result[i] = stripMeta(val)
+		}
+		return result
+	}
+	return v
+}
+
+// addDuplicate records d once, even though anchored mappings are converted
+// again for every alias that refers to them.
+func (c *yamlConverter) addDuplicate(d duplicateKey) {
+	for _, existing := range c.duplicates {
+		if existing == d {
+			return
+		}
+	}
+	c.duplicates = append(c.duplicates, d)
+}
+
+func mergeSources(node *yaml.Node) []*yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		return []*yaml.Node{node}
+	case yaml.SequenceNode:
+		var sources []*yaml.Node
+		for _, item := range node.Content {
+			sources = append(sources, mergeSources(item)...)
+		}
+		return sources
+	}
+	return nil
+}
+
+func yamlScalar(node *yaml.Node) interface{} {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil
+	case "!!str":
+		return node.Value
+	case "!!int":
+		var n int64
+		if err := node.Decode(&n); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+		if digits := strings.ReplaceAll(node.Value, "_", ""); IsDecimalInteger(digits) {
+			return json.Number(digits)
+		}
+	case "!!float":
+		if isJSONNumber(node.Value) {
+			return json.Number(node.Value)
+		}
+	case "!!binary":
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(node.Value), ""))
+		if err == nil {
+			return data
+		}
+	}
+
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return node.Value
+	}
+	return v
+}
+
+// IsDecimalInteger reports whether s is an integer written in decimal, with
+// an optional sign.
+func IsDecimalInteger(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isJSONNumber(s string) bool {
+	if s == "" || !(s[0] == '-' || (s[0] >= '0' && s[0] <= '9')) {
+		return false
+	}
+	return json.Valid([]byte(s))
+}
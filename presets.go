@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// preset bundles a selector and flags for the output of a common command,
+// e.g. -preset helm-list for `helm list -o json`.
+type preset struct {
+	Description string `yaml:"description"`
+	Selector    string `yaml:"selector"`
+	Flags       string `yaml:"flags"` // in the syntax of JT_OPTS
+}
+
+// builtinPresets ship with jt; preset files of the same name replace them.
+var builtinPresets = map[string]preset{
+	"aws-ec2": {
+		Description: "aws ec2 describe-instances --query 'Reservations[].Instances[]'",
+		Flags:       "-columns InstanceId,InstanceType,State.Name,PrivateIpAddress,PublicIpAddress,LaunchTime -sort-by LaunchTime:desc",
+	},
+	"gh-runs": {
+		Description: "gh api repos/{owner}/{repo}/actions/runs",
+		Selector:    ".workflow_runs",
+		Flags:       "-columns id,name,head_branch,event,status,conclusion,created_at -sort-by created_at:desc",
+	},
+	"docker-inspect": {
+		Description: "docker inspect <container>...",
+		Flags:       "-columns Name,Config.Image,State.Status,State.StartedAt,RestartCount,NetworkSettings.IPAddress",
+	},
+	"journald": {
+		Description: "journalctl -o json",
+		Flags:       "-journal",
+	},
+	"helm-list": {
+		Description: "helm list -o json",
+		Flags:       "-columns name,namespace,revision,status,chart,app_version,updated",
+	},
+}
+
+// presetDir is where users keep their own presets, <name>.yaml next to the
+// config file.
+func presetDir(configPath string) string {
+	if configPath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(configPath), "presets")
+}
+
+// loadPreset finds the named preset, in the preset directory first.
+func loadPreset(name, configPath string) preset {
+	if dir := presetDir(configPath); dir != "" && !strings.ContainsAny(name, `/\`) {
+		path := filepath.Join(dir, name+".yaml")
+		input, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			var p preset
+			if err := yaml.Unmarshal(input, &p); err != nil {
+				fail(exitUsage, "parsing preset %s: %v", path, err)
+			}
+			return p
+		case !os.IsNotExist(err):
+			fail(exitUsage, "reading preset: %v", err)
+		}
+	}
+	if p, ok := builtinPresets[name]; ok {
+		return p
+	}
+	fail(exitUsage, "unknown preset '%s' (available: %s)", name, strings.Join(presetNames(configPath), ", "))
+	return preset{} // Unreachable
+}
+
+// presetNames lists the built-in presets and those in the preset directory.
+func presetNames(configPath string) []string {
+	seen := make(map[string]bool)
+	for name := range builtinPresets {
+		seen[name] = true
+	}
+	if dir := presetDir(configPath); dir != "" {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+		for _, file := range files {
+			seen[strings.TrimSuffix(filepath.Base(file), ".yaml")] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyPreset sets the flags of p that were not given in JT_OPTS or on the
+// command line, so those still override the preset.
+func applyPreset(name string, p preset) {
+	args, err := splitArgs(p.Flags)
+	if err != nil {
+		fail(exitUsage, "parsing flags of preset %s: %v", name, err)
+	}
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	fs := flag.NewFlagSet("preset "+name, flag.ContinueOnError)
+	fs.SetOutput(flag.CommandLine.Output())
+	flag.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] {
+			fs.Var(ignoredValue{f.Value}, f.Name, f.Usage)
+		} else {
+			fs.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	if err := fs.Parse(args); err != nil {
+		os.Exit(exitUsage)
+	}
+	if fs.NArg() > 0 {
+		fail(exitUsage, "preset %s may only contain flags, got '%s'", name, fs.Arg(0))
+	}
+}
+
+// ignoredValue stands in for a flag given on the command line while a
+// preset is applied, so the preset cannot override it.
+type ignoredValue struct{ flag.Value }
+
+func (ignoredValue) Set(string) error { return nil }
+
+func (v ignoredValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
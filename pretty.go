@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// prettyPrinter renders data as indented JSON or YAML, coloring keys and
+// values with the same styles and color rules as tables.
+type prettyPrinter struct {
+	color bool
+}
+
+func (p prettyPrinter) key(s string) string {
+	if p.color {
+		return jt.KeyStyle().Render(s)
+	}
+	return s
+}
+
+func (p prettyPrinter) value(key string, v interface{}, text string) string {
+	if p.color {
+		return jt.StyleFor(key, v).Render(text)
+	}
+	return text
+}
+
+// renderPretty prints data in the format of the input: YAML stays YAML,
+// JSON and XML are shown as JSON.
+func renderPretty(data interface{}, opts renderOptions, isMultiDoc bool) string {
+	p := prettyPrinter{color: colorOutput(opts)}
+	docs := []interface{}{data}
+	if isMultiDoc {
+		docs = data.([]interface{})
+	}
+
+	var out []string
+	for _, doc := range docs {
+		var lines []string
+		if opts.source == "yaml" {
+			lines = p.yamlLines(doc, "")
+		} else {
+			lines = p.jsonLines(doc, "")
+		}
+		out = append(out, strings.Join(lines, "\n")+"\n")
+	}
+	if opts.source == "yaml" {
+		return strings.Join(out, "---\n")
+	}
+	return strings.Join(out, "")
+}
+
+// jsonLines renders v as indented JSON, one element per line.
+func (p prettyPrinter) jsonLines(v interface{}, key string) []string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := jt.OrderedKeys(v)
+		if len(keys) == 0 {
+			return []string{"{}"}
+		}
+		lines := []string{"{"}
+		for i, k := range keys {
+			name, _ := json.Marshal(k)
+			child := p.jsonLines(v[k], k)
+			child[0] = p.key(string(name)) + ": " + child[0]
+			if i < len(keys)-1 {
+				child[len(child)-1] += ","
+			}
+			lines = append(lines, indentLines(child, "  ")...)
+		}
+		return append(lines, "}")
+	case []interface{}:
+		if len(v) == 0 {
+			return []string{"[]"}
+		}
+		lines := []string{"["}
+		for i, item := range v {
+			child := p.jsonLines(item, key)
+			if i < len(v)-1 {
+				child[len(child)-1] += ","
+			}
+			lines = append(lines, indentLines(child, "  ")...)
+		}
+		return append(lines, "]")
+	}
+	text, err := encodeJSON(v, "")
+	if err != nil {
+		text = []byte(jt.ScalarString(v))
+	}
+	return []string{p.value(key, v, string(text))}
+}
+
+// yamlLines renders v as block-style YAML.
+func (p prettyPrinter) yamlLines(v interface{}, key string) []string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := jt.OrderedKeys(v)
+		if len(keys) == 0 {
+			return []string{"{}"}
+		}
+		var lines []string
+		for _, k := range keys {
+			name := p.key(yamlScalarText(k))
+			if isNonEmptyContainer(v[k]) {
+				lines = append(lines, name+":")
+				lines = append(lines, indentLines(p.yamlLines(v[k], k), "  ")...)
+				continue
+			}
+			lines = append(lines, name+": "+p.yamlLines(v[k], k)[0])
+		}
+		return lines
+	case []interface{}:
+		if len(v) == 0 {
+			return []string{"[]"}
+		}
+		var lines []string
+		for _, item := range v {
+			child := p.yamlLines(item, key)
+			lines = append(lines, "- "+child[0])
+			lines = append(lines, indentLines(child[1:], "  ")...)
+		}
+		return lines
+	}
+	return []string{p.value(key, v, yamlScalarText(v))}
+}
+
+// yamlScalarText returns a scalar as YAML would write it, quoted where
+// needed. Multi-line strings are written double-quoted so they fit on one
+// line.
+func yamlScalarText(v interface{}) string {
+	if s, ok := v.(string); ok && strings.ContainsAny(s, "\n\r") {
+		out, _ := json.Marshal(s)
+		return string(out)
+	}
+	out, err := yaml.Marshal(yamlNode(v))
+	if err != nil {
+		return jt.ScalarString(v)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+func isNonEmptyContainer(v interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return len(jt.OrderedKeys(v)) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+func indentLines(lines []string, prefix string) []string {
+	for i := range lines {
+		lines[i] = prefix + lines[i]
+	}
+	return lines
+}
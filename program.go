@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// loadProgram reads a selector from a file, for selectors too long to
+// write on the command line. Lines starting with # are comments; the other
+// lines are trimmed and joined, so a long selector can be split across
+// lines.
+func loadProgram(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fail(exitUsage, "reading program: %v", err)
+	}
+	var program strings.Builder
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		program.WriteString(line)
+	}
+	if program.Len() == 0 {
+		return "."
+	}
+	return program.String()
+}
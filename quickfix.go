@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+	"gopkg.in/yaml.v3"
+)
+
+// sourcePos is a line and column in the input, both counted from 1.
+type sourcePos struct {
+	line int
+	col  int
+}
+
+// quickfixSource maps paths in the selected data back to where they are
+// written in the input, for -grep and -quickfix.
+type quickfixSource struct {
+	filename string
+	selector string
+	mapped   bool                 // the selector was applied to each element of the input
+	values   map[string]sourcePos // where the value at a path starts
+	keys     map[string]sourcePos // where the key of a path is written
+}
+
+// newQuickfixSource records the positions of the values and keys of a JSON
+// or YAML input, parsed as data. Other formats have no positions and are
+// reported at the top of the file.
+func newQuickfixSource(input []byte, data interface{}, format, filename, selector string, isMultiDoc bool) *quickfixSource {
+	q := &quickfixSource{
+		filename: filename,
+		selector: strings.TrimPrefix(selector, "."),
+		values:   make(map[string]sourcePos),
+		keys:     make(map[string]sourcePos),
+	}
+	if q.selector != "" && !strings.HasPrefix(q.selector, "[") {
+		q.selector = "." + q.selector
+		_, q.mapped = data.([]interface{})
+	}
+	switch format {
+	case "json":
+		q.jsonPositions(input)
+	case "yaml":
+		q.yamlPositions(input, isMultiDoc)
+	}
+	return q
+}
+
+func (q *quickfixSource) jsonPositions(input []byte) {
+	starts := lineStarts(input)
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	// at is where the next token starts: the decoder's offset is at the end
+	// of the previous one, before any separator
+	at := func() sourcePos {
+		offset := int(decoder.InputOffset())
+		for offset < len(input) && strings.IndexByte(" \t\r\n,:", input[offset]) >= 0 {
+			offset++
+		}
+		return offsetPos(starts, offset)
+	}
+	var walk func(path string) error
+	walk = func(path string) error {
+		q.values[path] = at()
+		tok, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for decoder.More() {
+				pos := at()
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				keyPath := path + "." + key.(string)
+				q.keys[keyPath] = pos
+				if err := walk(keyPath); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		case json.Delim('['):
+			for i := 0; decoder.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		}
+		return err
+	}
+	// The input has been parsed already, so errors cannot happen here
+	walk("")
+}
+
+func (q *quickfixSource) yamlPositions(input []byte, isMultiDoc bool) {
+	decoder := yaml.NewDecoder(bytes.NewReader(input))
+	for i := 0; ; i++ {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if err != io.EOF {
+				return
+			}
+			break
+		}
+		prefix := ""
+		if isMultiDoc {
+			prefix = fmt.Sprintf("[%d]", i)
+		}
+		q.yamlNode(&doc, prefix)
+	}
+}
+
+func (q *quickfixSource) yamlNode(node *yaml.Node, path string) {
+	if node.Kind != yaml.DocumentNode {
+		q.values[path] = sourcePos{node.Line, node.Column}
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			q.yamlNode(child, path)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			keyPath := path + "." + keyNode.Value
+			q.keys[keyPath] = sourcePos{keyNode.Line, keyNode.Column}
+			q.yamlNode(node.Content[i+1], keyPath)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			q.yamlNode(child, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// lineStarts returns the offset at which each line of input starts.
+func lineStarts(input []byte) []int {
+	starts := []int{0}
+	for i, b := range input {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+func offsetPos(starts []int, offset int) sourcePos {
+	line := sort.SearchInts(starts, offset+1) - 1
+	return sourcePos{line + 1, offset - starts[line] + 1}
+}
+
+// find returns the position of the value at path in the selected data, or
+// of its closest ancestor written in the input: a missing property is
+// reported where its object starts.
+func (q *quickfixSource) find(path string, key bool) sourcePos {
+	path = strings.TrimPrefix(orRoot(path), ".")
+	if path != "" && !strings.HasPrefix(path, "[") {
+		path = "." + path
+	}
+	if q.mapped {
+		// [i].rest of the result is [i] of the input, then the selector
+		if end := strings.IndexByte(path, ']'); strings.HasPrefix(path, "[") && end > 0 {
+			path = path[:end+1] + q.selector + path[end+1:]
+		}
+	} else {
+		path = q.selector + path
+	}
+	if pos, ok := q.keys[path]; ok && key {
+		return pos
+	}
+	for {
+		if pos, ok := q.values[path]; ok {
+			return pos
+		}
+		if path == "" {
+			return sourcePos{1, 1}
+		}
+		path = path[:max(strings.LastIndexAny(path, ".["), 0)]
+	}
+}
+
+// line formats a result the way compilers report errors, for Vim's
+// quickfix list and editors' problem matchers.
+func (q *quickfixSource) line(path string, key bool, message string) string {
+	pos := q.find(path, key)
+	return fmt.Sprintf("%s:%d:%d: %s", q.filename, pos.line, pos.col, message)
+}
+
+// printQuickfix writes quickfix lines in file order.
+func printQuickfix(lines []string, positions []sourcePos, opts renderOptions) {
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		pa, pb := positions[order[a]], positions[order[b]]
+		return pa.line < pb.line || pa.line == pb.line && pa.col < pb.col
+	})
+	var buf bytes.Buffer
+	for _, i := range order {
+		buf.WriteString(lines[i])
+		buf.WriteByte('\n')
+	}
+	if !deliver(buf.Bytes(), opts) {
+		os.Stdout.Write(buf.Bytes())
+	}
+}
+
+// grepMatch is a key or scalar value of the data that contains the -grep
+// term.
+type grepMatch struct {
+	path  string
+	key   bool
+	value interface{}
+}
+
+// grepData finds the keys and scalar values containing term, ignoring case.
+func grepData(v interface{}, path, term string, matches *[]grepMatch) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, k := range jt.OrderedKeys(v) {
+			keyPath := path + "." + k
+			if strings.Contains(strings.ToLower(k), term) {
+				*matches = append(*matches, grepMatch{path: keyPath, key: true, value: v[k]})
+			}
+			grepData(v[k], keyPath, term, matches)
+		}
+	case []interface{}:
+		for i, item := range v {
+			grepData(item, fmt.Sprintf("%s[%d]", path, i), term, matches)
+		}
+	case nil:
+	default:
+		if strings.Contains(strings.ToLower(jt.ScalarString(v)), term) {
+			*matches = append(*matches, grepMatch{path: path, value: v})
+		}
+	}
+}
+
+// excerpt shows a matched value on one line.
+func excerpt(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return jt.Summary(v)
+	}
+	return jt.TruncateWidth(strings.Join(strings.Fields(jsonText(v)), " "), 80)
+}
+
+// runGrep lists the keys and values of data containing term with their
+// line in the input, or as quickfix lines with -quickfix.
+func runGrep(term string, data interface{}, source *quickfixSource, quickfix bool, opts renderOptions) {
+	var matches []grepMatch
+	grepData(data, "", strings.ToLower(term), &matches)
+	if quickfix {
+		var lines []string
+		var positions []sourcePos
+		for _, m := range matches {
+			lines = append(lines, source.line(m.path, m.key, orRoot(m.path)+": "+excerpt(m.value)))
+			positions = append(positions, source.find(m.path, m.key))
+		}
+		printQuickfix(lines, positions, opts)
+		return
+	}
+	if len(matches) == 0 {
+		fmt.Println("no matches")
+		return
+	}
+	rows := make([]interface{}, 0, len(matches))
+	for _, m := range matches {
+		rows = append(rows, map[string]interface{}{
+			"path":      orRoot(m.path),
+			"line":      source.find(m.path, m.key).line,
+			"value":     m.value,
+			jt.OrderKey: []string{"path", "line", "value"},
+		})
+	}
+	render(rows, opts, false)
+}
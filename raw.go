@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// rawOutput returns data as bare text for shell use, like `jq -r`: a scalar
+// as its plain value, an array of scalars as one value per line. Objects
+// and arrays containing them are not raw-printable.
+func rawOutput(data interface{}) ([]byte, bool) {
+	var buf bytes.Buffer
+	items, isSlice := data.([]interface{})
+	if !isSlice {
+		items = []interface{}{data}
+	}
+	for _, item := range items {
+		switch v := item.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, false
+		case []byte:
+			buf.Write(v)
+		default:
+			buf.WriteString(jt.ScalarString(v))
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), true
+}
+
+// printRaw writes data bare if it can be, and reports whether it did.
+func printRaw(data interface{}, opts renderOptions) bool {
+	out, ok := rawOutput(data)
+	if !ok {
+		return false
+	}
+	if !deliver(out, opts) {
+		os.Stdout.Write(out)
+	}
+	return true
+}
+
+// keyList returns the keys of an object in display order, or the indices of
+// an array, for -keys-only.
+func keyList(data interface{}) ([]interface{}, bool) {
+	var keys []interface{}
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for _, k := range jt.OrderedKeys(v) {
+			keys = append(keys, k)
+		}
+	case []interface{}:
+		for i := range v {
+			keys = append(keys, strconv.Itoa(i))
+		}
+	default:
+		return nil, false
+	}
+	return keys, true
+}
+
+// valueList returns the values of an object in key order, or the elements of
+// an array, for -values-only.
+func valueList(data interface{}) ([]interface{}, bool) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		var values []interface{}
+		for _, k := range jt.OrderedKeys(v) {
+			values = append(values, v[k])
+		}
+		return values, true
+	case []interface{}:
+		return v, true
+	}
+	return nil, false
+}
+
+// printLines writes items one per line for shell loops: scalars bare like
+// -r, objects and arrays as compact JSON.
+func printLines(items []interface{}, opts renderOptions) {
+	var buf bytes.Buffer
+	for _, item := range items {
+		switch v := item.(type) {
+		case map[string]interface{}, []interface{}:
+			out, err := encodeJSON(v, "")
+			if err != nil {
+				fail(exitRender, "encoding json: %v", err)
+			}
+			buf.Write(out)
+		case []byte:
+			buf.Write(v)
+		default:
+			buf.WriteString(jt.ScalarString(v))
+		}
+		buf.WriteByte('\n')
+	}
+	if !deliver(buf.Bytes(), opts) {
+		os.Stdout.Write(buf.Bytes())
+	}
+}
+
+// countOf returns the number of elements of an array or keys of an object;
+// null counts as empty and any other scalar as a single value.
+func countOf(data interface{}) int {
+	switch v := data.(type) {
+	case []interface{}:
+		return len(v)
+	case map[string]interface{}:
+		return len(jt.OrderedKeys(v))
+	case nil:
+		return 0
+	}
+	return 1
+}
+
+// isEmptyResult reports whether data is null, false, or an empty string,
+// array or object, for -exit-status.
+func isEmptyResult(data interface{}) bool {
+	switch v := data.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case []interface{}, map[string]interface{}:
+		return countOf(v) == 0
+	}
+	return false
+}
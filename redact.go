@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// redactedValue replaces the values -redact masks.
+const redactedValue = "***"
+
+// keyPattern is one pattern of -redact or -decode-base64, lowercased. A pattern without a
+// dot matches key names anywhere in the document; one with dots matches
+// key paths such as spec.env.value, where array indices are left out.
+type keyPattern struct {
+	glob   string // with / between keys, for path.Match
+	byPath bool
+}
+
+// redactPatterns is set from -redact before any input is read, and applied
+// to every document jt shows with redactInput.
+var redactPatterns []keyPattern
+
+// redactInput masks the values -redact asks for in data, as parsed.
+func redactInput(data interface{}) interface{} {
+	if len(redactPatterns) == 0 {
+		return data
+	}
+	return redact(data, "", redactPatterns)
+}
+
+// redactedPath reports whether -redact masks the value at path, spelled
+// like .spec.env[0].value, or the value of a key around it.
+func redactedPath(p string) bool {
+	keyPath := ""
+	for _, part := range strings.Split(p, ".") {
+		key, _, _ := strings.Cut(part, "[")
+		if key == "" {
+			continue
+		}
+		key = strings.ToLower(key)
+		if keyPath == "" {
+			keyPath = key
+		} else {
+			keyPath += "/" + key
+		}
+		if matchAny(redactPatterns, key, keyPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseKeyPatterns(list []string) ([]keyPattern, error) {
+	var patterns []keyPattern
+	for _, p := range list {
+		p = strings.ToLower(strings.TrimPrefix(p, "."))
+		glob := strings.ReplaceAll(p, ".", "/")
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %v", p, err)
+		}
+		patterns = append(patterns, keyPattern{glob: glob, byPath: strings.Contains(p, ".")})
+	}
+	return patterns, nil
+}
+
+func (p keyPattern) matches(key, keyPath string) bool {
+	name := key
+	if p.byPath {
+		name = keyPath
+	}
+	ok, _ := path.Match(p.glob, name)
+	return ok
+}
+
+func matchAny(patterns []keyPattern, key, keyPath string) bool {
+	for _, p := range patterns {
+		if p.matches(key, keyPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact masks the values of the keys matching any of the patterns, whole
+// objects and arrays included. keyPath is the lowercased path of keys
+// leading to v, separated by /.
+func redact(v interface{}, keyPath string, patterns []keyPattern) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, k := range jt.OrderedKeys(v) {
+			key := strings.ToLower(k)
+			childPath := key
+			if keyPath != "" {
+				childPath = keyPath + "/" + key
+			}
+			if matchAny(patterns, key, childPath) {
+				if v[k] != nil {
+					v[k] = redactedValue
+				}
+				continue
+			}
+			v[k] = redact(v[k], childPath, patterns)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item, keyPath, patterns)
+		}
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactVerifyReport(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "d.json")
+	input := `{"user":"bob","password":"oldsecret","password":"newsecret"}`
+	if err := os.WriteFile(file, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, code := runJT(t, "", "-redact", "password", "-verify", file)
+	if code != exitError || !strings.Contains(out, "duplicate value lost") {
+		t.Fatalf("got %q, exit %d; want the lost duplicate reported", out, code)
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("the report shows a redacted value: %s", out)
+	}
+}
+
+func TestRedactGraphQL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"user":{"name":"bob","password":"hunter2"}}}`)
+	}))
+	defer server.Close()
+	query := filepath.Join(t.TempDir(), "q.graphql")
+	if err := os.WriteFile(query, []byte("{ user { name password } }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, code := runJT(t, "", "-redact", "password", "-format", "json", "graphql", "-query", query, server.URL)
+	if code != 0 || compactJSON(out) != `{"user":{"name":"bob","password":"***"}}` {
+		t.Errorf("got %q, exit %d; want the password masked", out, code)
+	}
+}
+
+func TestRedactSQLQuery(t *testing.T) {
+	bin := t.TempDir()
+	psql := "#!/bin/sh\necho '[{\"name\":\"bob\",\"password\":\"hunter2\"}]'\n"
+	if err := os.WriteFile(filepath.Join(bin, "psql"), []byte(psql), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"PATH=" + bin + string(os.PathListSeparator) + os.Getenv("PATH")}
+	out, code := runJTEnv(t, env, "", "-redact", "password", "-format", "json", "sqlq", "postgres://db/app", "select * from users")
+	if code != 0 || compactJSON(out) != `[{"name":"bob","password":"***"}]` {
+		t.Errorf("got %q, exit %d; want the password masked", out, code)
+	}
+}
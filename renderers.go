@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// builtinRenderers are the output formats jt renders itself. Any other
+// format is rendered by a plugin: an executable named jt-render-<format>
+// on the PATH that reads the selected data as JSON on stdin and writes the
+// output on stdout.
+var builtinRenderers = []string{"table", "html", "markdown", "pretty", "json", "yaml"}
+
+// rendererPlugin returns the path of the plugin for -format.
+func rendererPlugin(format string) string {
+	path, err := exec.LookPath("jt-render-" + format)
+	if err != nil {
+		fail(exitUsage, "invalid -format '%s' (expected %s, or a jt-render-%s plugin on the PATH)", format, strings.Join(builtinRenderers, "/"), format)
+	}
+	return path
+}
+
+// runRenderer renders data with a plugin. Multi-document input is passed
+// as an array of the documents. JT_COLOR tells the plugin whether its
+// output goes to a terminal and may use ANSI colors.
+func runRenderer(path string, data interface{}, opts renderOptions) {
+	input, err := encodeJSON(data, "")
+	if err != nil {
+		fail(exitRender, "encoding json: %v", err)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "JT_COLOR="+map[bool]string{true: "1", false: "0"}[colorOutput(opts)])
+	output, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		fail(exitRender, "%s: %s", filepath.Base(path), message)
+	}
+	if !deliver(output, opts) {
+		os.Stdout.Write(output)
+	}
+}
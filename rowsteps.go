@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// rowSteps are the flags that reshape the selected data, parsed once: in
+// the order they are applied, -coerce, -tz, -map, -add-column, -flatten,
+// -where, -dedupe, -sort-by and -offset with -limit. Watch mode applies them
+// to every frame.
+type rowSteps struct {
+	coerce        bool
+	loc           *time.Location
+	maps          [][]mapAssignment
+	addedColumns  []mapAssignment
+	flatten       int
+	predicates    []predicate
+	dedupe        bool
+	dedupeBy      []string
+	dedupeCount   bool
+	sortKeys      []sortKey
+	offset, limit int
+}
+
+// rowFlags are the flags rowSteps are parsed from, as given.
+type rowFlags struct {
+	coerce        bool
+	tz            string
+	maps          []string
+	addedColumns  []string
+	flatten       int
+	where         []string
+	dedupe        bool
+	dedupeBy      string
+	dedupeCount   bool
+	sortBy        string
+	offset, limit int
+}
+
+// parseRowSteps parses the flags of the row steps, failing with exitUsage
+// on any that is invalid.
+func parseRowSteps(f rowFlags) rowSteps {
+	steps := rowSteps{
+		coerce:      f.coerce,
+		flatten:     f.flatten,
+		dedupe:      f.dedupe || f.dedupeBy != "" || f.dedupeCount,
+		dedupeBy:    splitList(f.dedupeBy),
+		dedupeCount: f.dedupeCount,
+		offset:      f.offset,
+		limit:       f.limit,
+	}
+	if f.tz != "" {
+		loc, err := loadTimezone(f.tz)
+		if err != nil {
+			fail(exitUsage, "invalid -tz '%s': %v", f.tz, err)
+		}
+		steps.loc = loc
+	}
+	for _, m := range f.maps {
+		program, err := parseMapProgram(m)
+		if err != nil {
+			fail(exitUsage, "invalid -map: %v", err)
+		}
+		steps.maps = append(steps.maps, program)
+	}
+	for _, c := range f.addedColumns {
+		a, err := parseAddColumn(c)
+		if err != nil {
+			fail(exitUsage, "invalid -add-column '%s': %v", c, err)
+		}
+		steps.addedColumns = append(steps.addedColumns, a)
+	}
+	for _, w := range f.where {
+		p, err := parsePredicate(w)
+		if err != nil {
+			fail(exitUsage, "invalid -where: %v", err)
+		}
+		steps.predicates = append(steps.predicates, p)
+	}
+	if f.sortBy != "" {
+		keys, err := parseSortKeys(f.sortBy)
+		if err != nil {
+			fail(exitUsage, "invalid -sort-by: %v", err)
+		}
+		steps.sortKeys = keys
+	}
+	return steps
+}
+
+// apply runs the steps over data. With -offset or -limit it also returns
+// the index of the first row kept and the footer saying which rows are
+// shown.
+func (s rowSteps) apply(data interface{}) (interface{}, int, string, error) {
+	if s.coerce {
+		data = coerceValues(data)
+	}
+	if s.loc != nil {
+		data = normalizeTimezones(data, s.loc)
+	}
+	var err error
+	for _, program := range s.maps {
+		if data, err = mapRows(data, program); err != nil {
+			return nil, 0, "", fmt.Errorf("-map: %v", err)
+		}
+	}
+	if len(s.addedColumns) > 0 {
+		if data, err = addColumns(data, s.addedColumns); err != nil {
+			return nil, 0, "", fmt.Errorf("-add-column: %v", err)
+		}
+	}
+	if s.flatten > 0 {
+		data = flattenRows(data, s.flatten)
+	}
+	if len(s.predicates) > 0 {
+		data = filterRows(data, s.predicates)
+	}
+	if s.dedupe {
+		data = dedupeRows(data, s.dedupeBy, s.dedupeCount)
+	}
+	if len(s.sortKeys) > 0 {
+		data = sortRows(data, s.sortKeys)
+	}
+	var start int
+	var footer string
+	if s.offset > 0 || s.limit > 0 {
+		data, start, footer = windowRows(data, s.offset, s.limit)
+	}
+	return data, start, footer, nil
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// schemaValidator checks a document against a JSON Schema. It covers the
+// validation keywords of draft 2020-12 that matter for configuration and API
+// payloads: type, enum, const, the numeric, string, array and object
+// constraints, the allOf/anyOf/oneOf/not combinators and local $ref
+// references. Annotations such as title or format are ignored.
+type schemaValidator struct {
+	root       map[string]interface{}
+	violations []interface{}
+	patterns   map[string]*regexp.Regexp
+}
+
+func (s *schemaValidator) report(path, message string, value interface{}) {
+	s.violations = append(s.violations, map[string]interface{}{
+		"path":      orRoot(path),
+		"message":   message,
+		"value":     value,
+		jt.OrderKey: []string{"path", "message", "value"},
+	})
+}
+
+// validate checks v at path against schema and reports each violation.
+func (s *schemaValidator) validate(v interface{}, schema interface{}, path string) {
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			s.report(path, "no value is allowed here", v)
+		}
+		return
+	case map[string]interface{}:
+		s.validateObject(v, schema, path)
+	}
+}
+
+// valid reports whether v matches schema without recording violations, for
+// the combinators.
+func (s *schemaValidator) valid(v interface{}, schema interface{}, path string) bool {
+	saved := s.violations
+	s.violations = nil
+	s.validate(v, schema, path)
+	ok := len(s.violations) == 0
+	s.violations = saved
+	return ok
+}
+
+func (s *schemaValidator) validateObject(v interface{}, schema map[string]interface{}, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := s.resolve(ref)
+		if err != nil {
+			s.report(path, err.Error(), v)
+			return
+		}
+		s.validate(v, target, path)
+	}
+
+	if t, ok := schema["type"]; ok {
+		var types []string
+		switch t := t.(type) {
+		case string:
+			types = []string{t}
+		case []interface{}:
+			for _, item := range t {
+				types = append(types, jt.ScalarString(item))
+			}
+		}
+		matched := false
+		for _, name := range types {
+			if schemaType(v, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			s.report(path, fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), typeName(v)), v)
+			// The remaining keywords would only repeat the mismatch
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if equalJSON(v, candidate) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			var allowed []string
+			for _, candidate := range enum {
+				allowed = append(allowed, jsonText(candidate))
+			}
+			s.report(path, "must be one of "+strings.Join(allowed, ", "), v)
+		}
+	}
+	if c, ok := schema["const"]; ok && !equalJSON(v, c) {
+		s.report(path, "must be "+jsonText(c), v)
+	}
+
+	s.validateNumber(v, schema, path)
+	s.validateString(v, schema, path)
+	s.validateArray(v, schema, path)
+	s.validateProperties(v, schema, path)
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			s.validate(v, sub, path)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if s.valid(v, sub, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			s.report(path, "does not match any of the allowed schemas (anyOf)", v)
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if s.valid(v, sub, path) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			s.report(path, fmt.Sprintf("must match exactly one schema (oneOf), matches %d", matches), v)
+		}
+	}
+	if not, ok := schema["not"]; ok && s.valid(v, not, path) {
+		s.report(path, "must not match the schema (not)", v)
+	}
+}
+
+func (s *schemaValidator) validateNumber(v interface{}, schema map[string]interface{}, path string) {
+	n, ok := schemaNumber(v)
+	if !ok {
+		return
+	}
+	limit := func(keyword string) (float64, bool) {
+		l, ok := schemaNumber(schema[keyword])
+		return l, ok
+	}
+	if l, ok := limit("minimum"); ok && n < l {
+		s.report(path, fmt.Sprintf("must be >= %s", jsonText(schema["minimum"])), v)
+	}
+	if l, ok := limit("maximum"); ok && n > l {
+		s.report(path, fmt.Sprintf("must be <= %s", jsonText(schema["maximum"])), v)
+	}
+	if l, ok := limit("exclusiveMinimum"); ok && n <= l {
+		s.report(path, fmt.Sprintf("must be > %s", jsonText(schema["exclusiveMinimum"])), v)
+	}
+	if l, ok := limit("exclusiveMaximum"); ok && n >= l {
+		s.report(path, fmt.Sprintf("must be < %s", jsonText(schema["exclusiveMaximum"])), v)
+	}
+	if l, ok := limit("multipleOf"); ok && l > 0 {
+		if q := n / l; math.Abs(q-math.Round(q)) > 1e-9 {
+			s.report(path, fmt.Sprintf("must be a multiple of %s", jsonText(schema["multipleOf"])), v)
+		}
+	}
+}
+
+func (s *schemaValidator) validateString(v interface{}, schema map[string]interface{}, path string) {
+	str, ok := schemaString(v)
+	if !ok {
+		return
+	}
+	length := len([]rune(str))
+	if l, ok := schemaNumber(schema["minLength"]); ok && float64(length) < l {
+		s.report(path, fmt.Sprintf("must be at least %s characters", jsonText(schema["minLength"])), v)
+	}
+	if l, ok := schemaNumber(schema["maxLength"]); ok && float64(length) > l {
+		s.report(path, fmt.Sprintf("must be at most %s characters", jsonText(schema["maxLength"])), v)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := s.compile(pattern)
+		if err != nil {
+			s.report(path, fmt.Sprintf("invalid pattern in schema: %v", err), v)
+		} else if !re.MatchString(str) {
+			s.report(path, "must match pattern "+pattern, v)
+		}
+	}
+}
+
+func (s *schemaValidator) validateArray(v interface{}, schema map[string]interface{}, path string) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return
+	}
+	if l, ok := schemaNumber(schema["minItems"]); ok && float64(len(items)) < l {
+		s.report(path, fmt.Sprintf("must have at least %s items", jsonText(schema["minItems"])), jt.Summary(v))
+	}
+	if l, ok := schemaNumber(schema["maxItems"]); ok && float64(len(items)) > l {
+		s.report(path, fmt.Sprintf("must have at most %s items", jsonText(schema["maxItems"])), jt.Summary(v))
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		seen := make(map[string]int)
+		for i, item := range items {
+			key := jsonText(item)
+			if j, ok := seen[key]; ok {
+				s.report(fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("duplicates item %d", j), item)
+				continue
+			}
+			seen[key] = i
+		}
+	}
+
+	prefix, _ := schema["prefixItems"].([]interface{})
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if i < len(prefix) {
+			s.validate(item, prefix[i], itemPath)
+		} else if sub, ok := schema["items"]; ok {
+			s.validate(item, sub, itemPath)
+		}
+	}
+}
+
+func (s *schemaValidator) validateProperties(v interface{}, schema map[string]interface{}, path string) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	keys := jt.OrderedKeys(obj)
+	if l, ok := schemaNumber(schema["minProperties"]); ok && float64(len(keys)) < l {
+		s.report(path, fmt.Sprintf("must have at least %s properties", jsonText(schema["minProperties"])), jt.Summary(v))
+	}
+	if l, ok := schemaNumber(schema["maxProperties"]); ok && float64(len(keys)) > l {
+		s.report(path, fmt.Sprintf("must have at most %s properties", jsonText(schema["maxProperties"])), jt.Summary(v))
+	}
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			key := jt.ScalarString(name)
+			if _, ok := obj[key]; !ok {
+				s.report(path+"."+key, "required property is missing", "")
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	patterns, _ := schema["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+	for _, key := range keys {
+		keyPath := path + "." + key
+		matched := false
+		if sub, ok := properties[key]; ok {
+			s.validate(obj[key], sub, keyPath)
+			matched = true
+		}
+		for pattern, sub := range patterns {
+			if re, err := s.compile(pattern); err == nil && re.MatchString(key) {
+				s.validate(obj[key], sub, keyPath)
+				matched = true
+			}
+		}
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				s.report(keyPath, "property is not allowed", obj[key])
+			} else {
+				s.validate(obj[key], additional, keyPath)
+			}
+		}
+	}
+}
+
+// resolve follows a local reference such as #/$defs/address.
+func (s *schemaValidator) resolve(ref string) (interface{}, error) {
+	if ref == "#" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %s: only references within the schema are followed", ref)
+	}
+	var current interface{} = s.root
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %s", ref)
+		}
+		if current, ok = obj[part]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %s", ref)
+		}
+	}
+	return current, nil
+}
+
+func (s *schemaValidator) compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := s.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	s.patterns[pattern] = re
+	return re, nil
+}
+
+// schemaType reports whether v is of the named JSON Schema type. YAML
+// timestamps and binary values count as strings, as they would in JSON.
+func schemaType(v interface{}, name string) bool {
+	switch name {
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			f, isFloat := v.(float64)
+			return isFloat && f == math.Trunc(f)
+		}
+		r, ok := new(big.Rat).SetString(n.String())
+		return ok && r.IsInt()
+	case "number":
+		return typeName(v) == "number"
+	case "string":
+		_, ok := schemaString(v)
+		return ok
+	case "boolean":
+		return typeName(v) == "bool"
+	}
+	return typeName(v) == name
+}
+
+func schemaNumber(v interface{}) (float64, bool) {
+	switch v.(type) {
+	case json.Number, float64, int, int64, uint64:
+		return jt.ToFloat(v)
+	}
+	return 0, false
+}
+
+func schemaString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case time.Time, []byte:
+		return jt.ScalarString(v), true
+	}
+	return "", false
+}
+
+// equalJSON compares two values as JSON would, numbers by value.
+func equalJSON(a, b interface{}) bool {
+	if x, ok := schemaNumber(a); ok {
+		y, ok := schemaNumber(b)
+		return ok && x == y
+	}
+	return jsonText(a) == jsonText(b)
+}
+
+func jsonText(v interface{}) string {
+	out, err := encodeJSON(v, "")
+	if err != nil {
+		return jt.ScalarString(v)
+	}
+	return string(out)
+}
+
+// runSchema validates data, or each document of multi-document input,
+// against the schema in path and renders the violations, or prints them as
+// quickfix lines when source is given, exiting with exitInvalid if there are
+// any.
+func runSchema(path string, data interface{}, isMultiDoc bool, source *quickfixSource, popts jt.ParseOptions, opts renderOptions) {
+	popts.Filename = path
+	schema, _ := parseInput(readFile(path), popts)
+	v := &schemaValidator{patterns: make(map[string]*regexp.Regexp)}
+	switch root := schema.(type) {
+	case map[string]interface{}:
+		v.root = root
+	case bool:
+	default:
+		fail(exitUsage, "schema %s must be an object or boolean", path)
+	}
+
+	if docs, ok := data.([]interface{}); ok && isMultiDoc {
+		for i, doc := range docs {
+			v.validate(doc, schema, fmt.Sprintf("[%d]", i))
+		}
+	} else {
+		v.validate(data, schema, "")
+	}
+	if source != nil {
+		var lines []string
+		var positions []sourcePos
+		for _, violation := range v.violations {
+			row := violation.(map[string]interface{})
+			path := row["path"].(string)
+			lines = append(lines, source.line(path, false, path+": "+row["message"].(string)))
+			positions = append(positions, source.find(path, false))
+		}
+		printQuickfix(lines, positions, opts)
+		if len(lines) > 0 {
+			os.Exit(exitInvalid)
+		}
+		return
+	}
+	if len(v.violations) == 0 {
+		fmt.Println("document is valid")
+		return
+	}
+	render(v.violations, opts, false)
+	os.Exit(exitInvalid)
+}
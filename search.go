@@ -0,0 +1,286 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+func (m *model) findMatches() {
+	m.matches = []searchMatch{}
+	if m.searchTerm == "" {
+		return
+	}
+
+	searchLower, _ := lowerWithOffsets(m.searchTerm)
+	for lineNum, line := range m.plainContent {
+		// Lowercasing can change the byte length of some characters, so
+		// keep a map from lowered offsets back to the original line
+		lineLower, offsets := lowerWithOffsets(line)
+		col := 0
+		for {
+			idx := strings.Index(lineLower[col:], searchLower)
+			if idx == -1 {
+				break
+			}
+			start := col + idx
+			end := start + len(searchLower)
+			m.matches = append(m.matches, searchMatch{
+				line: lineNum,
+				col:  offsets[start],
+				end:  offsets[end],
+				text: m.searchTerm,
+			})
+			col = end
+		}
+	}
+}
+
+// lowerWithOffsets lowercases s rune by rune. offsets[i] is the byte offset
+// in s of the character at byte i of the result; offsets[len(result)] is
+// len(s).
+func lowerWithOffsets(s string) (string, []int) {
+	var b strings.Builder
+	offsets := make([]int, 0, len(s)+1)
+	for i, r := range s {
+		n, _ := b.WriteRune(unicode.ToLower(r))
+		for j := 0; j < n; j++ {
+			offsets = append(offsets, i)
+		}
+	}
+	return b.String(), append(offsets, len(s))
+}
+
+func (m *model) renderContent() string {
+	if m.searchTerm == "" {
+		return strings.Join(m.content, "\n")
+	}
+
+	highlightedLines := make([]string, len(m.content))
+	copy(highlightedLines, m.content)
+
+	// Matches are found line by line, left to right, so the matches for a
+	// line are contiguous and already sorted by column
+	for i := 0; i < len(m.matches); {
+		lineNum := m.matches[i].line
+		var ranges []highlightRange
+		for ; i < len(m.matches) && m.matches[i].line == lineNum; i++ {
+			style := highlightStyle
+			if i == m.currentMatch {
+				style = currentMatchStyle
+			}
+			ranges = append(ranges, highlightRange{start: m.matches[i].col, end: m.matches[i].end, style: style})
+		}
+		if lineNum < len(m.content) {
+			highlightedLines[lineNum] = highlightLine(m.content[lineNum], ranges)
+		}
+	}
+
+	return strings.Join(highlightedLines, "\n")
+}
+
+// highlightRange is a span of a line in plain-text byte offsets.
+type highlightRange struct {
+	start int
+	end   int
+	style lipgloss.Style
+}
+
+// highlightLine renders the given plain-text ranges of a styled line with
+// their highlight style. Offsets are mapped past the line's ANSI escape
+// sequences, and the line's own styling is restored after each highlight.
+func highlightLine(styled string, ranges []highlightRange) string {
+	var result, matchText strings.Builder
+	var active []string // escape sequences in effect since the last reset
+	plainPos := 0
+	next := 0
+	inMatch := false
+
+	for i := 0; i < len(styled); {
+		if styled[i] == '\x1b' {
+			j := escapeEnd(styled, i)
+			seq := styled[i:j]
+			if seq == "\x1b[0m" || seq == "\x1b[m" {
+				active = active[:0]
+			} else {
+				active = append(active, seq)
+			}
+			if !inMatch {
+				result.WriteString(seq)
+			}
+			i = j
+			continue
+		}
+
+		if !inMatch && next < len(ranges) && plainPos == ranges[next].start {
+			inMatch = true
+			matchText.Reset()
+		}
+
+		_, size := utf8.DecodeRuneInString(styled[i:])
+		if inMatch {
+			matchText.WriteString(styled[i : i+size])
+		} else {
+			result.WriteString(styled[i : i+size])
+		}
+		i += size
+		plainPos += size
+
+		if inMatch && plainPos >= ranges[next].end {
+			result.WriteString("\x1b[0m")
+			result.WriteString(ranges[next].style.Render(matchText.String()))
+			result.WriteString(strings.Join(active, ""))
+			inMatch = false
+			next++
+		}
+	}
+
+	if inMatch {
+		result.WriteString(ranges[next].style.Render(matchText.String()))
+	}
+	return result.String()
+}
+
+// escapeEnd returns the index just past the escape sequence starting at
+// s[i], using the same rule as stripANSI: it ends at the first letter.
+func escapeEnd(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		c := s[j]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// openFilter starts filter mode, in which the rows not containing the
+// typed term are hidden as it is typed, if the data is an array of rows.
+func (m *model) openFilter() tea.Cmd {
+	if _, ok := m.inputRows(); !ok {
+		m.notice = "Only an array of rows can be filtered; select one first, e.g. .items"
+		return nil
+	}
+	m.filterMode = true
+	m.filterInput.SetValue(m.filterTerm)
+	m.filterInput.CursorEnd()
+	m.filterInput.Focus()
+	return textinput.Blink
+}
+
+// filterKey handles a key in filter mode: enter keeps the filter, esc
+// drops it, and anything else edits the term and filters again.
+func (m *model) filterKey(msg tea.KeyMsg) tea.Cmd {
+	var cmd tea.Cmd
+	switch msg.String() {
+	case "enter":
+		m.filterMode = false
+		m.filterInput.Blur()
+		return nil
+	case "esc":
+		m.filterMode = false
+		m.filterInput.Blur()
+		m.filterInput.SetValue("")
+	default:
+		m.filterInput, cmd = m.filterInput.Update(msg)
+	}
+	if term := m.filterInput.Value(); term != m.filterTerm {
+		m.filterTerm = term
+		m.showRows()
+		m.viewport.GotoTop()
+	}
+	return cmd
+}
+
+// rowField is a scalar value of a row and the keys leading to it, all
+// lowercased, for the filter.
+type rowField struct {
+	keys  []string
+	value string
+}
+
+// rowMatches reports whether input row i matches the filter term, ignoring
+// case. A term such as name:web keeps the rows whose name contains web. Any
+// other term is looked for in the values, or in the keys when no value of
+// any row contains it, so a column name keeps the rows that have the
+// column. The fields of the rows are gathered on first use.
+func (m *model) rowMatches(i int) bool {
+	if m.filterTerm == "" {
+		return true
+	}
+	if len(m.rowFields) != len(m.rows) {
+		m.rowFields = make([][]rowField, len(m.rows))
+		for j, row := range m.rows {
+			m.rowFields[j] = appendRowFields(nil, nil, row)
+		}
+		m.scopeTerm = ""
+	}
+	term := strings.ToLower(m.filterTerm)
+	if m.scopeTerm != term {
+		m.scopeTerm, m.filterScope = term, m.filterScopeFor(term)
+	}
+	key, value, _ := strings.Cut(term, ":")
+	for _, f := range m.rowFields[i] {
+		switch m.filterScope {
+		case "pair":
+			if slices.Contains(f.keys, key) && strings.Contains(f.value, value) {
+				return true
+			}
+		case "value":
+			if strings.Contains(f.value, term) {
+				return true
+			}
+		default:
+			if slices.ContainsFunc(f.keys, func(k string) bool { return strings.Contains(k, term) }) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterScopeFor returns what the filter term is matched against: "pair"
+// for a key:value term naming a key some row has, "value" when some value
+// contains it, and "key" otherwise.
+func (m *model) filterScopeFor(term string) string {
+	key, _, pair := strings.Cut(term, ":")
+	for _, fields := range m.rowFields {
+		for _, f := range fields {
+			if pair && slices.Contains(f.keys, key) {
+				return "pair"
+			}
+		}
+	}
+	for _, fields := range m.rowFields {
+		for _, f := range fields {
+			if strings.Contains(f.value, term) {
+				return "value"
+			}
+		}
+	}
+	return "key"
+}
+
+// appendRowFields appends the scalar values in v, found under keys, to
+// fields.
+func appendRowFields(fields []rowField, keys []string, v interface{}) []rowField {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, k := range jt.OrderedKeys(v) {
+			fields = appendRowFields(fields, append(slices.Clip(keys), strings.ToLower(k)), v[k])
+		}
+	case []interface{}:
+		for _, item := range v {
+			fields = appendRowFields(fields, keys, item)
+		}
+	default:
+		fields = append(fields, rowField{keys: keys, value: strings.ToLower(jt.ScalarString(v))})
+	}
+	return fields
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestFilterTerms(t *testing.T) {
+	var rows []interface{}
+	input := `[{"name":"web","port":80},{"name":"db","port":5432},{"host":"cache","port":6379}]`
+	if err := json.Unmarshal([]byte(input), &rows); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		term string
+		want []int
+	}{
+		{"web", []int{0}},
+		{"name", []int{0, 1}},
+		{"host", []int{2}},
+		{"name:d", []int{1}},
+		{"port:80", []int{0}},
+		{"80", []int{0}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		m := &model{rows: rows, filterTerm: tt.term}
+		var got []int
+		for i := range rows {
+			if m.rowMatches(i) {
+				got = append(got, i)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("filter %q: got rows %v, want %v", tt.term, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// runServe implements `jt serve`: it serves a page for browsing the file on
+// listen. The page queries the JSON API at /api/query, which re-reads the
+// file and evaluates the selector on every request, so the page always
+// shows the file as it is now. Errors are reported to the browser rather
+// than ending the server.
+func runServe(args []string, listen, caption string, popts jt.ParseOptions, opts renderOptions) {
+	if len(args) < 1 || len(args) > 2 || args[0] == "-" {
+		fail(exitUsage, "usage: jt serve [-listen addr] <file> [selector]")
+	}
+	path := args[0]
+	selector := "."
+	if len(args) == 2 {
+		selector = args[1]
+	}
+	if !isFile(path) {
+		fail(exitUsage, "file not found: %s", path)
+	}
+	popts.Filename = path
+	opts.Format = "html"
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, servePage(path, selector))
+	})
+	http.HandleFunc("/api/query", func(w http.ResponseWriter, r *http.Request) {
+		selector := r.URL.Query().Get("selector")
+		if selector == "" {
+			selector = "."
+		}
+		opts := opts
+		opts.Caption = expandCaption(caption, path, selector)
+		result, status := runQuery(path, selector, r.URL.Query().Get("sort"), popts, opts)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(result)
+	})
+
+	fmt.Fprintf(os.Stderr, "Serving %s on %s\n", path, serveURL(listen))
+	if err := http.ListenAndServe(listen, nil); err != nil {
+		fail(exitError, "serving: %v", err)
+	}
+}
+
+// queryResult is the answer of /api/query: the rendered tables, and the
+// columns the rows can be sorted by when the result is an array of objects.
+type queryResult struct {
+	HTML    string   `json:"html,omitempty"`
+	Columns []string `json:"columns,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// runQuery reads path, applies the selector and sorts the rows by sort, a
+// -sort-by list, then renders the result. The status tells problems with
+// the query apart from problems with the file.
+func runQuery(path, selector, sort string, popts jt.ParseOptions, opts renderOptions) (queryResult, int) {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return queryResult{Error: err.Error()}, http.StatusInternalServerError
+	}
+	data, isMultiDoc, err := jt.Parse(input, popts)
+	if err != nil {
+		return queryResult{Error: err.Error()}, http.StatusInternalServerError
+	}
+	data, err = jt.Select(redactInput(data), selector)
+	if err != nil {
+		return queryResult{Error: err.Error()}, http.StatusBadRequest
+	}
+	keys, err := parseSortKeys(sort)
+	if err != nil {
+		return queryResult{Error: err.Error()}, http.StatusBadRequest
+	}
+	data = sortRows(data, keys)
+
+	var result queryResult
+	if rows, ok := data.([]interface{}); ok && !isMultiDoc && !opts.Transpose && len(rows) > 0 && jt.IsObjectArray(rows) {
+		result.Columns = jt.TableColumns(rows, opts.Options)
+	}
+	result.HTML, err = jt.RenderDocuments(data, opts.Options, isMultiDoc)
+	if err != nil {
+		return queryResult{Error: err.Error()}, http.StatusInternalServerError
+	}
+	return result, http.StatusOK
+}
+
+// servePage returns the page of `jt serve`, starting at selector.
+func servePage(path, selector string) string {
+	return strings.NewReplacer(
+		"{{title}}", html.EscapeString(path),
+		"{{selector}}", html.EscapeString(selector),
+		"{{stylesheet}}", jt.StyleSheet(),
+	).Replace(servePageTemplate)
+}
+
+// serveURL turns a listen address such as :8080 into a URL to open.
+func serveURL(listen string) string {
+	if strings.HasPrefix(listen, ":") {
+		listen = "localhost" + listen
+	}
+	return "http://" + listen + "/"
+}
+
+// servePageTemplate is the web UI: a selector box that re-queries the
+// server, a search box that hides non-matching rows, and column headers
+// that sort the rows when clicked.
+const servePageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{title}}</title>
+{{stylesheet}}
+<style>
+.jt-bar { display: flex; gap: 8px; margin: 2px 2px 8px; font-family: monospace; }
+.jt-bar input { font-family: monospace; padding: 4px; }
+#selector { flex: 1; }
+.jt-error { color: #e78284; }
+.jt-sortable { cursor: pointer; }
+.jt-sorted::after { content: " ▲"; }
+.jt-sorted.jt-desc::after { content: " ▼"; }
+</style>
+</head>
+<body>
+<form class="jt-bar" id="query">
+<input id="selector" value="{{selector}}" placeholder="Selector, e.g. .items[0].metadata" spellcheck="false">
+<input id="search" type="search" placeholder="Search rows">
+</form>
+<div id="result"></div>
+<script>
+const form = document.getElementById("query");
+const selector = document.getElementById("selector");
+const search = document.getElementById("search");
+const result = document.getElementById("result");
+let sort = {column: "", desc: false};
+
+async function query() {
+	const params = new URLSearchParams({selector: selector.value});
+	if (sort.column) {
+		params.set("sort", sort.column + (sort.desc ? ":desc" : ""));
+	}
+	const response = await fetch("/api/query?" + params);
+	const answer = await response.json();
+	if (answer.error) {
+		result.innerHTML = "";
+		const pre = document.createElement("pre");
+		pre.className = "jt-error";
+		pre.textContent = answer.error;
+		result.appendChild(pre);
+		return;
+	}
+	result.innerHTML = answer.html;
+	addSorting(answer.columns || []);
+	filter();
+}
+
+// The index column comes first, so the columns are the last header cells
+function addSorting(columns) {
+	const table = result.querySelector(":scope > table");
+	if (!table || columns.length === 0) {
+		return;
+	}
+	const cells = Array.from(table.querySelectorAll(":scope > thead > tr > th")).slice(-columns.length);
+	cells.forEach((cell, i) => {
+		cell.classList.add("jt-sortable");
+		if (columns[i] === sort.column) {
+			cell.classList.add("jt-sorted");
+			cell.classList.toggle("jt-desc", sort.desc);
+		}
+		cell.addEventListener("click", () => {
+			sort = {column: columns[i], desc: sort.column === columns[i] && !sort.desc};
+			query();
+		});
+	});
+}
+
+function filter() {
+	const term = search.value.toLowerCase();
+	for (const row of result.querySelectorAll(":scope > table > tbody > tr")) {
+		row.hidden = term !== "" && !row.textContent.toLowerCase().includes(term);
+	}
+}
+
+form.addEventListener("submit", event => {
+	event.preventDefault();
+	sort = {column: "", desc: false};
+	query();
+});
+search.addEventListener("input", filter);
+query();
+</script>
+</body>
+</html>
+`
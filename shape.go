@@ -0,0 +1,103 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// shapeEntry is what is known about one generalized path of a document,
+// where all elements of an array share the path .list[].
+type shapeEntry struct {
+	types   []string
+	seen    int // times the path occurred
+	objects int // times it occurred as an object, to tell optional keys
+	example interface{}
+}
+
+// inferShape summarizes the structure of data: one row per path with the
+// types found there, whether it is missing from some of the objects that
+// could have it, and an example value.
+func inferShape(data interface{}) []interface{} {
+	order, entries := shapeEntries(data)
+	var rows []interface{}
+	for _, path := range order {
+		e := entries[path]
+		optional := ""
+		if shapeOptional(path, entries) {
+			optional = "yes"
+		}
+		example := e.example
+		if example == nil {
+			example = ""
+		}
+		rows = append(rows, map[string]interface{}{
+			"path":      path,
+			"type":      strings.Join(e.types, " | "),
+			"optional":  optional,
+			"example":   example,
+			jt.OrderKey: []string{"path", "type", "optional", "example"},
+		})
+	}
+	return rows
+}
+
+// shapeEntries walks data and returns its generalized paths, in the order
+// they first occur, and what is known about each.
+func shapeEntries(data interface{}) ([]string, map[string]*shapeEntry) {
+	entries := make(map[string]*shapeEntry)
+	var order []string
+	entry := func(path string) *shapeEntry {
+		e, ok := entries[path]
+		if !ok {
+			e = &shapeEntry{}
+			entries[path] = e
+			order = append(order, path)
+		}
+		return e
+	}
+
+	var walk func(v interface{}, path string)
+	walk = func(v interface{}, path string) {
+		e := entry(orRoot(path))
+		e.seen++
+		if t := typeName(v); !slices.Contains(e.types, t) {
+			e.types = append(e.types, t)
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			e.objects++
+			for _, k := range jt.OrderedKeys(v) {
+				walk(v[k], path+"."+k)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item, path+"[]")
+			}
+		case nil:
+		default:
+			if e.example == nil {
+				e.example = v
+			}
+		}
+	}
+	walk(data, "")
+	return order, entries
+}
+
+// shapeOptional reports whether the key at path is missing from some of the
+// objects that could have it.
+func shapeOptional(path string, entries map[string]*shapeEntry) bool {
+	parent, ok := entries[shapeParent(path)]
+	return ok && path != "." && !strings.HasSuffix(path, "[]") && entries[path].seen < parent.objects
+}
+
+// shapeParent returns the path of the object holding the key at path.
+func shapeParent(path string) string {
+	i := strings.LastIndex(path, ".")
+	if i <= 0 {
+		return "."
+	}
+	return path[:i]
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// snapshotContext is how many unchanged lines are shown around each change
+// when a snapshot does not match.
+const snapshotContext = 2
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// snapshotIndex is the file in a snapshot directory that lists what each
+// snapshot in it records, so one is never overwritten by another.
+const snapshotIndex = ".jt-snapshots"
+
+// snapshotPath names the snapshot of rendering selector from filename in
+// dir: the file's path relative to the working directory, then the
+// selector, then a hash of the flags that change the output when any are
+// given, with an extension for the output format. It also returns what the
+// snapshot records, for the index.
+func snapshotPath(dir, filename, selector, format string) (string, string) {
+	source := "stdin"
+	if filename != "" && filename != "-" {
+		source = relativePath(filename)
+	}
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(strings.ReplaceAll(source, "/", "__"), "_"), "._")
+	if selector != "." {
+		name += "_" + strings.Trim(unsafeFileChars.ReplaceAllString(selector, "_"), "._")
+	}
+	flags := snapshotFlags()
+	if flags != "" {
+		h := fnv.New32a()
+		h.Write([]byte(flags))
+		name += fmt.Sprintf("_%08x", h.Sum32())
+	}
+	switch format {
+	case "html":
+		name += ".html"
+	case "markdown":
+		name += ".md"
+	case "json", "yaml":
+		name += "." + format
+	default:
+		name += ".txt"
+	}
+	return filepath.Join(dir, name), strings.TrimSpace(source + " " + selector + " " + flags)
+}
+
+// relativePath spells filename relative to the working directory, with
+// slashes; files outside it keep their path, without a leading slash.
+func relativePath(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filepath.ToSlash(filename)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return strings.TrimLeft(filepath.ToSlash(abs), "/")
+}
+
+// snapshotFlags lists the flags given on the command line, but for the
+// snapshot directory, as they change what a snapshot records.
+func snapshotFlags() string {
+	var flags []string
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "snapshot" && f.Name != "verify-snapshot" {
+			flags = append(flags, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	return strings.Join(flags, " ")
+}
+
+// claimSnapshot enters the snapshot at path as recording what in the index
+// of its directory, failing when the snapshot there records something
+// else, whose names came out the same.
+func claimSnapshot(path, what string) {
+	index := filepath.Join(filepath.Dir(path), snapshotIndex)
+	name := filepath.Base(path)
+	content, err := os.ReadFile(index)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fail(exitError, "reading snapshot index: %v", err)
+	}
+	var lines []string
+	for _, line := range splitLines(content) {
+		entry, recorded, _ := strings.Cut(line, "\t")
+		switch {
+		case line == "":
+			continue
+		case entry != name:
+		case recorded == what:
+			return
+		case statOK(path):
+			fail(exitUsage, "snapshot %s already records %s, not %s; remove it first or use another directory", path, recorded, what)
+		default:
+			// the snapshot was removed; its name is free
+			continue
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, name+"\t"+what)
+	sort.Strings(lines)
+	writeOutput(index, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+func statOK(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// snapshot records content in opts.snapshot or, with -verify-snapshot,
+// compares it to the recorded content and exits with exitError, showing the
+// changed lines, when they differ. Output for a snapshot is rendered
+// without colors and at full width, so it does not depend on the terminal.
+func snapshot(content []byte, opts renderOptions) {
+	if !opts.verifySnapshot {
+		claimSnapshot(opts.snapshot, opts.snapshotOf)
+		writeOutput(opts.snapshot, content)
+		fmt.Fprintf(os.Stderr, "wrote snapshot %s\n", opts.snapshot)
+		return
+	}
+
+	recorded, err := os.ReadFile(opts.snapshot)
+	if errors.Is(err, fs.ErrNotExist) {
+		fail(exitError, "no snapshot at %s; record it with -snapshot", opts.snapshot)
+	} else if err != nil {
+		fail(exitError, "reading snapshot: %v", err)
+	}
+	if bytes.Equal(recorded, content) {
+		fmt.Fprintf(os.Stderr, "snapshot %s matches\n", opts.snapshot)
+		return
+	}
+	fmt.Printf("--- %s (snapshot)\n+++ current\n", opts.snapshot)
+	for _, line := range lineDiff(splitLines(recorded), splitLines(content), snapshotContext) {
+		fmt.Println(line)
+	}
+	os.Exit(exitDiffers)
+}
+
+func splitLines(content []byte) []string {
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// lineDiff returns the differences between a and b as unified diff hunks
+// with context unchanged lines around each change.
+func lineDiff(a, b []string, context int) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type edit struct {
+		op   byte // ' ', '-' or '+'
+		line string
+		ai   int // line of a before the edit
+		bi   int // line of b before the edit
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var out []string
+	for start := 0; start < len(edits); {
+		if edits[start].op == ' ' {
+			start++
+			continue
+		}
+		// A hunk runs from context lines before the first change to context
+		// lines after the last change that is not further away than that
+		from := max(start-context, 0)
+		end := start
+		for k := start; k < len(edits) && k <= end+2*context; k++ {
+			if edits[k].op != ' ' {
+				end = k
+			}
+		}
+		to := min(end+context+1, len(edits))
+		var removed, added int
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				removed++
+			}
+			if e.op != '-' {
+				added++
+			}
+		}
+		out = append(out, fmt.Sprintf("@@ -%d,%d +%d,%d @@", edits[from].ai+1, removed, edits[from].bi+1, added))
+		for _, e := range edits[from:to] {
+			out = append(out, string(e.op)+e.line)
+		}
+		start = to
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifySnapshotChanged(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "d.json")
+	snapshots := filepath.Join(dir, "snapshots")
+	if err := os.WriteFile(file, []byte(`{"replicas":2}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runJT(t, "", "-snapshot", snapshots, file); code != 0 {
+		t.Fatalf("recording: got %q, exit %d", out, code)
+	}
+	if out, code := runJT(t, "", "-verify-snapshot", snapshots, file); code != 0 {
+		t.Fatalf("verifying the same output: got %q, exit %d", out, code)
+	}
+	if err := os.WriteFile(file, []byte(`{"replicas":3}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runJT(t, "", "-verify-snapshot", snapshots, file); code != exitDiffers {
+		t.Errorf("verifying changed output: got %q, exit %d; want exit %d", out, code, exitDiffers)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// sortKey is one column of -sort-by, e.g. age:desc.
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+func parseSortKeys(spec string) ([]sortKey, error) {
+	var keys []sortKey
+	for _, item := range splitList(spec) {
+		column, dir, _ := strings.Cut(item, ":")
+		key := sortKey{column: column}
+		switch strings.ToLower(dir) {
+		case "", "asc":
+		case "desc":
+			key.desc = true
+		default:
+			return nil, fmt.Errorf("invalid direction '%s' for column '%s' (expected asc/desc)", dir, column)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortRows returns a sorted copy of the rows of an array by the given
+// columns. Values compare as numbers or timestamps when both sides are
+// one, otherwise as text; rows missing a column, or with a null in it,
+// always sort last. Anything but an array is returned unchanged.
+func sortRows(data interface{}, keys []sortKey) interface{} {
+	rows, ok := data.([]interface{})
+	if !ok || len(keys) == 0 {
+		return data
+	}
+	sorted := make([]interface{}, len(rows))
+	for i, j := range sortOrder(rows, keys) {
+		sorted[i] = rows[j]
+	}
+	return sorted
+}
+
+// sortOrder returns the indexes of rows in the order sortRows puts them.
+func sortOrder(rows []interface{}, keys []sortKey) []int {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		for _, key := range keys {
+			a, aOK := sortValue(rows[order[i]], key.column)
+			b, bOK := sortValue(rows[order[j]], key.column)
+			if !aOK || !bOK {
+				if aOK != bOK {
+					return aOK
+				}
+				continue
+			}
+			c := compareValues(a, b)
+			if c == 0 {
+				continue
+			}
+			if key.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return order
+}
+
+// sortValue returns the value a row is sorted by. Rows that are not
+// objects are sorted by their own value.
+func sortValue(row interface{}, column string) (interface{}, bool) {
+	m, ok := row.(map[string]interface{})
+	if !ok {
+		return row, row != nil
+	}
+	val, ok := jt.LookupColumn(m, column)
+	return val, ok && val != nil
+}
+
+// compareValues orders two values numerically, chronologically, or by
+// their text, in that order of preference.
+func compareValues(a, b interface{}) int {
+	if x, ok := jt.ToFloat(a); ok {
+		if y, ok := jt.ToFloat(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	if x, ok := toTime(a); ok {
+		if y, ok := toTime(b); ok {
+			return x.Compare(y)
+		}
+	}
+	return strings.Compare(jt.ScalarString(a), jt.ScalarString(b))
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return parseTimestamp(v)
+	}
+	return time.Time{}, false
+}
+
+// rowColumns lists the keys of the objects among rows, in the order they
+// first appear, for the sort box to offer.
+func rowColumns(rows []interface{}) []string {
+	var columns []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		if m, ok := row.(map[string]interface{}); ok {
+			for _, k := range jt.OrderedKeys(m) {
+				if !seen[k] {
+					seen[k] = true
+					columns = append(columns, k)
+				}
+			}
+		}
+	}
+	return columns
+}
+
+// openSort shows the sort box, filled with the columns the rows are sorted
+// by, if the data is an array of rows to sort.
+func (m *model) openSort() tea.Cmd {
+	if _, ok := m.inputRows(); !ok {
+		m.notice = "Only an array of rows can be sorted; select one first, e.g. .items"
+		return nil
+	}
+	m.sortMode = true
+	m.sortInput.SetValue(m.sortSpec)
+	m.sortInput.CursorEnd()
+	m.sortInput.Focus()
+	return textinput.Blink
+}
+
+// inputRows returns the rows of the data in input order.
+func (m *model) inputRows() ([]interface{}, bool) {
+	if m.isMultiDoc {
+		return nil, false
+	}
+	if m.rows != nil {
+		return m.rows, true
+	}
+	rows, ok := m.data.([]interface{})
+	return rows, ok
+}
+
+// sortBy sorts the rows in the viewer by columns written as for -sort-by;
+// no columns puts them back in input order.
+func (m *model) sortBy(spec string) {
+	if _, err := parseSortKeys(spec); err != nil {
+		m.notice = "Cannot sort: " + err.Error()
+		return
+	}
+	m.sortSpec = strings.Join(splitList(spec), ",")
+	m.showRows()
+}
+
+// showRows shows the input rows that pass the filter, in the order of the
+// sort, remembering where each came from in the input.
+func (m *model) showRows() {
+	m.rows, _ = m.inputRows()
+	m.order = m.order[:0]
+	for i := range m.rows {
+		if m.rowMatches(i) {
+			m.order = append(m.order, i)
+		}
+	}
+	if keys, _ := parseSortKeys(m.sortSpec); len(keys) > 0 {
+		shown := make([]interface{}, len(m.order))
+		for i, j := range m.order {
+			shown[i] = m.rows[j]
+		}
+		order := make([]int, len(m.order))
+		for i, j := range sortOrder(shown, keys) {
+			order[i] = m.order[j]
+		}
+		m.order = order
+	}
+	shown := make([]interface{}, len(m.order))
+	for i, j := range m.order {
+		shown[i] = m.rows[j]
+	}
+	m.data = shown
+	if m.tree != nil {
+		m.tree = newTreeView(m.data, m.isMultiDoc)
+		m.showTree()
+	} else {
+		m.setOutput(renderDocuments(m.data, m.opts, m.isMultiDoc))
+	}
+}
+
+// inputPath spells a path in the sorted rows with the index the row has
+// in the input, so it still selects the same value from it.
+func (m *model) inputPath(path string) string {
+	if m.rows == nil || !strings.HasPrefix(path, "[") {
+		return path
+	}
+	end := strings.Index(path, "]")
+	i, err := strconv.Atoi(path[1:end])
+	if err != nil || i >= len(m.order) {
+		return path
+	}
+	return fmt.Sprintf("[%d]%s", m.order[i], path[end+1:])
+}
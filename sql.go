@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// sqlTable is a table jt sql loads into SQLite: a name and its rows, with
+// the columns found in them in the order they first appear.
+type sqlTable struct {
+	name    string
+	columns []string
+	rows    []interface{}
+}
+
+// runSQL implements `jt sql <file>... <query>`: the arrays of the files are
+// loaded into tables of an in-memory SQLite database with the sqlite3
+// client, and the result of the query is rendered. A file holding an array
+// is a table named after the file; each array in an object at the top of
+// a file is a table named after its key.
+func runSQL(args []string, popts jt.ParseOptions, opts renderOptions) {
+	if len(args) < 2 {
+		fail(exitUsage, "usage: jt sql <file>... <query>")
+	}
+	files, query := args[:len(args)-1], args[len(args)-1]
+	checkSQLite()
+
+	var tables []sqlTable
+	names := make(map[string]string)
+	for _, path := range files {
+		popts.Filename = path
+		data, _ := parseInput(readFile(path), popts)
+		for _, t := range sqlTables(path, redactInput(data)) {
+			if other, ok := names[t.name]; ok {
+				fail(exitUsage, "%s and %s both have a table named '%s'", other, path, t.name)
+			}
+			names[t.name] = path
+			tables = append(tables, t)
+		}
+	}
+
+	var script bytes.Buffer
+	script.WriteString("BEGIN;\n")
+	for _, t := range tables {
+		writeSQLTable(&script, t)
+	}
+	script.WriteString("COMMIT;\n")
+	script.WriteString(strings.TrimRight(strings.TrimSpace(query), ";") + ";\n")
+
+	out := runClient("sqlite3", []string{"-bail", "-json", ":memory:"}, nil, script.Bytes())
+	render(sqlResult(out), opts, false)
+}
+
+// sqlTables finds the tables of a document: the document itself when it
+// is an array, or the arrays among the values of an object. An object
+// without any is a table of one row.
+func sqlTables(path string, data interface{}) []sqlTable {
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if path == "-" {
+		stem = "stdin"
+	}
+	switch v := data.(type) {
+	case []interface{}:
+		return []sqlTable{newSQLTable(stem, v)}
+	case map[string]interface{}:
+		var tables []sqlTable
+		for _, k := range jt.OrderedKeys(v) {
+			if list, ok := v[k].([]interface{}); ok {
+				tables = append(tables, newSQLTable(k, list))
+			}
+		}
+		if len(tables) > 0 {
+			return tables
+		}
+	}
+	return []sqlTable{newSQLTable(stem, []interface{}{data})}
+}
+
+// newSQLTable makes a table of rows; values that are not objects are rows
+// with a single column, value.
+func newSQLTable(name string, rows []interface{}) sqlTable {
+	t := sqlTable{name: name, rows: rows}
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		keys := []string{"value"}
+		if m, ok := row.(map[string]interface{}); ok {
+			keys = jt.OrderedKeys(m)
+		}
+		for _, k := range keys {
+			if !seen[k] {
+				seen[k] = true
+				t.columns = append(t.columns, k)
+			}
+		}
+	}
+	if len(t.columns) == 0 {
+		t.columns = []string{"value"}
+	}
+	return t
+}
+
+// writeSQLTable writes the statements creating and filling a table. The
+// columns have no declared type, so every value keeps the type it has.
+func writeSQLTable(w *bytes.Buffer, t sqlTable) {
+	quoted := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		quoted[i] = sqlIdentifier(c)
+	}
+	fmt.Fprintf(w, "CREATE TABLE %s (%s);\n", sqlIdentifier(t.name), strings.Join(quoted, ", "))
+	for _, row := range t.rows {
+		values := make([]string, len(t.columns))
+		for i, c := range t.columns {
+			var v interface{}
+			if m, ok := row.(map[string]interface{}); ok {
+				v = m[c]
+			} else if c == "value" {
+				v = row
+			}
+			values[i] = sqlLiteral(v)
+		}
+		fmt.Fprintf(w, "INSERT INTO %s VALUES (%s);\n", sqlIdentifier(t.name), strings.Join(values, ", "))
+	}
+}
+
+func sqlIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqlLiteral writes a value as SQLite reads it: booleans as 1 and 0, and
+// objects and arrays as JSON text for the json_* functions.
+func sqlLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case json.Number:
+		if numberText.MatchString(v.String()) {
+			return v.String()
+		}
+	case float64:
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return jt.ScalarString(v)
+		}
+	case int, int64, uint64:
+		return jt.ScalarString(v)
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'"
+	case map[string]interface{}, []interface{}:
+		return sqlString(jsonText(v))
+	}
+	return sqlString(jt.ScalarString(v))
+}
+
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// checkSQLite fails with exitUsage unless the sqlite3 client jt sql runs is
+// installed and new enough to print JSON, which it did from 3.33.
+func checkSQLite() {
+	path, err := exec.LookPath("sqlite3")
+	if err != nil {
+		fail(exitUsage, "jt sql needs sqlite3 installed, e.g. with apt install sqlite3 or brew install sqlite")
+	}
+	out, err := exec.Command(path, "-version").Output()
+	if err != nil {
+		fail(exitUsage, "jt sql needs sqlite3 installed: running %s -version: %v", path, err)
+	}
+	version := strings.Fields(string(out))
+	if len(version) == 0 {
+		return
+	}
+	parts := strings.Split(version[0], ".")
+	major, _ := strconv.Atoi(parts[0])
+	minor := 0
+	if len(parts) > 1 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	if major < 3 || major == 3 && minor < 33 {
+		fail(exitUsage, "jt sql needs sqlite3 3.33 or later, which can print JSON; %s is %s", path, version[0])
+	}
+}
+
+// sqlResult reads the rows sqlite3 -json printed for the last statement
+// that returned any; it prints nothing for an empty result.
+func sqlResult(out []byte) []interface{} {
+	rows := []interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	decoder.UseNumber()
+	for {
+		v, err := decodeJSONValue(decoder)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fail(exitError, "reading sqlite3 output: %v", err)
+		}
+		if list, ok := v.([]interface{}); ok {
+			rows = list
+		}
+	}
+	return rows
+}
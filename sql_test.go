@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSQLNeedsSQLite(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "t.json")
+	if err := os.WriteFile(file, []byte(`[{"a":1}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, code := runJTEnv(t, []string{"PATH=" + t.TempDir()}, "", "sql", file, "select * from t")
+	if code != exitUsage || !strings.Contains(out, "needs sqlite3 installed") {
+		t.Errorf("without sqlite3: got %q, exit %d; want exit %d", out, code, exitUsage)
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "sqlite3"), []byte("#!/bin/sh\necho '3.31.1 2020-01-27'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out, code = runJTEnv(t, []string{"PATH=" + bin}, "", "sql", file, "select * from t")
+	if code != exitUsage || !strings.Contains(out, "3.33 or later") {
+		t.Errorf("with sqlite3 3.31: got %q, exit %d; want exit %d", out, code, exitUsage)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// runSQLQuery implements `jt sqlq <url> <query>`: it runs the query with
+// the database's command-line client, psql or mysql, and renders the result
+// set as a table with one column per result column, in order.
+func runSQLQuery(args []string, opts renderOptions) {
+	if len(args) != 2 {
+		fail(exitUsage, "usage: jt sqlq <postgres://...|mysql://...> <query>")
+	}
+	u, err := url.Parse(args[0])
+	if err != nil {
+		fail(exitUsage, "invalid database URL: %v", err)
+	}
+	query := strings.TrimRight(strings.TrimSpace(args[1]), "; \n\t")
+
+	var rows []interface{}
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		rows = queryPostgres(u, query)
+	case "mysql":
+		rows = queryMySQL(u, query)
+	default:
+		fail(exitUsage, "unsupported database URL '%s' (expected postgres://... or mysql://...)", u.Scheme)
+	}
+	render(redactInput(rows), opts, false)
+}
+
+// queryPostgres has psql aggregate the result set into a JSON array, so
+// column names and types survive the trip.
+func queryPostgres(u *url.URL, query string) []interface{} {
+	dsn, env := postgresDSN(u)
+	wrapped := "select coalesce(json_agg(q), '[]'::json) from (" + query + "\n) q"
+	out := runClient("psql", []string{"-X", "-q", "-A", "-t", "-v", "ON_ERROR_STOP=1", "-d", dsn, "-c", wrapped}, env, nil)
+	rows, err := decodeJSON(out)
+	if err != nil {
+		fail(exitError, "reading psql output: %v", err)
+	}
+	list, ok := rows.([]interface{})
+	if !ok {
+		fail(exitError, "reading psql output: expected a JSON array")
+	}
+	return list
+}
+
+// postgresDSN returns the URL without its password, which goes in the
+// PGPASSWORD environment variable of psql instead: like MYSQL_PWD for
+// mysql, it keeps the password out of the argument list, which other users
+// can see.
+func postgresDSN(u *url.URL) (string, []string) {
+	stripped := *u
+	var env []string
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			env = append(env, "PGPASSWORD="+password)
+			stripped.User = url.User(u.User.Username())
+		}
+	}
+	params := u.Query()
+	if password := params.Get("password"); params.Has("password") {
+		env = append(env, "PGPASSWORD="+password)
+		params.Del("password")
+		stripped.RawQuery = params.Encode()
+	}
+	return stripped.String(), env
+}
+
+// queryMySQL reads the tab-separated output of mysql --batch. It carries no
+// types: NULL is null, values written like numbers are numbers, the rest
+// text.
+func queryMySQL(u *url.URL, query string) []interface{} {
+	args := []string{"--batch", "-e", query}
+	if host := u.Hostname(); host != "" {
+		args = append(args, "-h", host)
+	}
+	if port := u.Port(); port != "" {
+		args = append(args, "-P", port)
+	}
+	var env []string
+	if u.User != nil {
+		args = append(args, "-u", u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			// Kept out of the argument list, which other users can see
+			env = append(env, "MYSQL_PWD="+password)
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		args = append(args, db)
+	}
+	out := runClient("mysql", args, env, nil)
+
+	rows := []interface{}{}
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) < 2 {
+		return rows
+	}
+	columns := strings.Split(lines[0], "\t")
+	for _, line := range lines[1:] {
+		row := map[string]interface{}{jt.OrderKey: columns}
+		for i, field := range strings.Split(line, "\t") {
+			if i < len(columns) {
+				row[columns[i]] = mysqlValue(field)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+var mysqlUnescape = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n", `\0`, "\x00")
+
+func mysqlValue(field string) interface{} {
+	switch {
+	case field == "NULL":
+		return nil
+	case numberText.MatchString(field):
+		return json.Number(field)
+	}
+	return mysqlUnescape.Replace(field)
+}
+
+var scriptLine = regexp.MustCompile(` near line \d+`)
+
+// runClient runs a database client, feeding it stdin if not nil, and
+// returns its output, reporting what it wrote to stderr if it fails.
+func runClient(name string, args, env []string, stdin []byte) []byte {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		fail(exitUsage, "jt needs the %s client on the PATH", name)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Env = append(os.Environ(), env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// sqlite3 counts lines from the start of the script jt sql feeds
+		// it, which mean nothing to someone who only wrote the query
+		message := scriptLine.ReplaceAllString(strings.TrimSpace(stderr.String()), "")
+		if message == "" {
+			message = err.Error()
+		}
+		fail(exitError, "%s: %s", name, message)
+	}
+	return out
+}
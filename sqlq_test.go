@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPostgresPasswordNotInArguments(t *testing.T) {
+	bin := t.TempDir()
+	log := filepath.Join(bin, "psql.log")
+	psql := "#!/bin/sh\necho \"$@\" > " + log + "\necho \"PGPASSWORD=$PGPASSWORD\" >> " + log + "\necho '[]'\n"
+	if err := os.WriteFile(filepath.Join(bin, "psql"), []byte(psql), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"PATH=" + bin + string(os.PathListSeparator) + os.Getenv("PATH")}
+	for _, dsn := range []string{"postgres://app:s3cret@db/app", "postgres://app@db/app?password=s3cret&sslmode=disable"} {
+		if out, code := runJTEnv(t, env, "", "sqlq", dsn, "select 1"); code != 0 {
+			t.Fatalf("%s: got %q, exit %d", dsn, out, code)
+		}
+		logged, err := os.ReadFile(log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.TrimSpace(string(logged))
+		i := strings.LastIndex(lines, "\n")
+		args, password := lines[:i], lines[i+1:]
+		if strings.Contains(args, "s3cret") {
+			t.Errorf("%s: the password is in psql's arguments: %s", dsn, args)
+		}
+		if strings.TrimSpace(password) != "PGPASSWORD=s3cret" {
+			t.Errorf("%s: got %q, want the password in PGPASSWORD", dsn, password)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// typeName names the type of a value as it appears in the input.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case json.Number, float64, int, int64, uint64:
+		return "number"
+	case string:
+		return "string"
+	case time.Time:
+		return "timestamp"
+	case []byte:
+		return "binary"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// columnStats accumulates the profile of one column of an array.
+type columnStats struct {
+	types     map[string]int
+	missing   int
+	distinct  map[string]bool
+	min, max  interface{}
+	minF      float64
+	maxF      float64
+	sum       float64
+	numbers   int
+	shortest  string
+	longest   string
+	hasString bool
+}
+
+func (c *columnStats) add(v interface{}) {
+	t := typeName(v)
+	c.types[t]++
+	if t == "object" || t == "array" {
+		out, _ := encodeJSON(v, "")
+		c.distinct[string(out)] = true
+	} else {
+		c.distinct[t+":"+jt.ScalarString(v)] = true
+	}
+
+	if t == "number" {
+		if f, ok := jt.ToFloat(v); ok {
+			if c.numbers == 0 || f < c.minF {
+				c.min, c.minF = v, f
+			}
+			if c.numbers == 0 || f > c.maxF {
+				c.max, c.maxF = v, f
+			}
+			c.sum += f
+			c.numbers++
+		}
+	}
+	if s, ok := v.(string); ok {
+		n := utf8.RuneCountInString(s)
+		if !c.hasString || n < utf8.RuneCountInString(c.shortest) {
+			c.shortest = s
+		}
+		if !c.hasString || n > utf8.RuneCountInString(c.longest) {
+			c.longest = s
+		}
+		c.hasString = true
+	}
+}
+
+func (c *columnStats) row(column string) map[string]interface{} {
+	names := make([]string, 0, len(c.types))
+	for t := range c.types {
+		names = append(names, t)
+	}
+	// Most frequent type first
+	sort.Slice(names, func(i, j int) bool {
+		if c.types[names[i]] != c.types[names[j]] {
+			return c.types[names[i]] > c.types[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	var types []string
+	for _, t := range names {
+		types = append(types, fmt.Sprintf("%s %d", t, c.types[t]))
+	}
+
+	row := map[string]interface{}{
+		"column":    column,
+		"types":     strings.Join(types, ", "),
+		"nulls":     json.Number(strconv.Itoa(c.types["null"])),
+		"missing":   json.Number(strconv.Itoa(c.missing)),
+		"distinct":  json.Number(strconv.Itoa(len(c.distinct))),
+		"min":       "",
+		"max":       "",
+		"mean":      "",
+		"shortest":  "",
+		"longest":   "",
+		jt.OrderKey: []string{"column", "types", "nulls", "missing", "distinct", "min", "max", "mean", "shortest", "longest"},
+	}
+	if c.numbers > 0 {
+		row["min"] = c.min
+		row["max"] = c.max
+		row["mean"] = json.Number(strconv.FormatFloat(c.sum/float64(c.numbers), 'f', -1, 64))
+	}
+	if c.hasString {
+		row["shortest"] = c.shortest
+		row["longest"] = c.longest
+	}
+	return row
+}
+
+// columnStatistics profiles each column of an array of objects, or the
+// values of an array of anything else, one row per column.
+func columnStatistics(v []interface{}) []interface{} {
+	columns := []string{"[value]"}
+	if len(v) > 0 && jt.IsObjectArray(v) {
+		columns = jt.ColumnNames(v)
+	}
+
+	var rows []interface{}
+	for _, column := range columns {
+		stats := &columnStats{types: map[string]int{}, distinct: map[string]bool{}}
+		for _, item := range v {
+			if column == "[value]" {
+				stats.add(item)
+				continue
+			}
+			val, ok := item.(map[string]interface{})[column]
+			if !ok {
+				stats.missing++
+				continue
+			}
+			stats.add(val)
+		}
+		rows = append(rows, stats.row(column))
+	}
+	return rows
+}
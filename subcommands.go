@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// subcommand is one of the modes of jt. All of them share the global
+// flags, which may be given before or after the subcommand name.
+type subcommand struct {
+	name    string
+	usage   string
+	summary string
+}
+
+var subcommands = []subcommand{
+	{"view", "jt [view] <file> [selector]", "Render data as tables (the default)"},
+	{"get", "jt get <file> <selector>", "Print the selected value: scalars bare, the rest as JSON"},
+	{"convert", "jt convert -to json|yaml <file> [selector]", "Re-emit the data as JSON or YAML"},
+	{"diff", "jt diff <file> <file>", "Compare two documents structurally"},
+	{"drift", "jt drift <old> <new>", "Compare the inferred shapes of two documents: added and removed fields, type changes"},
+	{"merge", "jt merge <base> <override>...", "Deep-merge layered documents"},
+	{"join", "jt join -on <key> [-left] <left> <right>", "Join the rows of two documents on a key column"},
+	{"sql", "jt sql <file>... <query>", "Load the arrays of the files into SQLite and show the result of an SQL query"},
+	{"sqlq", "jt sqlq <postgres://...|mysql://...> <query>", "Run an SQL query with psql or mysql and show the result set"},
+	{"mcp", "jt mcp", "Serve jt's tools to AI assistants over the Model Context Protocol on stdio"},
+	{"graphql", "jt graphql -query <file> [-var name=value]... <endpoint> [selector]", "Run a GraphQL query and show its data, connections flattened"},
+	{"serve", "jt serve [-listen addr] <file> [selector]", "Serve the data as an HTML page, re-read on every request"},
+}
+
+// parseSubcommand picks the subcommand named by the first argument, unless
+// a file of that name exists, and parses the flags that follow it, before
+// or between its arguments, as in `jt join a.json b.csv -on id`. Without a
+// subcommand name it is view, whose flags may follow its arguments too, as
+// in `jt cfg.yaml .env -keys-only`.
+func parseSubcommand() string {
+	args := flag.Args()
+	if len(args) > 0 && !isFile(args[0]) {
+		for _, cmd := range subcommands {
+			if cmd.name == args[0] {
+				parseInterspersed(args[1:], false)
+				return cmd.name
+			}
+		}
+	}
+	// After a -- nothing is a flag, as in the command -watch runs
+	if parsed := len(os.Args) - 1 - len(args); parsed > 0 && os.Args[parsed] == "--" {
+		return "view"
+	}
+	parseInterspersed(args, true)
+	return "view"
+}
+
+// parseInterspersed parses the flags among args, leaving the other
+// arguments in order as flag.Args(). Everything after -- is an argument;
+// keepDashes keeps the -- among them, for -watch's `[selector] -- command`.
+func parseInterspersed(args []string, keepDashes bool) {
+	var positional []string
+	for len(args) > 0 {
+		flag.CommandLine.Parse(args)
+		rest := flag.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			if keepDashes {
+				positional = append(positional, "--")
+			}
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	flag.CommandLine.Parse(append([]string{"--"}, positional...))
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: cat data.json | jt [flags] [selector]")
+	fmt.Fprintln(out, "       jt [subcommand] [flags] <file> [selector]")
+	fmt.Fprintln(out, "\nSubcommands:")
+	for _, cmd := range subcommands {
+		fmt.Fprintf(out, "  %-8s %s\n           %s\n", cmd.name, cmd.summary, cmd.usage)
+	}
+	fmt.Fprintln(out, "\nExpressions:")
+	fmt.Fprintln(out, "  A selector may be a jq filter, with jq's arithmetic: + on a string and a number")
+	fmt.Fprintln(out, "  is an error, and only false and null are false. In -map and -add-column, + joins")
+	fmt.Fprintln(out, "  text when either side is a string, other operators read strings as numbers,")
+	fmt.Fprintln(out, "  arithmetic on null gives null, and null, false, \"\" and empty values are false.")
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+// emitDocuments encodes data as JSON or YAML: multi-document input becomes
+// a stream of JSON values or YAML documents.
+func emitDocuments(data interface{}, format string, isMultiDoc bool) ([]byte, error) {
+	docs := []interface{}{data}
+	if isMultiDoc {
+		docs = data.([]interface{})
+	}
+	var buf bytes.Buffer
+	for i, doc := range docs {
+		switch format {
+		case "json":
+			out, err := encodeJSON(doc, "  ")
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(out)
+			buf.WriteByte('\n')
+		case "yaml":
+			out, err := encodeYAML(doc)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				buf.WriteString("---\n")
+			}
+			buf.Write(out)
+		default:
+			return nil, fmt.Errorf("unknown format '%s' (expected json/yaml)", format)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// printEmitted writes encoded data to stdout or the -o file.
+func printEmitted(data interface{}, format string, isMultiDoc bool, opts renderOptions) {
+	out, err := emitDocuments(data, format, isMultiDoc)
+	if err != nil {
+		fail(exitRender, "encoding %s: %v", format, err)
+	}
+	if !deliver(out, opts) {
+		os.Stdout.Write(out)
+	}
+}
+
+// printValue implements `jt get`: scalars are printed bare, objects and
+// arrays as JSON.
+func printValue(data interface{}, isMultiDoc bool, opts renderOptions) {
+	switch data.(type) {
+	case map[string]interface{}, []interface{}:
+		if _, ok := rawOutput(data); !ok || isMultiDoc {
+			printEmitted(data, "json", isMultiDoc, opts)
+			return
+		}
+	}
+	printRaw(data, opts)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// Timestamp layouts recognized by -tz. Only layouts that carry a zone are
+// listed, since a timestamp without one cannot be converted reliably.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+}
+
+func loadTimezone(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// parseTimestamp parses s as a timestamp with a zone.
+func parseTimestamp(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < len("2006-01-02T15:04Z") || len(s) > 64 {
+		return time.Time{}, false
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// normalizeTimezones rewrites every recognized timestamp in data into loc,
+// so values collected from machines in different zones line up.
+func normalizeTimezones(data interface{}, loc *time.Location) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if !jt.IsMetaKey(k) {
+				v[k] = normalizeTimezones(val, loc)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = normalizeTimezones(val, loc)
+		}
+		return v
+	case time.Time:
+		// Dates without a time of day are decoded as midnight UTC and have
+		// no zone to convert
+		if jt.IsDateOnly(v) {
+			return v
+		}
+		return v.In(loc)
+	case string:
+		if t, ok := parseTimestamp(v); ok {
+			return t.In(loc).Format(time.RFC3339Nano)
+		}
+	}
+	return data
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestTOMLEdgeCases(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"s = '''\nfirst\n  second'''\n", `{"s":"first\n  second"}`},
+		{"t = { b.y = 1, a = 2 }\n", `{"t":{"b":{"y":1},"a":2}}`},
+		{"at = 1979-05-27T00:32:00.999999-07:00\n", `{"at":"1979-05-27T00:32:00.999999-07:00"}`},
+		{"z = 1\na = 2\n[m]\ny = 1\nx = 2\n", `{"z":1,"a":2,"m":{"y":1,"x":2}}`},
+		{"[[p]]\nb = 1\na = 2\n[[p]]\na = 3\nb = 4\n", `{"p":[{"b":1,"a":2},{"a":3,"b":4}]}`},
+		{"d = 1979-05-27\nl = 1979-05-27 07:32:00\n", `{"d":"1979-05-27","l":"1979-05-27T07:32:00"}`},
+		{"f = 1.0\nh = 0xff\n", `{"f":1.0,"h":255}`},
+	}
+	for _, tt := range tests {
+		out, code := runJT(t, tt.input, "-in", "toml", "-format", "json")
+		if code != 0 || compactJSON(out) != tt.want {
+			t.Errorf("TOML %q: got %q, exit %d; want %s", tt.input, out, code, tt.want)
+		}
+	}
+}
+
+func TestTOMLInvalid(t *testing.T) {
+	for _, input := range []string{"a = 1\na = 2\n", "a = \n", "[t]\n[t]\n", "s = \"unterminated\n"} {
+		if out, code := runJT(t, input, "-in", "toml"); code != exitParse {
+			t.Errorf("TOML %q: got %q, exit %d; want exit %d", input, out, code, exitParse)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+var (
+	treeKeyStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#8caaee"))
+	treeCursorStyle = lipgloss.NewStyle().
+			Background(lipgloss.Color("#51576d")).
+			Foreground(lipgloss.Color("#c6d0f5"))
+)
+
+// treeNode is a value in the tree view. The nodes of its children are made
+// when it is first expanded, so large documents open quickly.
+type treeNode struct {
+	label    string // key, [index], or . for the root
+	path     string // as a selector, . for the root
+	value    interface{}
+	depth    int
+	expanded bool
+	children []*treeNode
+	parent   *treeNode
+}
+
+// treeView shows data as a tree of collapsible objects and arrays, one
+// line per visible node, with a cursor on one of them.
+type treeView struct {
+	root    *treeNode
+	visible []*treeNode
+	cursor  int
+}
+
+// newTreeView makes a tree of data with the root expanded. The documents of
+// multi-document input are the children of the root.
+func newTreeView(data interface{}, isMultiDoc bool) *treeView {
+	label := "."
+	if isMultiDoc {
+		label = "documents"
+	}
+	t := &treeView{root: &treeNode{label: label, path: ".", value: data, expanded: true}}
+	t.refresh()
+	return t
+}
+
+// isContainer reports whether a node has children to expand.
+func (n *treeNode) isContainer() bool {
+	switch v := n.value.(type) {
+	case map[string]interface{}:
+		return len(jt.OrderedKeys(v)) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+func (n *treeNode) kids() []*treeNode {
+	if n.children != nil || !n.isContainer() {
+		return n.children
+	}
+	prefix := strings.TrimSuffix(n.path, ".")
+	child := func(label, path string, value interface{}) {
+		n.children = append(n.children, &treeNode{label: label, path: path, value: value, depth: n.depth + 1, parent: n})
+	}
+	switch v := n.value.(type) {
+	case map[string]interface{}:
+		for _, k := range jt.OrderedKeys(v) {
+			child(k, prefix+"."+k, v[k])
+		}
+	case []interface{}:
+		for i, item := range v {
+			label := fmt.Sprintf("[%d]", i)
+			child(label, prefix+label, item)
+		}
+	}
+	return n.children
+}
+
+// refresh lists the visible nodes again after one was expanded or
+// collapsed, keeping the cursor in range.
+func (t *treeView) refresh() {
+	t.visible = t.visible[:0]
+	var walk func(n *treeNode)
+	walk = func(n *treeNode) {
+		t.visible = append(t.visible, n)
+		if n.expanded {
+			for _, c := range n.kids() {
+				walk(c)
+			}
+		}
+	}
+	walk(t.root)
+	t.cursor = max(0, min(t.cursor, len(t.visible)-1))
+}
+
+func (t *treeView) current() *treeNode {
+	return t.visible[t.cursor]
+}
+
+func (t *treeView) move(delta int) {
+	t.cursor = max(0, min(t.cursor+delta, len(t.visible)-1))
+}
+
+// toggle expands or collapses the node under the cursor.
+func (t *treeView) toggle() {
+	if n := t.current(); n.isContainer() {
+		n.expanded = !n.expanded
+		t.refresh()
+	}
+}
+
+// expand opens the node under the cursor, or moves to its first child when
+// it is open already.
+func (t *treeView) expand() {
+	n := t.current()
+	switch {
+	case !n.isContainer():
+	case !n.expanded:
+		n.expanded = true
+		t.refresh()
+	default:
+		t.move(1)
+	}
+}
+
+// collapse closes the node under the cursor, or moves to its parent when it
+// is closed already.
+func (t *treeView) collapse() {
+	n := t.current()
+	if n.expanded && n.isContainer() {
+		n.expanded = false
+		t.refresh()
+		return
+	}
+	if n.parent != nil {
+		for i, v := range t.visible {
+			if v == n.parent {
+				t.cursor = i
+			}
+		}
+	}
+}
+
+// expandAll opens every node, for printing the whole tree.
+func (t *treeView) expandAll() {
+	var walk func(n *treeNode)
+	walk = func(n *treeNode) {
+		n.expanded = true
+		for _, c := range n.kids() {
+			walk(c)
+		}
+	}
+	walk(t.root)
+	t.refresh()
+}
+
+// lines renders the visible nodes, the one under the cursor highlighted
+// when color is set.
+func (t *treeView) lines(color bool) []string {
+	lines := make([]string, len(t.visible))
+	for i, n := range t.visible {
+		marker := "  "
+		if n.isContainer() {
+			marker = "▸ "
+			if n.expanded {
+				marker = "▾ "
+			}
+		}
+		indent := strings.Repeat("  ", n.depth)
+		summary := treeSummary(n.value)
+		switch {
+		case !color:
+			lines[i] = indent + marker + n.label + ": " + summary
+		case i == t.cursor:
+			lines[i] = treeCursorStyle.Render(indent + marker + n.label + ": " + summary)
+		default:
+			lines[i] = indent + marker + treeKeyStyle.Render(n.label) + ": " + summary
+		}
+	}
+	return lines
+}
+
+// treeSummary shows an object or array by its size and a scalar as JSON
+// would.
+func treeSummary(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}, []interface{}:
+		return jt.Summary(v)
+	case nil:
+		return "null"
+	case string:
+		return jsonText(v)
+	}
+	return jt.ScalarString(v)
+}
+
+// renderTree renders data as a fully expanded tree, for output that is not
+// browsed interactively.
+func renderTree(data interface{}, isMultiDoc bool, color bool) string {
+	t := newTreeView(data, isMultiDoc)
+	t.expandAll()
+	t.cursor = -1
+	return strings.Join(t.lines(color), "\n") + "\n"
+}
+
+// renderTreeView shows data as a tree: browsed in the viewer on a
+// terminal, otherwise printed fully expanded.
+func renderTreeView(data interface{}, opts renderOptions, isMultiDoc bool) {
+	output := renderTree(data, isMultiDoc, colorOutput(opts))
+	if deliver([]byte(output), opts) {
+		return
+	}
+	if !colorOutput(opts) || pickViewer(opts.viewer) != "tui" {
+		fmt.Print(output)
+		return
+	}
+	m := newModel(data, opts, isMultiDoc)
+	m.tree = newTreeView(data, isMultiDoc)
+	m.showTree()
+	runViewer(m, output)
+}
+
+// toggleTree switches the viewer between the table and the tree.
+func (m *model) toggleTree() {
+	if m.tree != nil {
+		m.tree = nil
+		m.setOutput(renderDocuments(m.data, m.opts, m.isMultiDoc))
+		return
+	}
+	m.tree = newTreeView(m.data, m.isMultiDoc)
+	m.viewport.SetXOffset(0)
+	m.showTree()
+}
+
+// showTree puts the visible nodes in the viewport, scrolled so the cursor
+// is in view.
+func (m *model) showTree() {
+	m.setOutput(strings.Join(m.tree.lines(true), "\n"))
+	if height := m.viewport.Height; height > 0 {
+		switch {
+		case m.tree.cursor < m.viewport.YOffset:
+			m.viewport.SetYOffset(m.tree.cursor)
+		case m.tree.cursor >= m.viewport.YOffset+height:
+			m.viewport.SetYOffset(m.tree.cursor - height + 1)
+		}
+	}
+}
+
+// treeKey handles the keys that move around the tree, reporting whether
+// key was one of them.
+func (m *model) treeKey(key string) bool {
+	switch key {
+	case "up", "k":
+		m.tree.move(-1)
+	case "down", "j":
+		m.tree.move(1)
+	case "pgup":
+		m.tree.move(-m.viewport.Height)
+	case "pgdown":
+		m.tree.move(m.viewport.Height)
+	case "g", "home":
+		m.tree.cursor = 0
+	case "G", "end":
+		m.tree.cursor = len(m.tree.visible) - 1
+	case "enter", " ", "space":
+		m.tree.toggle()
+	case "right", "l":
+		m.tree.expand()
+	case "left", "h":
+		m.tree.collapse()
+	default:
+		return false
+	}
+	m.showTree()
+	return true
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// fact is one observable detail of a document: a scalar as written, the
+// order of an object's keys, an attribute, a comment. Comparing the facts
+// of the input with those of its re-serialized tree shows what a
+// conversion through jt would lose.
+type fact struct {
+	path  string
+	kind  string
+	value string
+}
+
+type factList struct {
+	facts []fact
+}
+
+func (l *factList) add(path, kind, value string) {
+	l.facts = append(l.facts, fact{path: path, kind: kind, value: value})
+}
+
+// verifyRoundTrip re-serializes data to the format input was read from and
+// returns one row per divergence between the two.
+func verifyRoundTrip(input []byte, format string, data interface{}, isMultiDoc bool) ([]interface{}, error) {
+	var source, roundTrip []fact
+	var err error
+
+	switch format {
+	case "json":
+		var out []byte
+		if out, err = encodeJSON(data, "  "); err != nil {
+			return nil, err
+		}
+		if source, err = jsonFacts(input); err != nil {
+			return nil, err
+		}
+		roundTrip, err = jsonFacts(out)
+	case "ndjson":
+		var out bytes.Buffer
+		for _, row := range data.([]interface{}) {
+			line, err := encodeJSON(row, "")
+			if err != nil {
+				return nil, err
+			}
+			out.Write(line)
+			out.WriteByte('\n')
+		}
+		if source, err = ndjsonFacts(input); err != nil {
+			return nil, err
+		}
+		roundTrip, err = ndjsonFacts(out.Bytes())
+	case "toml":
+		return nil, fmt.Errorf("jt cannot write TOML, so it has no round trip to compare")
+	case "csv":
+		return nil, fmt.Errorf("jt cannot write CSV, so it has no round trip to compare")
+	case "xml":
+		root := xmlRootName(input)
+		if source, err = xmlFacts(input); err != nil {
+			return nil, err
+		}
+		roundTrip, err = xmlFacts(encodeXML(root, data))
+	default:
+		documents := []interface{}{data}
+		if isMultiDoc {
+			documents = data.([]interface{})
+		}
+		var out bytes.Buffer
+		for i, doc := range documents {
+			encoded, err := encodeYAML(doc)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				out.WriteString("---\n")
+			}
+			out.Write(encoded)
+		}
+		if source, err = yamlFacts(input); err != nil {
+			return nil, err
+		}
+		roundTrip, err = yamlFacts(out.Bytes())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return compareFacts(source, roundTrip), nil
+}
+
+// compareFacts lists facts that changed, disappeared or appeared, in the
+// order they occur in the source.
+func compareFacts(source, roundTrip []fact) []interface{} {
+	key := func(f fact) string { return f.kind + "\x00" + f.path }
+	after := make(map[string]string)
+	for _, f := range roundTrip {
+		after[key(f)] = f.value
+	}
+	before := make(map[string]bool)
+	last := make(map[string]int)
+	for i, f := range source {
+		before[key(f)] = true
+		last[key(f)] = i
+	}
+
+	var rows []interface{}
+	row := func(path, issue, source, roundTrip string) {
+		rows = append(rows, map[string]interface{}{
+			"path":       path,
+			"issue":      issue,
+			"source":     source,
+			"round trip": roundTrip,
+			jt.OrderKey:  []string{"path", "issue", "source", "round trip"},
+		})
+	}
+
+	for i, f := range source {
+		k := key(f)
+		if last[k] != i {
+			// Of a duplicate key only the last occurrence survives
+			row(f.path, "duplicate "+f.kind+" lost", f.value, "")
+			continue
+		}
+		value, ok := after[k]
+		switch {
+		case !ok:
+			row(f.path, f.kind+" lost", f.value, "")
+		case value != f.value:
+			row(f.path, f.kind+" changed", f.value, value)
+		}
+	}
+	for _, f := range roundTrip {
+		if !before[key(f)] {
+			row(f.path, f.kind+" added", "", f.value)
+		}
+	}
+	return rows
+}
+
+func jsonFacts(input []byte) ([]fact, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	var list factList
+	if err := jsonValueFacts(decoder, &list, ""); err != nil {
+		return nil, err
+	}
+	return list.facts, nil
+}
+
+// ndjsonFacts reads the facts of each line, under its index.
+func ndjsonFacts(input []byte) ([]fact, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	var list factList
+	for i := 0; decoder.More(); i++ {
+		if err := jsonValueFacts(decoder, &list, fmt.Sprintf("[%d]", i)); err != nil {
+			return nil, err
+		}
+	}
+	return list.facts, nil
+}
+
+func jsonValueFacts(decoder *json.Decoder, list *factList, path string) error {
+	tok, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			var keys []string
+			for decoder.More() {
+				keyTok, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				k := keyTok.(string)
+				keys = append(keys, k)
+				if err := jsonValueFacts(decoder, list, path+"."+k); err != nil {
+					return err
+				}
+			}
+			list.add(orRoot(path), "key order", strings.Join(keys, ", "))
+		} else {
+			for i := 0; decoder.More(); i++ {
+				if err := jsonValueFacts(decoder, list, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+		_, err = decoder.Token()
+		return err
+	case string:
+		list.add(orRoot(path), "value", strconv.Quote(tok))
+	default:
+		list.add(orRoot(path), "value", jt.ScalarString(tok))
+	}
+	return nil
+}
+
+func yamlFacts(input []byte) ([]fact, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(input))
+	var list factList
+	var documents []*yaml.Node
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		documents = append(documents, &doc)
+	}
+	for i, doc := range documents {
+		prefix := ""
+		if len(documents) > 1 {
+			prefix = fmt.Sprintf("[%d]", i)
+		}
+		yamlNodeFacts(doc, &list, prefix)
+	}
+	return list.facts, nil
+}
+
+func yamlNodeFacts(node *yaml.Node, list *factList, path string) {
+	for _, comment := range []string{node.HeadComment, node.LineComment, node.FootComment} {
+		if comment != "" {
+			list.add(orRoot(path), "comment", comment)
+		}
+	}
+	if node.Anchor != "" {
+		list.add(orRoot(path), "anchor", "&"+node.Anchor)
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			yamlNodeFacts(child, list, path)
+		}
+	case yaml.MappingNode:
+		var keys []string
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			keys = append(keys, keyNode.Value)
+			childPath := path + "." + keyNode.Value
+			for _, comment := range []string{keyNode.HeadComment, keyNode.LineComment, keyNode.FootComment} {
+				if comment != "" {
+					list.add(childPath, "comment", comment)
+				}
+			}
+			if keyNode.ShortTag() != "!!str" && keyNode.ShortTag() != "!!merge" {
+				list.add(childPath, "key type", keyNode.ShortTag())
+			}
+			yamlNodeFacts(node.Content[i+1], list, childPath)
+		}
+		list.add(orRoot(path), "key order", strings.Join(keys, ", "))
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			yamlNodeFacts(child, list, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case yaml.AliasNode:
+		list.add(orRoot(path), "alias", "*"+node.Value)
+	case yaml.ScalarNode:
+		list.add(orRoot(path), "value", node.ShortTag()+" "+node.Value)
+	}
+}
+
+func xmlRootName(input []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(input))
+	for {
+		tok, err := decoder.RawToken()
+		if err != nil {
+			return "root"
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name.Local
+		}
+	}
+}
+
+// xmlFacts reads raw tokens so namespace prefixes, comments and processing
+// instructions are seen as written. Repeated siblings are told apart by
+// their position, e.g. /project/dependency[2]/version.
+func xmlFacts(input []byte) ([]fact, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(input))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	type element struct {
+		path     string
+		children []string
+		seen     map[string]int
+		text     strings.Builder
+	}
+	var list factList
+	stack := []*element{{path: "", seen: map[string]int{}}}
+
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent := stack[len(stack)-1]
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			name := qualifiedName(tok.Name)
+			parent.children = append(parent.children, name)
+			parent.seen[name]++
+			path := parent.path + "/" + name
+			if n := parent.seen[name]; n > 1 {
+				path += fmt.Sprintf("[%d]", n)
+			}
+			for _, attr := range tok.Attr {
+				list.add(path+"/@"+qualifiedName(attr.Name), "attribute", attr.Value)
+			}
+			stack = append(stack, &element{path: path, seen: map[string]int{}})
+		case xml.EndElement:
+			if len(stack) == 1 {
+				continue
+			}
+			if text := strings.TrimSpace(parent.text.String()); text != "" {
+				list.add(parent.path, "text", text)
+			}
+			if len(parent.children) > 0 {
+				list.add(parent.path, "child order", strings.Join(parent.children, ", "))
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			parent.text.Write(tok)
+		case xml.Comment:
+			list.add(orRoot(parent.path), "comment", strings.TrimSpace(string(tok)))
+		case xml.ProcInst:
+			if tok.Target != "xml" {
+				list.add(orRoot(parent.path), "instruction", tok.Target+" "+string(tok.Inst))
+			}
+		case xml.Directive:
+			list.add(orRoot(parent.path), "directive", string(tok))
+		}
+	}
+	return list.facts, nil
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// runVerify prints the divergences found by verifyRoundTrip and exits
+// non-zero if there are any.
+func runVerify(input []byte, data interface{}, isMultiDoc bool, opts renderOptions) {
+	format := opts.source
+	rows, err := verifyRoundTrip(input, format, data, isMultiDoc)
+	if err != nil {
+		fail(exitError, "verifying round trip: %v", err)
+	}
+	if len(rows) == 0 {
+		fmt.Printf("%s round trip is lossless\n", strings.ToUpper(format))
+		return
+	}
+	// The facts come from the input as written, before -redact masked it
+	for _, row := range rows {
+		row := row.(map[string]interface{})
+		if !redactedPath(row["path"].(string)) {
+			continue
+		}
+		for _, column := range []string{"source", "round trip"} {
+			if row[column] != "" {
+				row[column] = redactedValue
+			}
+		}
+	}
+	fmt.Fprint(os.Stdout, renderDocuments(rows, opts, false))
+	os.Exit(exitError)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+var changedStyle = lipgloss.NewStyle().Reverse(true)
+
+// watchArgs splits the arguments of watch mode, `[selector] -- command...`,
+// into the selector and the command to run.
+func watchArgs(args []string) (string, []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			if i > 1 {
+				fail(exitUsage, "expected at most a selector before --, got %s", strings.Join(args[:i], " "))
+			}
+			selector := "."
+			if i == 1 {
+				selector = args[0]
+			}
+			return selector, args[i+1:]
+		}
+	}
+	return ".", args
+}
+
+// runWatch runs command every interval and redraws its output as a table in
+// place, like watch(1), with the selector and row steps applied to each run.
+// Cells whose text differs from the previous run are shown in reverse video.
+func runWatch(interval time.Duration, args []string, popts jt.ParseOptions, steps rowSteps, opts renderOptions) {
+	selector, command := watchArgs(args)
+	if len(command) == 0 {
+		fail(exitUsage, "-watch needs a command to run, e.g. jt -watch 5 -- kubectl get pods -o json")
+	}
+
+	var previous []string
+	for {
+		var stderr bytes.Buffer
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stderr = &stderr
+		input, err := cmd.Output()
+
+		var body string
+		if err != nil {
+			body = fmt.Sprintf("%s failed: %v\n%s", command[0], err, stderr.String())
+		} else if data, isMultiDoc, err := watchData(input, selector, popts); err != nil {
+			// shown like a failed command; the next frame may be fine again
+			body = err.Error() + "\n"
+		} else if data, start, footer, err := steps.apply(data); err != nil {
+			body = err.Error() + "\n"
+		} else {
+			frameOpts := opts
+			if footer != "" {
+				frameOpts.Footer = footer
+				if !isMultiDoc {
+					frameOpts.FirstIndex = start
+				}
+			}
+			output := renderDocuments(data, frameOpts, isMultiDoc)
+			lines := strings.Split(output, "\n")
+			plain := make([]string, len(lines))
+			for i, line := range lines {
+				plain[i] = stripANSI(line)
+			}
+			if previous != nil {
+				lines = highlightChanges(lines, plain, previous)
+			}
+			previous = plain
+			body = strings.Join(lines, "\n")
+		}
+
+		header := fmt.Sprintf("Every %s: %s", interval, strings.Join(command, " "))
+		fmt.Fprintf(os.Stdout, "\x1b[H\x1b[2J%s    %s\n\n%s", header, time.Now().Format(time.TimeOnly), body)
+		time.Sleep(interval)
+	}
+}
+
+// watchData parses one frame of output and applies the selector, returning
+// the error instead of exiting so watching goes on.
+func watchData(input []byte, selector string, popts jt.ParseOptions) (interface{}, bool, error) {
+	if plugin := decoderPlugin(popts); plugin != "" {
+		input, popts.Format = runDecoder(plugin, input), "json"
+	}
+	data, isMultiDoc, err := jt.Parse(input, popts)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err = jt.Select(redactInput(data), selector)
+	return data, isMultiDoc, err
+}
+
+// highlightChanges marks the cells of each line whose text differs from the
+// same cell of the previous frame. Cells are the spans between the table's
+// column separators.
+func highlightChanges(lines, plain, previous []string) []string {
+	result := make([]string, len(lines))
+	copy(result, lines)
+	for i, line := range plain {
+		if i >= len(previous) || line == previous[i] {
+			continue
+		}
+		cells := cellSpans(line)
+		before := cellSpans(previous[i])
+		var ranges []highlightRange
+		for j, cell := range cells {
+			text := strings.TrimSpace(line[cell[0]:cell[1]])
+			if text == "" {
+				continue
+			}
+			if j < len(before) && strings.TrimSpace(previous[i][before[j][0]:before[j][1]]) == text {
+				continue
+			}
+			// Highlight the text, not the padding around it
+			start := cell[0] + strings.Index(line[cell[0]:cell[1]], text)
+			ranges = append(ranges, highlightRange{start: start, end: start + len(text), style: changedStyle})
+		}
+		if len(ranges) > 0 {
+			result[i] = highlightLine(lines[i], ranges)
+		}
+	}
+	return result
+}
+
+// cellSpans returns the byte ranges between the column separators of a
+// plain table line.
+func cellSpans(line string) [][2]int {
+	var spans [][2]int
+	start := 0
+	for i, r := range line {
+		if r == '│' {
+			if i > start {
+				spans = append(spans, [2]int{start, i})
+			}
+			start = i + len(string(r))
+		}
+	}
+	if start < len(line) {
+		spans = append(spans, [2]int{start, len(line)})
+	}
+	return spans
+}
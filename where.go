@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/obegron/jt/pkg/jt"
+)
+
+// stringList is a flag that may be given several times.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// predicate is a -where condition such as status=Running or size>100.
+type predicate struct {
+	column  string
+	op      string
+	operand string
+	re      *regexp.Regexp
+}
+
+// whereOperators are tried in order, so two-character operators win over
+// their one-character prefixes.
+var whereOperators = []string{"!=", ">=", "<=", "==", "=", ">", "<", "~"}
+
+func parsePredicate(s string) (predicate, error) {
+	idx, op := -1, ""
+	for _, candidate := range whereOperators {
+		if i := strings.Index(s, candidate); i > 0 && (idx == -1 || i < idx) {
+			idx, op = i, candidate
+		}
+	}
+	if idx == -1 {
+		return predicate{}, fmt.Errorf("'%s' has no operator (expected one of %s)", s, strings.Join(whereOperators, " "))
+	}
+	p := predicate{
+		column:  strings.TrimSpace(s[:idx]),
+		op:      op,
+		operand: strings.TrimSpace(s[idx+len(op):]),
+	}
+	if op == "~" {
+		re, err := regexp.Compile(p.operand)
+		if err != nil {
+			return predicate{}, fmt.Errorf("'%s': %v", s, err)
+		}
+		p.re = re
+	}
+	return p, nil
+}
+
+func (p predicate) matches(row interface{}) bool {
+	val, ok := sortValue(row, p.column)
+	if !ok {
+		// Only != matches rows without the column
+		return p.op == "!="
+	}
+	switch p.op {
+	case "~":
+		return p.re.MatchString(jt.ScalarString(val))
+	case "=", "==":
+		return compareValues(val, p.operand) == 0
+	case "!=":
+		return compareValues(val, p.operand) != 0
+	case ">":
+		return compareValues(val, p.operand) > 0
+	case ">=":
+		return compareValues(val, p.operand) >= 0
+	case "<":
+		return compareValues(val, p.operand) < 0
+	case "<=":
+		return compareValues(val, p.operand) <= 0
+	}
+	return false
+}
+
+// filterRows keeps the rows of an array that match all predicates.
+// Anything but an array is returned unchanged.
+func filterRows(data interface{}, predicates []predicate) interface{} {
+	rows, ok := data.([]interface{})
+	if !ok || len(predicates) == 0 {
+		return data
+	}
+	kept := []interface{}{}
+	for _, row := range rows {
+		match := true
+		for _, p := range predicates {
+			if !p.matches(row) {
+				match = false
+				break
+			}
+		}
+		if match {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,28 @@
+package main
+
+import "fmt"
+
+// windowRows returns the rows of an array from offset on, at most limit of
+// them (0 for no limit), the index of the first of them, and a note on what
+// was left out. Anything but an array is returned unchanged.
+func windowRows(data interface{}, offset, limit int) (interface{}, int, string) {
+	rows, ok := data.([]interface{})
+	if !ok || (offset <= 0 && limit <= 0) {
+		return data, 0, ""
+	}
+	total := len(rows)
+	start := min(max(offset, 0), total)
+	end := total
+	if limit > 0 {
+		end = min(start+limit, total)
+	}
+	if start == 0 && end == total {
+		return data, 0, ""
+	}
+
+	note := fmt.Sprintf("%d of %d rows omitted", total-(end-start), total)
+	if end > start {
+		note = fmt.Sprintf("rows %d-%d of %d shown, %s", start, end-1, total, note)
+	}
+	return rows[start:end], start, note
+}